@@ -0,0 +1,156 @@
+// Package admission decides which requests may start work, so that a burst
+// of them waits its turn instead of exhausting memory.
+package admission
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("too many requests waiting")
+var ErrQueueTimeout = errors.New("timed out waiting for capacity")
+
+// Limits bound a Controller. A zero MaxInFlight or MaxBytes is no limit,
+// and a zero QueueTimeout lets a request wait for as long as it is live.
+type Limits struct {
+	// MaxInFlight caps the requests admitted at once, and MaxBytes the
+	// weight they hold between them.
+	MaxInFlight int
+	MaxBytes    int64
+	// QueueSize is how many more requests may wait for room, each for at
+	// most QueueTimeout.
+	QueueSize    int
+	QueueTimeout time.Duration
+}
+
+type waiter struct {
+	weight int64
+	ready  chan struct{}
+}
+
+// Controller is a semaphore over a count of requests and their combined
+// weight, such as the bytes of their uploads. Waiting requests are admitted
+// strictly in arrival order, so a heavy request is never starved by lighter
+// ones behind it.
+type Controller struct {
+	limits Limits
+
+	mu       sync.Mutex
+	inFlight int
+	weight   int64
+	queue    list.List
+}
+
+func NewController(limits Limits) *Controller {
+	return &Controller{limits: limits}
+}
+
+// Limits returns the limits the controller was created with.
+func (c *Controller) Limits() Limits {
+	return c.limits
+}
+
+// Acquire admits a request of the given weight, waiting in the queue while
+// there is no room. A weight above MaxBytes counts as MaxBytes, so such a
+// request runs alone rather than never. It fails with ErrQueueFull when the
+// queue is full, with ErrQueueTimeout after waiting QueueTimeout, or with
+// ctx's error once ctx ends. On success, release must be called when the
+// request is done.
+func (c *Controller) Acquire(ctx context.Context, weight int64) (release func(), err error) {
+	if c.limits.MaxBytes > 0 {
+		weight = min(weight, c.limits.MaxBytes)
+	}
+	release = func() { c.release(weight) }
+
+	c.mu.Lock()
+	if c.queue.Len() == 0 && c.fits(weight) {
+		c.take(weight)
+		c.mu.Unlock()
+		return release, nil
+	}
+	if c.queue.Len() >= c.limits.QueueSize {
+		c.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := &waiter{weight: weight, ready: make(chan struct{})}
+	elem := c.queue.PushBack(w)
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if c.limits.QueueTimeout > 0 {
+		timer := time.NewTimer(c.limits.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Admitted while giving up; hand the room straight back.
+		c.inFlight--
+		c.weight -= weight
+	default:
+		c.queue.Remove(elem)
+	}
+	// Whoever is now at the front may fit.
+	c.admit()
+	return nil, err
+}
+
+// InFlight returns the number of requests admitted and their combined
+// weight.
+func (c *Controller) InFlight() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inFlight, c.weight
+}
+
+// Queued returns the number of requests waiting.
+func (c *Controller) Queued() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queue.Len()
+}
+
+func (c *Controller) fits(weight int64) bool {
+	return (c.limits.MaxInFlight == 0 || c.inFlight < c.limits.MaxInFlight) &&
+		(c.limits.MaxBytes == 0 || c.weight+weight <= c.limits.MaxBytes)
+}
+
+func (c *Controller) take(weight int64) {
+	c.inFlight++
+	c.weight += weight
+}
+
+func (c *Controller) release(weight int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	c.weight -= weight
+	c.admit()
+}
+
+// admit lets in waiters from the front of the queue for as long as they fit.
+func (c *Controller) admit() {
+	for elem := c.queue.Front(); elem != nil; elem = c.queue.Front() {
+		w := elem.Value.(*waiter)
+		if !c.fits(w.weight) {
+			return
+		}
+		c.take(w.weight)
+		c.queue.Remove(elem)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,96 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// acquireAsync starts Acquire in the background and returns where its
+// outcome arrives, once the request is in the queue.
+func acquireAsync(t *testing.T, c *Controller, ctx context.Context, weight int64) <-chan error {
+	t.Helper()
+	queued := c.Queued()
+	done := make(chan error, 1)
+	go func() {
+		release, err := c.Acquire(ctx, weight)
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return c.Queued() > queued }, time.Second, time.Millisecond)
+	return done
+}
+
+func TestController_InFlightLimit(t *testing.T) {
+	c := NewController(Limits{MaxInFlight: 2, QueueSize: 1})
+	first, err := c.Acquire(context.Background(), 0)
+	assert.NoError(t, err)
+	_, err = c.Acquire(context.Background(), 0)
+	assert.NoError(t, err)
+
+	waiting := acquireAsync(t, c, context.Background(), 0)
+	_, err = c.Acquire(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	first()
+	assert.NoError(t, <-waiting)
+	n, _ := c.InFlight()
+	assert.Equal(t, 1, n)
+}
+
+func TestController_WeightLimit(t *testing.T) {
+	c := NewController(Limits{MaxBytes: 100, QueueSize: 2})
+	release, err := c.Acquire(context.Background(), 60)
+	assert.NoError(t, err)
+
+	// The heavy request is first in line, and the light one behind it
+	// waits too even though it would fit.
+	heavy := acquireAsync(t, c, context.Background(), 500)
+	light := acquireAsync(t, c, context.Background(), 10)
+	_, w := c.InFlight()
+	assert.Equal(t, int64(60), w)
+
+	release()
+	assert.NoError(t, <-heavy)
+	assert.NoError(t, <-light)
+	n, w := c.InFlight()
+	assert.Equal(t, 0, n)
+	assert.Equal(t, int64(0), w)
+}
+
+func TestController_QueueTimeout(t *testing.T) {
+	c := NewController(Limits{MaxInFlight: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond})
+	release, err := c.Acquire(context.Background(), 0)
+	assert.NoError(t, err)
+
+	_, err = c.Acquire(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+	assert.Equal(t, 0, c.Queued())
+
+	release()
+	release, err = c.Acquire(context.Background(), 0)
+	assert.NoError(t, err)
+	release()
+}
+
+func TestController_Canceled(t *testing.T) {
+	c := NewController(Limits{MaxBytes: 10, QueueSize: 2})
+	release, err := c.Acquire(context.Background(), 10)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := acquireAsync(t, c, ctx, 10)
+	second := acquireAsync(t, c, context.Background(), 1)
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+
+	// The request behind the canceled one moves up, and still waits for
+	// room.
+	assert.Equal(t, 1, c.Queued())
+	release()
+	assert.NoError(t, <-second)
+}
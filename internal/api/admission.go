@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"league/internal/admission"
+	"league/internal/metrics"
+	"net/http"
+	"strconv"
+)
+
+// Admission limits the operations running at once and the upload bytes
+// they hold between them, as ADMISSION_MAX_IN_FLIGHT and
+// ADMISSION_MAX_BYTES configure. nil admits every request.
+var Admission *admission.Controller
+
+var (
+	admissionQueueFull    = metrics.NewCounter("admission_rejected_queue_full_total", "Requests turned away with 503 because the admission queue was full.")
+	admissionQueueTimeout = metrics.NewCounter("admission_rejected_queue_timeout_total", "Requests turned away with 503 after waiting the whole admission queue timeout.")
+)
+
+func init() {
+	metrics.NewGaugeFunc("admission_in_flight", "Requests admitted and not yet finished.", func() float64 {
+		if Admission == nil {
+			return 0
+		}
+		n, _ := Admission.InFlight()
+		return float64(n)
+	})
+	metrics.NewGaugeFunc("admission_in_flight_bytes", "Upload bytes held by admitted requests.", func() float64 {
+		if Admission == nil {
+			return 0
+		}
+		_, bytes := Admission.InFlight()
+		return float64(bytes)
+	})
+	metrics.NewGaugeFunc("admission_queue_depth", "Requests waiting to be admitted.", func() float64 {
+		if Admission == nil {
+			return 0
+		}
+		return float64(Admission.Queued())
+	})
+}
+
+// Admitted runs next once Admission lets the request in, weighing it by the
+// size of its body; one of unknown size is charged the whole byte budget. A
+// request turned away responds 503 with a Retry-After of the queue timeout.
+func Admitted(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if Admission == nil {
+			next(w, r)
+			return
+		}
+
+		weight := r.ContentLength
+		if weight < 0 {
+			weight = Admission.Limits().MaxBytes
+		}
+		release, err := Admission.Acquire(r.Context(), weight)
+		switch {
+		case errors.Is(err, admission.ErrQueueFull):
+			admissionQueueFull.Inc()
+			overloaded(w, err)
+		case errors.Is(err, admission.ErrQueueTimeout):
+			admissionQueueTimeout.Inc()
+			overloaded(w, err)
+		case err != nil:
+			operationError(w, err)
+		default:
+			defer release()
+			next(w, r)
+		}
+	}
+}
+
+// overloaded responds 503, asking the client to come back after about as
+// long as a request may wait in the queue.
+func overloaded(w http.ResponseWriter, err error) {
+	retryAfter := max(1, ceilSeconds(Admission.Limits().QueueTimeout))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, "server overloaded: "+err.Error(), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"league/internal/auth"
+	"league/internal/metrics"
+	"net/http"
+)
+
+// Auth checks the credentials of every request to an Authorized route. nil
+// leaves the service open, as it is unless AUTH_KEYS_FILE or a JWT key is
+// configured.
+var Auth *auth.Authenticator
+
+var authRejected = metrics.NewCounter("auth_rejected_total", "Requests refused with 401 or 403 by authentication.")
+
+// Authorized runs next for requests from a key whose scopes include scope,
+// with the key's principal in the request context. Requests without valid
+// credentials get 401, and those whose key lacks the scope 403. A signed
+// request's body is checked against its signature before next runs.
+func Authorized(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if Auth == nil {
+			next(w, r)
+			return
+		}
+
+		p, err := Auth.Authenticate(r)
+		if errors.Is(err, auth.ErrBodyTooLarge) {
+			http.Error(w, fmt.Sprintf("signed body exceeds %d bytes", Auth.MaxBody), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			authRejected.Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer, APIKey header="`+auth.KeyHeader+`"`)
+			http.Error(w, fmt.Sprintf("unauthorized: %s", err), http.StatusUnauthorized)
+			return
+		}
+		// A signed body may have been put aside in a file while it was
+		// checked; closing it removes the file.
+		defer r.Body.Close()
+		logSubject(r.Context(), p.ID)
+		if !p.Allows(scope) {
+			forbidden(w, p, scope)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	}
+}
+
+// forbidden responds 403 for a principal lacking scope.
+func forbidden(w http.ResponseWriter, p *auth.Principal, scope string) {
+	authRejected.Inc()
+	http.Error(w, fmt.Sprintf("%q may not use %s", p.ID, scope), http.StatusForbidden)
+}
+
+// HealthHandler responds 200 while the server is serving. It needs no
+// credentials.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
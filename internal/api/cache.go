@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"league/internal/metrics"
+	"league/internal/storage"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ResultCache holds recent successful operation responses, evicting the
+// least recently used beyond RESULT_CACHE_BYTES. nil disables caching.
+var ResultCache *storage.MemoryStore
+
+var (
+	cacheHits   = metrics.NewCounter("result_cache_hits_total", "Operation responses served from the result cache, including 304s.")
+	cacheMisses = metrics.NewCounter("result_cache_misses_total", "Operation responses computed because the result cache had no entry.")
+)
+
+func init() {
+	metrics.NewGaugeFunc("result_cache_bytes", "Bytes of responses held in the result cache.", func() float64 {
+		if ResultCache == nil {
+			return 0
+		}
+		return float64(ResultCache.Size())
+	})
+	metrics.NewGaugeFunc("result_cache_entries", "Responses held in the result cache.", func() float64 {
+		if ResultCache == nil {
+			return 0
+		}
+		return float64(ResultCache.Len())
+	})
+}
+
+// Cached serves the operation's successful responses from ResultCache. The
+// key covers the operation, every parameter, the Accept header and the
+// content of every uploaded file, so an identical request always maps to
+// the same response. That key is also the response's ETag, and a request
+// whose If-None-Match carries it is answered 304 without running the
+// operation.
+func Cached(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ResultCache == nil {
+			next(w, r)
+			return
+		}
+		key, err := cacheKey(operation, r)
+		if err != nil {
+			// The operation reports malformed uploads itself.
+			next(w, r)
+			return
+		}
+		etag := `"` + key + `"`
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			cacheHits.Inc()
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if data, err := ResultCache.Get(key); err == nil {
+			cacheHits.Inc()
+			contentType, body, _ := bytes.Cut(data, []byte("\n"))
+			w.Header().Set("Content-Type", string(contentType))
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusOK)
+			w.Write(body)
+			return
+		}
+
+		cacheMisses.Inc()
+		out := &cachingResponse{ResponseWriter: w, etag: etag, limit: ResultCache.Limits().MaxBytes}
+		next(out, r)
+		if out.aborted != nil {
+			panic(http.ErrAbortHandler)
+		}
+		out.WriteHeader(http.StatusOK)
+		if !out.sent {
+			out.send(nil)
+		}
+		if out.status == http.StatusOK && !out.uncached {
+			data := append([]byte(out.Header().Get("Content-Type")+"\n"), out.body.Bytes()...)
+			// An entry too large for the cache is simply not kept.
+			ResultCache.Put(key, data)
+		}
+	}
+}
+
+// cachingResponse passes a response through to the client as it is written,
+// keeping a copy of a successful one for the cache until it outgrows limit.
+type cachingResponse struct {
+	http.ResponseWriter
+	etag     string
+	limit    int64
+	status   int
+	sent     bool
+	body     bytes.Buffer
+	uncached bool
+	aborted  error
+}
+
+func (c *cachingResponse) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+// send passes the status and header on, once the first bytes of the body
+// are known. A successful response gets its ETag, and the Content-Type
+// net/http would sniff from body is pinned down, so hits match.
+func (c *cachingResponse) send(body []byte) {
+	c.sent = true
+	if c.status == http.StatusOK {
+		if c.Header().Get("Content-Type") == "" {
+			c.Header().Set("Content-Type", http.DetectContentType(body))
+		}
+		c.Header().Set("ETag", c.etag)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+}
+
+func (c *cachingResponse) Write(p []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	if !c.sent {
+		c.send(p)
+	}
+	if !c.uncached {
+		if c.limit > 0 && int64(c.body.Len()+len(p)) > c.limit {
+			c.uncached = true
+			c.body = bytes.Buffer{}
+		} else {
+			c.body.Write(p)
+		}
+	}
+
+	n, err := c.ResponseWriter.Write(p)
+	if err != nil {
+		c.uncached = true
+	}
+	return n, err
+}
+
+func (c *cachingResponse) abort(err error) {
+	c.aborted = err
+}
+
+// cacheKey hashes everything a response depends on. Parameters are sorted
+// so their order does not matter, and uploads are hashed by content, so
+// the multipart boundary does not either.
+func cacheKey(operation string, r *http.Request) (string, error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q\n", operation, r.Header.Get("Accept"))
+	for _, name := range sortedKeys(r.Form) {
+		fmt.Fprintf(h, "%q=%q\n", name, r.Form[name])
+	}
+	if r.MultipartForm != nil {
+		for _, field := range sortedKeys(r.MultipartForm.File) {
+			for _, file := range r.MultipartForm.File[field] {
+				fmt.Fprintf(h, "%q %q %q %d\n", field, file.Filename, file.Header.Get("Content-Type"), file.Size)
+				if err := hashFile(h, file); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h hash.Hash, file *multipart.FileHeader) error {
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// etagMatches reports whether an If-None-Match header lists etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,145 +1,674 @@
 package api
 
 import (
-	"encoding/csv"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"league/internal/jobs"
+	"league/internal/matrixoperations"
 	"league/internal/utils"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 type MatrixProcessor interface {
 	String() string
+	WriteTo(w io.Writer) (int64, error)
 	Flatten() string
 	Invert()
 	Sum() (int64, error)
 	Multiply() (int64, error)
+	SumAxis(axis matrixoperations.Axis) ([]int64, error)
+	MultiplyAxis(axis matrixoperations.Axis) ([]int64, error)
+	Min(axis matrixoperations.Axis) ([]int64, error)
+	Max(axis matrixoperations.Axis) ([]int64, error)
+	Mean(axis matrixoperations.Axis) ([]float64, error)
+	Count(axis matrixoperations.Axis) ([]int64, error)
+	Stats(axis matrixoperations.Axis, opts matrixoperations.StatsOptions) ([]matrixoperations.Stats, error)
+	Rotate(degrees int) error
+	FlipHorizontal()
+	FlipVertical()
+	AntiTranspose()
+	Reshape(rows, cols int) error
+	Shape() (int, int)
+	Cells() [][]string
+	Select(rows, cols []int) error
+
+	// The Context variants stop early once the request's context ends.
+	CellsContext(ctx context.Context) ([][]string, error)
+	WriteToContext(ctx context.Context, w io.Writer) (int64, error)
+	FlattenContext(ctx context.Context) (string, error)
+	InvertContext(ctx context.Context) error
+	SumContext(ctx context.Context) (int64, error)
+	MultiplyContext(ctx context.Context) (int64, error)
+	SumAxisContext(ctx context.Context, axis matrixoperations.Axis) ([]int64, error)
+	MultiplyAxisContext(ctx context.Context, axis matrixoperations.Axis) ([]int64, error)
+	MinContext(ctx context.Context, axis matrixoperations.Axis) ([]int64, error)
+	MaxContext(ctx context.Context, axis matrixoperations.Axis) ([]int64, error)
+	MeanContext(ctx context.Context, axis matrixoperations.Axis) ([]float64, error)
+	CountContext(ctx context.Context, axis matrixoperations.Axis) ([]int64, error)
+	StatsContext(ctx context.Context, axis matrixoperations.Axis, opts matrixoperations.StatsOptions) ([]matrixoperations.Stats, error)
+	RotateContext(ctx context.Context, degrees int) error
+	FlipHorizontalContext(ctx context.Context) error
+	FlipVerticalContext(ctx context.Context) error
+	AntiTransposeContext(ctx context.Context) error
+	ReshapeContext(ctx context.Context, rows, cols int) error
+}
+
+// parseOptions controls how an uploaded matrix is parsed and which part of
+// it is kept.
+type parseOptions struct {
+	// rows and cols are selectors, see utils.ParseSelector.
+	rows, cols string
+	// nullTokens are the cell values read as null in an otherwise numeric
+	// matrix, and nullPolicy decides how operations treat them.
+	nullTokens []string
+	nullPolicy matrixoperations.NullPolicy
+	// ragged decides what happens to rows of differing lengths, and pad
+	// fills the cells added under utils.RaggedPad.
+	ragged utils.RaggedMode
+	pad    string
+}
+
+var defaultNullTokens = []string{"", "NA"}
+
+func parseOptionsFromRequest(r *http.Request) (parseOptions, error) {
+	opts := parseOptions{
+		rows:       r.FormValue("rows"),
+		cols:       r.FormValue("cols"),
+		nullTokens: defaultNullTokens,
+		pad:        r.FormValue("pad"),
+	}
+
+	if _, ok := r.Form["null_tokens"]; ok {
+		opts.nullTokens = strings.Split(r.FormValue("null_tokens"), ",")
+	}
+	policy, err := matrixoperations.ParseNullPolicy(r.FormValue("nulls"))
+	if err != nil {
+		return opts, err
+	}
+	opts.nullPolicy = policy
+	if opts.ragged, err = utils.ParseRaggedMode(r.FormValue("ragged")); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// loadMatrix reads and parses the matrix uploaded in field, or loads the
+// stored matrix named by the field's ID parameter instead. When err is
+// non-nil, status is the HTTP status to respond with. A job running the
+// request counts as half done once its upload is parsed.
+func loadMatrix(r *http.Request, field string) (MatrixProcessor, int, error) {
+	opts, err := parseOptionsFromRequest(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if id := r.FormValue(idParams[field]); id != "" {
+		matrix, err := loadStoredMatrix(id, opts)
+		if err != nil {
+			return nil, storeErrorStatus(err), err
+		}
+		jobs.ReportProgress(r.Context(), 0.5)
+		return matrix, http.StatusOK, nil
+	}
+	table, err := parseCSVFromRequest(r, field)
+	if err != nil {
+		return nil, readErrorStatus(err), err
+	}
+	matrix, err := parseMatrix(table, opts)
+	if err != nil {
+		return nil, parseErrorStatus(err), err
+	}
+	jobs.ReportProgress(r.Context(), 0.5)
+
+	return matrix, http.StatusOK, nil
+}
+
+// readErrorStatus is the status for an upload that could not be read,
+// 413 if it declares a matrix too large to hold and otherwise 400.
+func readErrorStatus(err error) int {
+	if errors.Is(err, matrixoperations.ErrTooManyCells) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
 }
 
-// parseMatrix tries to parse [][]string as MatrixProcessor
-func parseMatrix(data [][]string) (MatrixProcessor, error) {
-	if len(data) == 0 {
+func parseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrInvalidSelector), errors.Is(err, utils.ErrRaggedRows), errors.Is(err, matrixoperations.ErrTooManyCells):
+		return http.StatusBadRequest
+	case errors.Is(err, utils.ErrSelectorOutOfRange):
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
+// SparseThreshold is the density, the share of nonzero cells, below which
+// an integer matrix is held as a SparseMatrix. Sparse uploads at or above it
+// are expanded into a NumericMatrix.
+var SparseThreshold = 0.1
+
+// parseMatrix evens out ragged rows as opts.ragged asks, tries to parse the
+// table records as MatrixProcessor, wraps it in a LabeledMatrix when the table
+// has labels, then applies the rows and cols selectors from opts. Integer
+// matrices sparser than SparseThreshold become a SparseMatrix.
+func parseMatrix(table utils.Table, opts parseOptions) (MatrixProcessor, error) {
+	if table.Sparse != nil {
+		return parseSparseMatrix(table.Sparse, opts)
+	}
+	if len(table.Records) == 0 {
 		return nil, fmt.Errorf("matrix is empty")
 	}
+	table, _, err := utils.NormalizeRagged(table, opts.ragged, opts.pad)
+	if err != nil {
+		return nil, err
+	}
+	data := table.Records
 
+	var matrix MatrixProcessor
 	// Try int parsing first
-	intMatrix, err := utils.ParseIntMatrix(data)
-	if err == nil {
-		return &intMatrix, nil
+	if intMatrix, err := utils.ParseIntMatrix(data); err == nil {
+		matrix = &intMatrix
+		if intMatrix.Density() < SparseThreshold {
+			matrix = matrixoperations.SparseFromDense(intMatrix)
+		}
+	} else if nullable, err := utils.ParseNullableMatrix(data, opts.nullTokens, opts.nullPolicy); err == nil && opts.nullTokens != nil {
+		// Numeric apart from null cells
+		matrix = nullable
+	} else if stringMatrix, err := utils.ParseStringMatrix(data); err == nil {
+		// Fallback to string
+		matrix = &stringMatrix
+	} else {
+		return nil, fmt.Errorf("unable to parse matrix as int type or string type")
 	}
 
-	// Fallback to string
-	stringMatrix, err := utils.ParseStringMatrix(data)
-	if err == nil {
-		return &stringMatrix, nil
+	if table.Header != nil || table.Index != nil {
+		matrix = &LabeledMatrix{
+			MatrixProcessor: matrix,
+			Labels: matrixoperations.Labels{
+				Corner: table.Corner,
+				Rows:   table.Index,
+				Cols:   table.Header,
+			},
+		}
+	}
+
+	if err := applySelectors(matrix, opts); err != nil {
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+// parseSparseMatrix keeps a matrix read from a sparse format as it is, or
+// expands it when it is too dense to benefit, then applies the selectors.
+func parseSparseMatrix(sparse *matrixoperations.SparseMatrix, opts parseOptions) (MatrixProcessor, error) {
+	if rows, cols := sparse.Shape(); rows == 0 || cols == 0 {
+		return nil, fmt.Errorf("matrix is empty")
+	}
+
+	var matrix MatrixProcessor = sparse
+	if sparse.Density() >= SparseThreshold {
+		dense := sparse.Dense()
+		matrix = &dense
+	}
+	if err := applySelectors(matrix, opts); err != nil {
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+func applySelectors(matrix MatrixProcessor, opts parseOptions) error {
+	rows, cols := matrix.Shape()
+	rowIndices, err := utils.ParseSelector(opts.rows, rows)
+	if err != nil {
+		return fmt.Errorf("rows=%s: %w, matrix shape is %dx%d", opts.rows, err, rows, cols)
+	}
+	colIndices, err := utils.ParseSelector(opts.cols, cols)
+	if err != nil {
+		return fmt.Errorf("cols=%s: %w, matrix shape is %dx%d", opts.cols, err, rows, cols)
+	}
+	if rowIndices == nil && colIndices == nil {
+		return nil
+	}
+	if err := matrix.Select(rowIndices, colIndices); err != nil {
+		return fmt.Errorf("rows=%s cols=%s: %w", opts.rows, opts.cols, err)
 	}
 
-	return nil, fmt.Errorf("unable to parse matrix as int type or string type")
+	return nil
 }
 
-func parseCSVFromRequest(r *http.Request) ([][]string, error) {
-	var records [][]string
-	file, _, err := r.FormFile("file")
+// parseCSVFromRequest reads the file uploaded in field in the format picked
+// by inputFormat. The file is decoded from the charset request parameter,
+// falling back to the charset of its Content-Type and then to its byte order
+// mark. For delimited formats the dialect starts from the format's delimiter
+// and the file's Content-Type, and is refined by the delimiter, comment,
+// lazy_quotes, trim_space, header and index request parameters.
+func parseCSVFromRequest(r *http.Request, field string) (utils.Table, error) {
+	file, fileHeader, err := r.FormFile(field)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file from request: %w", err)
+		return utils.Table{}, fmt.Errorf("failed to get %s from request: %w", field, err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err = reader.ReadAll()
+	contentType := fileHeader.Header.Get("Content-Type")
+	format, err := inputFormat(r, fileHeader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		return utils.Table{}, err
+	}
+	dialect, err := dialectFromRequest(r, contentType, format)
+	if err != nil {
+		return utils.Table{}, err
+	}
+	charset, err := charsetFromRequest(r, contentType)
+	if err != nil {
+		return utils.Table{}, err
 	}
 
-	return records, nil
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return utils.Table{}, fmt.Errorf("failed to read %s: %w", field, err)
+	}
+	if !format.Binary {
+		if data, err = utils.DecodeText(data, charset); err != nil {
+			return utils.Table{}, fmt.Errorf("failed to decode %s: %w", field, err)
+		}
+	}
+
+	table, err := format.Read(bytes.NewReader(data), dialect)
+	if err != nil {
+		return utils.Table{}, fmt.Errorf("failed to read %s file: %w", strings.ToUpper(format.Name), err)
+	}
+
+	return table, nil
+}
+
+// inputFormat picks the reader for an upload from the input_format request
+// parameter, then the upload's Content-Type, then its file extension, and
+// defaults to CSV.
+func inputFormat(r *http.Request, fileHeader *multipart.FileHeader) (*utils.Format, error) {
+	if name := r.FormValue("input_format"); name != "" {
+		return utils.LookupFormat(name)
+	}
+	if mediaType, _, err := mime.ParseMediaType(fileHeader.Header.Get("Content-Type")); err == nil {
+		if format := utils.FormatForMediaType(mediaType); format != nil {
+			return format, nil
+		}
+	}
+	if format := utils.FormatForFilename(fileHeader.Filename); format != nil {
+		return format, nil
+	}
+
+	return utils.CSVFormat, nil
+}
+
+// outputFormat picks the writer for a response from the format request
+// parameter, then the most preferred registered type in the Accept header,
+// and defaults to CSV.
+func outputFormat(r *http.Request) (*utils.Format, error) {
+	if name := r.FormValue("format"); name != "" {
+		return utils.LookupFormat(name)
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []accepted
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		ranges = append(ranges, accepted{mediaType, q})
+	}
+	slices.SortStableFunc(ranges, func(a, b accepted) int {
+		return cmp.Compare(b.q, a.q)
+	})
+	for _, ar := range ranges {
+		if format := utils.FormatForMediaType(ar.mediaType); format != nil {
+			return format, nil
+		}
+	}
+
+	return utils.CSVFormat, nil
+}
+
+func charsetFromRequest(r *http.Request, contentType string) (utils.Charset, error) {
+	name := r.FormValue("charset")
+	if name == "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			name = params["charset"]
+		}
+	}
+
+	return utils.ParseCharset(name)
+}
+
+func dialectFromRequest(r *http.Request, contentType string, format *utils.Format) (utils.Dialect, error) {
+	dialect := utils.DialectFromMediaType(contentType)
+	if format.Delimiter != 0 {
+		dialect.Delimiter = format.Delimiter
+	}
+
+	var err error
+	if v := r.FormValue("delimiter"); v != "" {
+		if dialect.Delimiter, err = utils.ParseDelimiter(v); err != nil {
+			return dialect, fmt.Errorf("delimiter: %w", err)
+		}
+	}
+	if v := r.FormValue("comment"); v != "" {
+		if dialect.Comment, err = utils.ParseDelimiter(v); err != nil {
+			return dialect, fmt.Errorf("comment: %w", err)
+		}
+	}
+	for name, flag := range map[string]*bool{
+		"lazy_quotes": &dialect.LazyQuotes,
+		"trim_space":  &dialect.TrimLeadingSpace,
+		"header":      &dialect.Header,
+		"index":       &dialect.Index,
+	} {
+		if v := r.FormValue(name); v != "" {
+			if *flag, err = strconv.ParseBool(v); err != nil {
+				return dialect, fmt.Errorf("%w: %s=%q is not a boolean", utils.ErrInvalidDialect, name, v)
+			}
+		}
+	}
+
+	return dialect, nil
 }
 
 func EchoHandler(w http.ResponseWriter, r *http.Request) {
-	records, err := parseCSVFromRequest(r)
+	matrix, status, err := loadMatrix(r, "file")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
-	matrix, err := parseMatrix(records)
+
+	respondMatrix(w, r, matrix)
+}
+
+func InvertHandler(w http.ResponseWriter, r *http.Request) {
+	matrix, status, err := loadMatrix(r, "file")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err := matrix.InvertContext(r.Context()); err != nil {
+		operationError(w, err)
 		return
 	}
 
-	respond(w, 200, matrix.String())
+	respondMatrix(w, r, matrix)
 }
 
-func InvertHandler(w http.ResponseWriter, r *http.Request) {
-	records, err := parseCSVFromRequest(r)
+func RotateHandler(w http.ResponseWriter, r *http.Request) {
+	degrees, err := strconv.Atoi(r.FormValue("degrees"))
 	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid degrees %q", r.FormValue("degrees")), http.StatusBadRequest)
+		return
+	}
+
+	transformHandler(w, r, func(m MatrixProcessor) error {
+		return m.RotateContext(r.Context(), degrees)
+	})
+}
+
+func FlipHorizontalHandler(w http.ResponseWriter, r *http.Request) {
+	transformHandler(w, r, func(m MatrixProcessor) error {
+		return m.FlipHorizontalContext(r.Context())
+	})
+}
+
+func FlipVerticalHandler(w http.ResponseWriter, r *http.Request) {
+	transformHandler(w, r, func(m MatrixProcessor) error {
+		return m.FlipVerticalContext(r.Context())
+	})
+}
+
+func AntiTransposeHandler(w http.ResponseWriter, r *http.Request) {
+	transformHandler(w, r, func(m MatrixProcessor) error {
+		return m.AntiTransposeContext(r.Context())
+	})
+}
+
+func ReshapeHandler(w http.ResponseWriter, r *http.Request) {
+	// rows and cols are taken by the selectors, so the target shape is
+	// passed as a single RxC parameter.
+	shape := r.FormValue("shape")
+	rowsParam, colsParam, _ := strings.Cut(shape, "x")
+	rows, rowsErr := strconv.Atoi(rowsParam)
+	cols, colsErr := strconv.Atoi(colsParam)
+	if rowsErr != nil || colsErr != nil {
+		http.Error(w, fmt.Sprintf("invalid shape %q, want RxC", shape), http.StatusBadRequest)
+		return
+	}
+
+	transformHandler(w, r, func(m MatrixProcessor) error {
+		return m.ReshapeContext(r.Context(), rows, cols)
+	})
+}
+
+// transformHandler applies a shape-changing operation and responds with the
+// resulting matrix.
+func transformHandler(w http.ResponseWriter, r *http.Request, transform func(MatrixProcessor) error) {
+	matrix, status, err := loadMatrix(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	err = transform(matrix)
+	if errors.Is(err, matrixoperations.ErrInvalidRotation) || errors.Is(err, matrixoperations.ErrInvalidShape) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	matrix, err := parseMatrix(records)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		operationError(w, err)
 		return
 	}
-	matrix.Invert()
 
-	respond(w, 200, matrix.String())
+	respondMatrix(w, r, matrix)
 }
 
 func FlattenHandler(w http.ResponseWriter, r *http.Request) {
-	records, err := parseCSVFromRequest(r)
+	matrix, status, err := loadMatrix(r, "file")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
-	matrix, err := parseMatrix(records)
+
+	format, err := outputFormat(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format != utils.CSVFormat {
+		cells, err := matrix.CellsContext(r.Context())
+		if err != nil {
+			operationError(w, err)
+			return
+		}
+		var flat []string
+		for _, row := range cells {
+			flat = append(flat, row...)
+		}
+		respondTable(w, format, utils.Table{Records: [][]string{flat}})
 		return
 	}
 
-	flat := matrix.Flatten()
+	flat, err := matrix.FlattenContext(r.Context())
+	if err != nil {
+		operationError(w, err)
+		return
+	}
 	respond(w, 200, flat)
 }
 
 func SumHandler(w http.ResponseWriter, r *http.Request) {
-	records, err := parseCSVFromRequest(r)
+	aggregateHandler(w, r, MatrixProcessor.SumAxisContext)
+}
+
+func MultiplyHandler(w http.ResponseWriter, r *http.Request) {
+	aggregateHandler(w, r, MatrixProcessor.MultiplyAxisContext)
+}
+
+func MinHandler(w http.ResponseWriter, r *http.Request) {
+	aggregateHandler(w, r, MatrixProcessor.MinContext)
+}
+
+func MaxHandler(w http.ResponseWriter, r *http.Request) {
+	aggregateHandler(w, r, MatrixProcessor.MaxContext)
+}
+
+func CountHandler(w http.ResponseWriter, r *http.Request) {
+	aggregateHandler(w, r, MatrixProcessor.CountContext)
+}
+
+func MeanHandler(w http.ResponseWriter, r *http.Request) {
+	aggregateHandler(w, r, MatrixProcessor.MeanContext)
+}
+
+// aggregateHandler runs an axis-aware aggregate and responds with one value
+// per lane. With the default axis=all the response is a single value, which
+// keeps /sum and /multiply backwards compatible.
+func aggregateHandler[T int64 | float64](w http.ResponseWriter, r *http.Request, aggregate func(MatrixProcessor, context.Context, matrixoperations.Axis) ([]T, error)) {
+	axis, err := matrixoperations.ParseAxis(r.FormValue("axis"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	matrix, err := parseMatrix(records)
+	matrix, status, err := loadMatrix(r, "file")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	sum, err := matrix.Sum()
+	values, err := aggregate(matrix, r.Context(), axis)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		operationError(w, err)
 		return
 	}
 
-	respond(w, 200, sum)
+	respondVector(w, r, values, labelsFor(matrix, axis))
 }
 
-func MultiplyHandler(w http.ResponseWriter, r *http.Request) {
-	records, err := parseCSVFromRequest(r)
+// respondMatrix writes matrix in the format picked by outputFormat.
+func respondMatrix(w http.ResponseWriter, r *http.Request, matrix MatrixProcessor) {
+	format, err := outputFormat(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	matrix, err := parseMatrix(records)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	if format == utils.CSVFormat {
+		streamMatrix(w, r, matrix)
 		return
 	}
 
-	product, err := matrix.Multiply()
+	cells, err := matrix.CellsContext(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		operationError(w, err)
+		return
+	}
+
+	table := utils.Table{Records: cells}
+	if labeled, ok := matrix.(*LabeledMatrix); ok {
+		table.Corner = labeled.Labels.Corner
+		table.Header = labeled.Labels.Cols
+		table.Index = labeled.Labels.Rows
+	}
+	respondTable(w, format, table)
+}
+
+// streamMatrix writes matrix as CSV straight to w, so the response is never
+// held in memory whole. A failure before the first byte is reported as
+// usual; after it the status has gone out, and the response is aborted
+// instead so it cannot pass for a complete one.
+func streamMatrix(w http.ResponseWriter, r *http.Request, matrix MatrixProcessor) {
+	w.Header().Set("Content-Type", utils.CSVFormat.MediaTypes[0])
+	n, err := matrix.WriteToContext(r.Context(), w)
+	if err == nil {
+		_, err = io.WriteString(w, "\n")
+	}
+	switch {
+	case err == nil:
+	case n == 0:
+		operationError(w, err)
+	default:
+		abortResponse(w, err)
+	}
+}
+
+// An aborter is a ResponseWriter that can be told its response was cut
+// short after the status went out.
+type aborter interface {
+	abort(err error)
+}
+
+// abortResponse gives up on a response that is partly written. A client
+// connection is closed mid-response, which is how net/http tells the
+// client the body is incomplete.
+func abortResponse(w http.ResponseWriter, err error) {
+	fmt.Printf("response cut short: %v\n", err)
+	if a, ok := w.(aborter); ok {
+		a.abort(err)
 		return
 	}
+	panic(http.ErrAbortHandler)
+}
 
-	respond(w, 200, product)
+// respondTable writes table with format's writer, or responds with 406 when
+// the format cannot hold it.
+func respondTable(w http.ResponseWriter, format *utils.Format, table utils.Table) {
+	var body strings.Builder
+	if err := format.Write(&body, table); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrUnrepresentable) {
+			status = http.StatusNotAcceptable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.MediaTypes[0])
+	if format.Binary {
+		w.WriteHeader(200)
+		io.WriteString(w, body.String())
+		return
+	}
+	respond(w, 200, body.String())
+}
+
+// operationError responds to an operation that failed with err. One that
+// ran out of time gets 504, and one whose request was canceled, because the
+// client went away or the server is shutting down, gets 503.
+func operationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "operation timed out", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled):
+		http.Error(w, "operation canceled", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+	}
 }
 
 func respond(w http.ResponseWriter, status int, body interface{}) {
@@ -149,3 +678,298 @@ func respond(w http.ResponseWriter, status int, body interface{}) {
 		fmt.Printf("failed to write response: %v\n", err)
 	}
 }
+
+func AddHandler(w http.ResponseWriter, r *http.Request) {
+	elementwiseHandler(w, r, matrixoperations.OpAdd)
+}
+
+func SubtractHandler(w http.ResponseWriter, r *http.Request) {
+	elementwiseHandler(w, r, matrixoperations.OpSubtract)
+}
+
+func HadamardHandler(w http.ResponseWriter, r *http.Request) {
+	elementwiseHandler(w, r, matrixoperations.OpHadamard)
+}
+
+func DivideHandler(w http.ResponseWriter, r *http.Request) {
+	elementwiseHandler(w, r, matrixoperations.OpDivide)
+}
+
+// elementwiseHandler combines the uploaded file with either a second upload
+// in the other field or a scalar parameter.
+func elementwiseHandler(w http.ResponseWriter, r *http.Request, op matrixoperations.Operator) {
+	scalarParam := r.FormValue("scalar")
+	var scalar int64
+	if scalarParam != "" {
+		var err error
+		scalar, err = strconv.ParseInt(scalarParam, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid scalar %q", scalarParam), http.StatusBadRequest)
+			return
+		}
+		if _, _, err := r.FormFile("other"); err == nil || r.FormValue(idParams["other"]) != "" {
+			http.Error(w, "provide either an other matrix or a scalar, not both", http.StatusBadRequest)
+			return
+		}
+	}
+
+	matrix, status, err := loadMatrix(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	// Elementwise results are rarely as sparse as their operands, so they
+	// are computed densely.
+	matrix = replaceMatrix(matrix, densify(unwrapMatrix(matrix)))
+
+	if scalarParam != "" {
+		err = applyElementwiseScalar(r.Context(), unwrapMatrix(matrix), op, scalar)
+	} else {
+		var other MatrixProcessor
+		var status int
+		other, status, err = loadMatrix(r, "other")
+		if status == http.StatusBadRequest {
+			http.Error(w, fmt.Sprintf("%s: provide an other matrix or a scalar", err), status)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		var result MatrixProcessor
+		result, err = applyElementwise(r.Context(), unwrapMatrix(matrix), densify(unwrapMatrix(other)), op)
+		if err == nil {
+			matrix = replaceMatrix(matrix, result)
+		}
+	}
+	if errors.Is(err, matrixoperations.ErrShapeMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		operationError(w, err)
+		return
+	}
+
+	respondMatrix(w, r, matrix)
+}
+
+// densify expands a SparseMatrix into a NumericMatrix and returns any other
+// matrix unchanged.
+func densify(matrix MatrixProcessor) MatrixProcessor {
+	if sparse, ok := matrix.(*matrixoperations.SparseMatrix); ok {
+		dense := sparse.Dense()
+		return &dense
+	}
+	return matrix
+}
+
+func applyElementwiseScalar(ctx context.Context, matrix MatrixProcessor, op matrixoperations.Operator, scalar int64) error {
+	switch m := matrix.(type) {
+	case *matrixoperations.NumericMatrix:
+		return m.ElementwiseScalarContext(ctx, op, scalar)
+	case *matrixoperations.NullableMatrix:
+		return m.ElementwiseScalarContext(ctx, op, scalar)
+	}
+
+	return matrixoperations.ErrUnsupportedOperation
+}
+
+// applyElementwise combines two numeric operands and returns the result. A
+// NumericMatrix is promoted to a NullableMatrix when the other operand has
+// nulls; the left operand's null policy applies.
+func applyElementwise(ctx context.Context, left, right MatrixProcessor, op matrixoperations.Operator) (MatrixProcessor, error) {
+	switch l := left.(type) {
+	case *matrixoperations.NumericMatrix:
+		switch r := right.(type) {
+		case *matrixoperations.NumericMatrix:
+			return l, l.ElementwiseContext(ctx, op, *r)
+		case *matrixoperations.NullableMatrix:
+			promoted := matrixoperations.NewNullableMatrix(*l, r.Policy, r.Token)
+			return promoted, promoted.ElementwiseContext(ctx, op, r)
+		}
+	case *matrixoperations.NullableMatrix:
+		switch r := right.(type) {
+		case *matrixoperations.NumericMatrix:
+			return l, l.ElementwiseContext(ctx, op, matrixoperations.NewNullableMatrix(*r, l.Policy, l.Token))
+		case *matrixoperations.NullableMatrix:
+			return l, l.ElementwiseContext(ctx, op, r)
+		}
+	}
+
+	return nil, matrixoperations.ErrUnsupportedOperation
+}
+
+// StatsHandler describes each lane selected by axis. Percentiles are taken
+// from a comma-separated p list and the histogram bucket count from bins.
+// The result is always a JSON array with one entry per lane.
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	axis, err := matrixoperations.ParseAxis(r.FormValue("axis"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseStatsOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format := r.FormValue("format"); format != "" && format != "json" {
+		http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+		return
+	}
+	matrix, status, err := loadMatrix(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	stats, err := matrix.StatsContext(r.Context(), axis, opts)
+	if errors.Is(err, matrixoperations.ErrInvalidStatsOption) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		operationError(w, err)
+		return
+	}
+
+	if labels := labelsFor(matrix, axis); labels != nil {
+		for i := range stats {
+			stats[i].Label = labels[i]
+		}
+	}
+
+	respondJSON(w, 200, stats)
+}
+
+func parseStatsOptions(r *http.Request) (matrixoperations.StatsOptions, error) {
+	opts := matrixoperations.StatsOptions{
+		Percentiles: matrixoperations.DefaultPercentiles,
+		Bins:        matrixoperations.DefaultHistogramBins,
+	}
+
+	if p := r.FormValue("p"); p != "" {
+		opts.Percentiles = nil
+		for _, field := range strings.Split(p, ",") {
+			val, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid percentile %q", field)
+			}
+			opts.Percentiles = append(opts.Percentiles, val)
+		}
+	}
+	if bins := r.FormValue("bins"); bins != "" {
+		n, err := strconv.Atoi(bins)
+		if err != nil {
+			return opts, fmt.Errorf("invalid bins %q", bins)
+		}
+		opts.Bins = n
+	}
+
+	return opts, nil
+}
+
+// validationReport is the response of /validate. Valid is true when every
+// cell is an integer or a null token. Type is what the other endpoints would
+// parse the matrix as, and Error why they would reject it.
+type validationReport struct {
+	Valid     bool          `json:"valid"`
+	Type      string        `json:"type,omitempty"`
+	Rows      int           `json:"rows"`
+	Cols      int           `json:"cols"`
+	Error     string        `json:"error,omitempty"`
+	Issues    []utils.Issue `json:"issues"`
+	Truncated bool          `json:"truncated"`
+	// AdjustedRows are the rows ragged=pad or ragged=truncate evened out.
+	AdjustedRows []utils.RaggedRow `json:"adjusted_rows,omitempty"`
+}
+
+// ValidateHandler scans the whole upload and reports up to max_issues
+// problems without running an operation.
+func ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseOptionsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := utils.DefaultMaxIssues
+	if v := r.FormValue("max_issues"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, fmt.Sprintf("invalid max_issues %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+	table, err := parseCSVFromRequest(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), readErrorStatus(err))
+		return
+	}
+
+	// Ragged rows are reported as issues unless they are evened out, and
+	// the whole upload is validated regardless of any selectors.
+	var report validationReport
+	if opts.ragged != utils.RaggedError {
+		table, report.AdjustedRows, _ = utils.NormalizeRagged(table, opts.ragged, opts.pad)
+	}
+	opts.rows, opts.cols = "", ""
+
+	report.Issues, report.Truncated = utils.Validate(table.Records, opts.nullTokens, limit)
+	if matrix, err := parseMatrix(table, opts); err != nil {
+		report.Error = err.Error()
+	} else {
+		report.Rows, report.Cols = matrix.Shape()
+		report.Type = matrixType(matrix)
+	}
+	report.Valid = report.Error == "" && len(report.Issues) == 0
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// respondVector writes values as a comma-separated line, or as a JSON array
+// when the request asks for format=json. Labeled values are preceded by a
+// line of labels, or returned as a JSON object with labels and values.
+func respondVector[T int64 | float64](w http.ResponseWriter, r *http.Request, values []T, labels []string) {
+	format, err := outputFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strValues := make([]string, len(values))
+	for i, val := range values {
+		switch v := any(val).(type) {
+		case int64:
+			strValues[i] = strconv.FormatInt(v, 10)
+		case float64:
+			strValues[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+
+	switch format {
+	case utils.CSVFormat:
+		body := strings.Join(strValues, ",")
+		if labels != nil {
+			body = strings.Join(labels, ",") + "\n" + body
+		}
+		respond(w, 200, body)
+	case utils.JSONFormat:
+		if labels != nil {
+			respondJSON(w, 200, map[string]interface{}{"labels": labels, "values": values})
+			return
+		}
+		respondJSON(w, 200, values)
+	default:
+		// Other formats get a single row, headed by the labels.
+		respondTable(w, format, utils.Table{Header: labels, Records: [][]string{strValues}})
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		// log error
+		fmt.Printf("failed to write response: %v\n", err)
+	}
+}
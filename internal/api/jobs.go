@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"league/internal/auth"
+	"league/internal/jobs"
+	"league/internal/ratelimit"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Operations maps each operation name to its handler. main serves each at
+// /<name>, and POST /jobs runs them by name.
+var Operations = map[string]http.HandlerFunc{
+	"echo":            EchoHandler,
+	"invert":          InvertHandler,
+	"sum":             SumHandler,
+	"multiply":        MultiplyHandler,
+	"flatten":         FlattenHandler,
+	"min":             MinHandler,
+	"max":             MaxHandler,
+	"mean":            MeanHandler,
+	"count":           CountHandler,
+	"stats":           StatsHandler,
+	"add":             AddHandler,
+	"subtract":        SubtractHandler,
+	"hadamard":        HadamardHandler,
+	"divide":          DivideHandler,
+	"rotate":          RotateHandler,
+	"flip-horizontal": FlipHorizontalHandler,
+	"flip-vertical":   FlipVerticalHandler,
+	"anti-transpose":  AntiTransposeHandler,
+	"reshape":         ReshapeHandler,
+	"validate":        ValidateHandler,
+}
+
+// JobHandler serves the asynchronous job API. A job replays its submission
+// against the handler of the operation it names, so it takes the same
+// uploads and parameters and produces the same output as the synchronous
+// endpoint.
+type JobHandler struct {
+	Pool *jobs.Pool
+	// MaxUpload caps the bytes of a submission, which is held in memory
+	// until the job runs.
+	MaxUpload int64
+	// RateLimits gives the rate limit of each operation, which a job
+	// running it counts against as a request to its endpoint would; nil
+	// leaves jobs unmetered.
+	RateLimits func(operation string) ratelimit.Limit
+	// Timeouts gives the timeout of each operation, which bounds a job
+	// running it as it would a request to its endpoint; nil leaves jobs
+	// unbounded.
+	Timeouts func(operation string) time.Duration
+}
+
+// Submit queues the operation named by the operation parameter and
+// responds 202 with the job and its URL in Location. The key a request
+// authenticated with needs the operation's scope as well as the jobs one,
+// and the job takes a token from the operation's rate limit. Once a worker
+// picks the job up, it waits for Admission and runs under the operation's
+// timeout as a request to the endpoint would, failing if turned away or
+// timed out.
+func (h *JobHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.MaxUpload))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to read upload: %s", err), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	name := r.FormValue("operation")
+	handler, ok := Operations[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown operation %q", name), http.StatusBadRequest)
+		return
+	}
+	if p, ok := auth.FromContext(r.Context()); ok && !p.Allows(name) {
+		forbidden(w, p, name)
+		return
+	}
+	if h.RateLimits != nil && !allowed(w, r, name, h.RateLimits(name)) {
+		return
+	}
+	var timeout time.Duration
+	if h.Timeouts != nil {
+		timeout = h.Timeouts(name)
+	}
+	run := Admitted(WithTimeout(timeout, handler))
+	if _, _, err := r.FormFile("file"); err != nil && r.FormValue(idParams["file"]) == "" {
+		http.Error(w, fmt.Sprintf("failed to get file from request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	url := "/" + name + "?" + r.URL.RawQuery
+	header := http.Header{}
+	for _, key := range []string{"Content-Type", "Accept"} {
+		if value := r.Header.Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+	job, err := h.Pool.Submit(name, func(ctx context.Context) (*jobs.Output, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header
+
+		var out bufferedResponse
+		run(&out, req)
+		if out.aborted != nil {
+			return nil, out.aborted
+		}
+		if out.status >= 400 {
+			return nil, errors.New(strings.TrimSpace(out.body.String()))
+		}
+		return &jobs.Output{ContentType: out.Header().Get("Content-Type"), Body: out.body.Bytes()}, nil
+	})
+	if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrShuttingDown) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+// Status reports a job's status and progress.
+func (h *JobHandler) Status(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// Result streams the output of a succeeded job with the Content-Type the
+// operation responded with. Any other job responds 409.
+func (h *JobHandler) Result(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	switch job.Status {
+	case jobs.StatusSucceeded:
+	case jobs.StatusFailed:
+		http.Error(w, fmt.Sprintf("job %s failed: %s", job.ID, job.Error), http.StatusConflict)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("job %s is %s", job.ID, job.Status), http.StatusConflict)
+		return
+	}
+
+	if job.Output.ContentType != "" {
+		w.Header().Set("Content-Type", job.Output.ContentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, bytes.NewReader(job.Output.Body)); err != nil {
+		// log error
+		fmt.Printf("failed to write response: %v\n", err)
+	}
+}
+
+// Cancel stops a queued or running job and responds 202 with it, or
+// deletes a finished job and its result and responds 204.
+func (h *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	if job.Status.Done() {
+		h.Pool.Remove(job.ID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	job, err := h.Pool.Cancel(job.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+// lookup finds the job named by the id path value, responding 404 when
+// there is none.
+func (h *JobHandler) lookup(w http.ResponseWriter, r *http.Request) (jobs.Job, bool) {
+	id := r.PathValue("id")
+	job, err := h.Pool.Get(id)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		http.Error(w, fmt.Sprintf("job %s not found", id), http.StatusNotFound)
+		return job, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		return job, false
+	}
+
+	return job, true
+}
+
+// bufferedResponse collects what a handler writes so a job can store it.
+type bufferedResponse struct {
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	aborted error
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	if b.header == nil {
+		b.header = http.Header{}
+	}
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) abort(err error) {
+	b.aborted = err
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"league/internal/matrixoperations"
+	"strings"
+)
+
+// LabeledMatrix wraps a NumericMatrix or AlphanumericMatrix together with its
+// row and column labels. Every shape-changing operation is applied to the
+// labels as well, and String re-emits them as a header line and a leading
+// label column.
+type LabeledMatrix struct {
+	MatrixProcessor
+	Labels matrixoperations.Labels
+}
+
+// unwrapMatrix returns the matrix underneath any labels.
+func unwrapMatrix(m MatrixProcessor) MatrixProcessor {
+	if labeled, ok := m.(*LabeledMatrix); ok {
+		return labeled.MatrixProcessor
+	}
+	return m
+}
+
+// replaceMatrix swaps the matrix underneath any labels for inner and returns
+// the result.
+func replaceMatrix(m MatrixProcessor, inner MatrixProcessor) MatrixProcessor {
+	if labeled, ok := m.(*LabeledMatrix); ok {
+		labeled.MatrixProcessor = inner
+		return labeled
+	}
+	return inner
+}
+
+// labelsFor returns the labels of the lanes an aggregate over axis produces.
+func labelsFor(m MatrixProcessor, axis matrixoperations.Axis) []string {
+	if labeled, ok := m.(*LabeledMatrix); ok {
+		return labeled.Labels.ForAxis(axis)
+	}
+	return nil
+}
+
+func (l *LabeledMatrix) String() string {
+	var output strings.Builder
+	l.WriteTo(&output)
+	return output.String()
+}
+
+func (l *LabeledMatrix) WriteTo(w io.Writer) (int64, error) {
+	return l.WriteToContext(context.Background(), w)
+}
+
+// WriteToContext writes the matrix as CSV led by a header line of column
+// labels, with each row led by its label.
+func (l *LabeledMatrix) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	var written int64
+	if l.Labels.Cols != nil {
+		header := l.Labels.Cols
+		if l.Labels.Rows != nil {
+			header = append([]string{l.Labels.Corner}, header...)
+		}
+		n, err := io.WriteString(w, csvLine(header...))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if l.Labels.Rows == nil {
+		n, err := l.MatrixProcessor.WriteToContext(ctx, w)
+		return written + n, err
+	}
+	lw := &labelWriter{w: w, lineStart: true}
+	for _, label := range l.Labels.Rows {
+		lw.labels = append(lw.labels, strings.TrimSuffix(csvLine(label), "\n")+",")
+	}
+	_, err := l.MatrixProcessor.WriteToContext(ctx, lw)
+	return written + lw.written, err
+}
+
+// csvLine encodes fields as one line of CSV.
+func csvLine(fields ...string) string {
+	var line strings.Builder
+	writer := csv.NewWriter(&line)
+	writer.Write(fields)
+	writer.Flush()
+	return line.String()
+}
+
+// labelWriter passes CSV through to w, putting the next of labels in front
+// of each record. Newlines inside quoted fields do not start a record.
+type labelWriter struct {
+	w         io.Writer
+	labels    []string
+	row       int
+	lineStart bool
+	quoted    bool
+	written   int64
+	buf       []byte
+}
+
+func (lw *labelWriter) Write(p []byte) (int, error) {
+	lw.buf = lw.buf[:0]
+	for _, b := range p {
+		if lw.lineStart && lw.row < len(lw.labels) {
+			lw.buf = append(lw.buf, lw.labels[lw.row]...)
+			lw.row++
+		}
+		lw.lineStart = false
+		lw.buf = append(lw.buf, b)
+		switch {
+		case b == '"':
+			lw.quoted = !lw.quoted
+		case b == '\n' && !lw.quoted:
+			lw.lineStart = true
+		}
+	}
+
+	n, err := lw.w.Write(lw.buf)
+	lw.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l *LabeledMatrix) Invert() {
+	l.MatrixProcessor.Invert()
+	l.Labels.Invert()
+}
+
+func (l *LabeledMatrix) Rotate(degrees int) error {
+	if err := l.MatrixProcessor.Rotate(degrees); err != nil {
+		return err
+	}
+
+	l.Labels.Rotate(degrees)
+	return nil
+}
+
+func (l *LabeledMatrix) FlipHorizontal() {
+	l.MatrixProcessor.FlipHorizontal()
+	l.Labels.FlipHorizontal()
+}
+
+func (l *LabeledMatrix) FlipVertical() {
+	l.MatrixProcessor.FlipVertical()
+	l.Labels.FlipVertical()
+}
+
+func (l *LabeledMatrix) AntiTranspose() {
+	l.MatrixProcessor.AntiTranspose()
+	l.Labels.AntiTranspose()
+}
+
+// Reshape drops the labels, since rows and columns no longer correspond to
+// the original ones.
+func (l *LabeledMatrix) Reshape(rows, cols int) error {
+	if err := l.MatrixProcessor.Reshape(rows, cols); err != nil {
+		return err
+	}
+
+	l.Labels = matrixoperations.Labels{}
+	return nil
+}
+
+func (l *LabeledMatrix) Select(rows, cols []int) error {
+	if err := l.MatrixProcessor.Select(rows, cols); err != nil {
+		return err
+	}
+	l.Labels.Select(rows, cols)
+	return nil
+}
+
+func (l *LabeledMatrix) InvertContext(ctx context.Context) error {
+	if err := l.MatrixProcessor.InvertContext(ctx); err != nil {
+		return err
+	}
+
+	l.Labels.Invert()
+	return nil
+}
+
+func (l *LabeledMatrix) RotateContext(ctx context.Context, degrees int) error {
+	if err := l.MatrixProcessor.RotateContext(ctx, degrees); err != nil {
+		return err
+	}
+
+	l.Labels.Rotate(degrees)
+	return nil
+}
+
+func (l *LabeledMatrix) FlipHorizontalContext(ctx context.Context) error {
+	if err := l.MatrixProcessor.FlipHorizontalContext(ctx); err != nil {
+		return err
+	}
+
+	l.Labels.FlipHorizontal()
+	return nil
+}
+
+func (l *LabeledMatrix) FlipVerticalContext(ctx context.Context) error {
+	if err := l.MatrixProcessor.FlipVerticalContext(ctx); err != nil {
+		return err
+	}
+
+	l.Labels.FlipVertical()
+	return nil
+}
+
+func (l *LabeledMatrix) AntiTransposeContext(ctx context.Context) error {
+	if err := l.MatrixProcessor.AntiTransposeContext(ctx); err != nil {
+		return err
+	}
+
+	l.Labels.AntiTranspose()
+	return nil
+}
+
+func (l *LabeledMatrix) ReshapeContext(ctx context.Context, rows, cols int) error {
+	if err := l.MatrixProcessor.ReshapeContext(ctx, rows, cols); err != nil {
+		return err
+	}
+
+	l.Labels = matrixoperations.Labels{}
+	return nil
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"league/internal/auth"
+	"net/http"
+	"time"
+)
+
+// requestLog collects what a request's log line reports from the handlers
+// it passes through.
+type requestLog struct {
+	subject string
+}
+
+type requestLogKey struct{}
+
+// logSubject records who a request was authenticated as in its log line.
+func logSubject(ctx context.Context, subject string) {
+	if l, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		l.subject = subject
+	}
+}
+
+// Logged writes a line for each request once next has served it, with its
+// method, path, status, response size, duration, the subject it was
+// authenticated as and that of its client certificate, each - for none.
+func Logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		l := &requestLog{subject: "-"}
+		out := &loggingResponse{ResponseWriter: w}
+		cert, ok := auth.ClientSubject(r)
+		if !ok {
+			cert = "-"
+		}
+		// Deferred so that a response aborted by a panic is logged too.
+		defer func() {
+			fmt.Printf("%s %s %d %dB %s subject=%q cert=%q\n", r.Method, r.URL.Path, out.status, out.bytes, time.Since(start).Round(time.Microsecond), l.subject, cert)
+		}()
+
+		next.ServeHTTP(out, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, l)))
+	})
+}
+
+// loggingResponse notes the status and size of the response written
+// through it.
+type loggingResponse struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (l *loggingResponse) WriteHeader(status int) {
+	if l.status == 0 {
+		l.status = status
+	}
+	l.ResponseWriter.WriteHeader(status)
+}
+
+func (l *loggingResponse) Write(p []byte) (int, error) {
+	if l.status == 0 {
+		l.status = http.StatusOK
+	}
+	n, err := l.ResponseWriter.Write(p)
+	l.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection's writer.
+func (l *loggingResponse) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"league/internal/matrixoperations"
+	"league/internal/storage"
+	"net/http"
+)
+
+// MatrixStore holds the matrices uploaded to /matrices. main replaces it
+// with one configured from the environment.
+var MatrixStore storage.Store = storage.NewMemoryStore(storage.Limits{})
+
+// idParams names the parameter that refers to a stored matrix in place of
+// each upload field.
+var idParams = map[string]string{"file": "matrix_id", "other": "other_matrix_id"}
+
+// storedMatrix is the JSON a parsed matrix is kept as. Exactly one of the
+// value fields is set, as Type says.
+type storedMatrix struct {
+	Type    string                              `json:"type"`
+	Values  matrixoperations.NumericMatrix      `json:"values,omitempty"`
+	Null    [][]bool                            `json:"null,omitempty"`
+	Token   string                              `json:"token,omitempty"`
+	Strings matrixoperations.AlphanumericMatrix `json:"strings,omitempty"`
+	Rows    int                                 `json:"rows,omitempty"`
+	Cols    int                                 `json:"cols,omitempty"`
+	Entries []matrixoperations.Entry            `json:"entries,omitempty"`
+	Labels  *matrixoperations.Labels            `json:"labels,omitempty"`
+}
+
+// matrixType names the kind of matrix underneath any labels, as reported
+// by /validate and /matrices.
+func matrixType(matrix MatrixProcessor) string {
+	switch unwrapMatrix(matrix).(type) {
+	case *matrixoperations.NumericMatrix:
+		return "numeric"
+	case *matrixoperations.SparseMatrix:
+		return "sparse"
+	case *matrixoperations.NullableMatrix:
+		return "nullable"
+	case *matrixoperations.AlphanumericMatrix:
+		return "string"
+	}
+	return ""
+}
+
+// matrixID is the content address of a matrix: the SHA-256 of data, the
+// form encodeMatrix stores it in. That covers its type, nulls and their
+// token, and labels, which its CSV form alone does not tell apart.
+func matrixID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func encodeMatrix(matrix MatrixProcessor) ([]byte, error) {
+	stored := storedMatrix{Type: matrixType(matrix)}
+	switch m := unwrapMatrix(matrix).(type) {
+	case *matrixoperations.NumericMatrix:
+		stored.Values = *m
+	case *matrixoperations.SparseMatrix:
+		stored.Rows, stored.Cols, stored.Entries = m.Rows, m.Cols, m.Entries()
+	case *matrixoperations.NullableMatrix:
+		stored.Values, stored.Null, stored.Token = m.Values, m.Null, m.Token
+	case *matrixoperations.AlphanumericMatrix:
+		stored.Strings = *m
+	default:
+		return nil, matrixoperations.ErrUnsupportedOperation
+	}
+	if labeled, ok := matrix.(*LabeledMatrix); ok {
+		stored.Labels = &labeled.Labels
+	}
+
+	return json.Marshal(stored)
+}
+
+// decodeMatrix rebuilds a stored matrix. Nulls follow the policy in opts.
+func decodeMatrix(data []byte, opts parseOptions) (MatrixProcessor, error) {
+	var stored storedMatrix
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	var matrix MatrixProcessor
+	switch stored.Type {
+	case "numeric":
+		matrix = &stored.Values
+	case "sparse":
+		sparse, err := matrixoperations.NewSparseMatrix(stored.Rows, stored.Cols, stored.Entries)
+		if err != nil {
+			return nil, err
+		}
+		matrix = sparse
+	case "nullable":
+		matrix = &matrixoperations.NullableMatrix{Values: stored.Values, Null: stored.Null, Policy: opts.nullPolicy, Token: stored.Token}
+	case "string":
+		matrix = &stored.Strings
+	default:
+		return nil, fmt.Errorf("unknown stored matrix type %q", stored.Type)
+	}
+	if stored.Labels != nil {
+		matrix = &LabeledMatrix{MatrixProcessor: matrix, Labels: *stored.Labels}
+	}
+
+	return matrix, nil
+}
+
+// loadStoredMatrix fetches the matrix stored under id and applies the
+// selectors from opts.
+func loadStoredMatrix(id string, opts parseOptions) (MatrixProcessor, error) {
+	data, err := MatrixStore.Get(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("matrix %s %w", id, err)
+	}
+	if errors.Is(err, storage.ErrInvalidID) {
+		return nil, fmt.Errorf("%w %q", err, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, err := decodeMatrix(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode matrix %s: %w", id, err)
+	}
+	if err := applySelectors(matrix, opts); err != nil {
+		return nil, err
+	}
+
+	return matrix, nil
+}
+
+func storeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrInvalidID):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return parseErrorStatus(err)
+}
+
+type storedMatrixInfo struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Rows int    `json:"rows"`
+	Cols int    `json:"cols"`
+}
+
+// StoreMatrixHandler parses the upload with the usual parameters and
+// stores the result, responding 201 with its ID. Storing the same matrix
+// again yields the same ID.
+func StoreMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	matrix, status, err := loadMatrix(r, "file")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	data, err := encodeMatrix(matrix)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	info := storedMatrixInfo{ID: matrixID(data), Type: matrixType(matrix)}
+	info.Rows, info.Cols = matrix.Shape()
+	if err := MatrixStore.Put(info.ID, data); err != nil {
+		status := storeErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			err = fmt.Errorf("failed to process request: %w", err)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Location", "/matrices/"+info.ID)
+	respondJSON(w, http.StatusCreated, info)
+}
+
+// GetMatrixHandler responds with a stored matrix in the requested format.
+func GetMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseOptionsFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	matrix, err := loadStoredMatrix(r.PathValue("id"), opts)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	respondMatrix(w, r, matrix)
+}
+
+// DeleteMatrixHandler removes a stored matrix and responds 204.
+func DeleteMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := MatrixStore.Delete(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("matrix %s %s", id, err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to process request: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
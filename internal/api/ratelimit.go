@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"league/internal/auth"
+	"league/internal/metrics"
+	"league/internal/ratelimit"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitStore holds the token buckets of RateLimited routes. nil turns
+// rate limiting off.
+var RateLimitStore ratelimit.Store
+
+var rateLimited = metrics.NewCounter("rate_limited_total", "Requests refused with 429 by the rate limiter.")
+
+// RateLimited meters each client's requests to the named route with a token
+// bucket of its own, refusing those over limit with 429. Every response
+// carries RateLimit-* headers describing the client's bucket. A zero limit
+// leaves next unmetered, and a store that fails lets the request through.
+func RateLimited(name string, limit ratelimit.Limit, next http.HandlerFunc) http.HandlerFunc {
+	if limit.Count == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if allowed(w, r, name, limit) {
+			next(w, r)
+		}
+	}
+}
+
+// allowed takes a token from the client's bucket for the named route, as
+// RateLimited does, and reports whether the request may go ahead. When it
+// may not, it has already responded 429.
+func allowed(w http.ResponseWriter, r *http.Request, name string, limit ratelimit.Limit) bool {
+	if RateLimitStore == nil || limit.Count == 0 {
+		return true
+	}
+
+	d, err := RateLimitStore.Take(r.Context(), name+" "+clientID(r), limit)
+	if err != nil {
+		fmt.Printf("rate limit store failed: %v\n", err)
+		return true
+	}
+
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Count, ceilSeconds(limit.Window)))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Count))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if !d.Allowed {
+		rateLimited.Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// clientID names who a request comes from: the key or token subject it
+// authenticated as, or else its remote address. Headers it merely carries
+// are not trusted, since a client could vary them to get fresh buckets.
+func clientID(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "id:" + p.ID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
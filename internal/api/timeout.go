@@ -0,0 +1,22 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithTimeout gives next a request context that ends after timeout, so an
+// operation running past it stops and responds 504. Zero leaves next
+// unbounded.
+func WithTimeout(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
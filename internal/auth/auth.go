@@ -0,0 +1,399 @@
+// Package auth identifies the client behind a request, by a static API key,
+// an HMAC-SHA256 signature made with one or a JSON Web Token, and records
+// what it may call.
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnauthenticated = errors.New("missing credentials")
+var ErrUnknownKey = errors.New("unknown API key")
+var ErrInvalidSignature = errors.New("invalid signature")
+var ErrStaleRequest = errors.New("request timestamp is outside the allowed window")
+var ErrReplayed = errors.New("request was already received")
+var ErrBodyMismatch = errors.New("body does not match its signed hash")
+var ErrBodyTooLarge = errors.New("signed body is too large")
+var ErrInvalidKeys = errors.New("invalid API keys")
+var ErrWrongCertificate = errors.New("key is bound to another client certificate")
+
+// AllScopes is the scope that allows everything.
+const AllScopes = "*"
+
+// The headers a request carries its credentials in. A request with a key
+// sends it in KeyHeader. A signed one names its key in KeyIDHeader and sends
+// the rest of what it signed alongside the signature.
+const (
+	KeyHeader       = "X-API-Key"
+	KeyIDHeader     = "X-Key-Id"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	BodyHashHeader  = "X-Content-SHA256"
+	SignatureHeader = "X-Signature"
+)
+
+// maxNonce is the longest nonce accepted, which bounds what is remembered
+// of each signed request.
+const maxNonce = 128
+
+// maxMemory is how much of a signed body is held in memory while it is
+// checked; the rest goes to a temporary file.
+var maxMemory int64 = 32 << 20
+
+// sweepEvery is how many nonces are remembered between sweeps for expired
+// ones.
+const sweepEvery = 1024
+
+// Key is one client's credentials. Secret is both the API key it may send
+// as is and the key it signs requests with. A key with a CertSubject
+// belongs to the client presenting a certificate with that subject: the
+// certificate alone authenticates it, and its secret, if it has one, is
+// only accepted along with the certificate.
+type Key struct {
+	ID          string   `json:"id"`
+	Secret      string   `json:"secret"`
+	CertSubject string   `json:"cert_subject"`
+	Scopes      []string `json:"scopes"`
+}
+
+// LoadKeys reads a JSON array of keys from path.
+func LoadKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeys, err)
+	}
+	return keys, nil
+}
+
+// Principal is the client a request was authenticated as.
+type Principal struct {
+	ID     string
+	Scopes []string
+}
+
+// Allows reports whether the principal may use scope.
+func (p *Principal) Allows(scope string) bool {
+	return slices.Contains(p.Scopes, AllScopes) || slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal ctx carries, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Authenticator checks requests against a fixed set of keys, and bearer
+// tokens against Tokens.
+type Authenticator struct {
+	// Tokens verifies bearer tokens; nil refuses them.
+	Tokens *TokenVerifier
+	// MaxBody caps the bytes of a signed body, which is read in full to
+	// check it before the request goes any further; zero is no limit.
+	MaxBody int64
+
+	byID     map[string]Key
+	bySecret map[[sha256.Size]byte]Key
+	byCert   map[string]Key
+	// maxAge is how far a signed request's timestamp may be from now.
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu         sync.Mutex
+	seen       map[string]time.Time
+	remembered int
+}
+
+// NewAuthenticator accepts keys and requests signed with them up to maxAge
+// either side of now. Every key needs a unique ID, and a unique secret,
+// certificate subject or both.
+func NewAuthenticator(keys []Key, maxAge time.Duration) (*Authenticator, error) {
+	a := &Authenticator{
+		byID:     make(map[string]Key),
+		bySecret: make(map[[sha256.Size]byte]Key),
+		byCert:   make(map[string]Key),
+		maxAge:   maxAge,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+	}
+	for i, key := range keys {
+		if key.ID == "" || key.Secret == "" && key.CertSubject == "" {
+			return nil, fmt.Errorf("%w: key %d needs an id and a secret or cert_subject", ErrInvalidKeys, i+1)
+		}
+		if _, ok := a.byID[key.ID]; ok {
+			return nil, fmt.Errorf("%w: id %q is used twice", ErrInvalidKeys, key.ID)
+		}
+		a.byID[key.ID] = key
+		if key.Secret != "" {
+			secret := sha256.Sum256([]byte(key.Secret))
+			if _, ok := a.bySecret[secret]; ok {
+				return nil, fmt.Errorf("%w: key %q shares its secret with another", ErrInvalidKeys, key.ID)
+			}
+			a.bySecret[secret] = key
+		}
+		if key.CertSubject != "" {
+			if _, ok := a.byCert[key.CertSubject]; ok {
+				return nil, fmt.Errorf("%w: key %q shares its cert_subject with another", ErrInvalidKeys, key.ID)
+			}
+			a.byCert[key.CertSubject] = key
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate returns who r comes from, by its signature, bearer token,
+// API key or client certificate, in that order. A signed request's body is
+// read through and checked against its signed hash, and its size against
+// MaxBody, before it returns, so the caller must close r.Body, even if it
+// is replaced, once done with r.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.Header.Get(SignatureHeader) != "" {
+		return a.verify(r)
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		if a.Tokens == nil {
+			return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidToken)
+		}
+		return a.Tokens.Verify(strings.TrimSpace(token))
+	}
+	if secret := r.Header.Get(KeyHeader); secret != "" {
+		// Keys are looked up by hash, so the time taken says nothing
+		// about how much of a key was right.
+		key, ok := a.bySecret[sha256.Sum256([]byte(secret))]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return principal(r, key)
+	}
+	if subject, ok := ClientSubject(r); ok {
+		if key, ok := a.byCert[subject]; ok {
+			return principal(r, key)
+		}
+	}
+
+	return nil, ErrUnauthenticated
+}
+
+// principal returns the principal for key, if r came with the client
+// certificate key is bound to.
+func principal(r *http.Request, key Key) (*Principal, error) {
+	if key.CertSubject != "" {
+		if subject, _ := ClientSubject(r); subject != key.CertSubject {
+			return nil, ErrWrongCertificate
+		}
+	}
+	return &Principal{ID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// ClientSubject returns the subject of the verified certificate r's client
+// presented, if any, such as CN=batch,O=League.
+func ClientSubject(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", false
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.String(), true
+}
+
+func (a *Authenticator) verify(r *http.Request) (*Principal, error) {
+	key, ok := a.byID[r.Header.Get(KeyIDHeader)]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not a Unix time", ErrInvalidSignature, TimestampHeader)
+	}
+	signed := time.Unix(seconds, 0)
+	now := a.now()
+	if signed.Before(now.Add(-a.maxAge)) || signed.After(now.Add(a.maxAge)) {
+		return nil, ErrStaleRequest
+	}
+
+	nonce := r.Header.Get(NonceHeader)
+	if nonce == "" || len(nonce) > maxNonce {
+		return nil, fmt.Errorf("%w: %s must be 1 to %d characters", ErrInvalidSignature, NonceHeader, maxNonce)
+	}
+	bodyHash, err := hex.DecodeString(r.Header.Get(BodyHashHeader))
+	if err != nil || len(bodyHash) != sha256.Size {
+		return nil, fmt.Errorf("%w: %s must be a hex SHA-256", ErrInvalidSignature, BodyHashHeader)
+	}
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || !hmac.Equal(signature, sign(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, bodyHash)) {
+		return nil, ErrInvalidSignature
+	}
+
+	// Only requests signed by a known key get remembered, so others cannot
+	// fill the memory of nonces.
+	if !a.remember(key.ID+" "+nonce, signed.Add(a.maxAge), now) {
+		return nil, ErrReplayed
+	}
+	p, err := principal(r, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyBody(r, bodyHash, a.MaxBody); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// remember records a nonce until it expires, reporting false if it is
+// already recorded. Expired nonces are dropped as new ones come in; a
+// request that reuses one after that is stale anyway.
+func (a *Authenticator) remember(nonce string, expires, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.seen[nonce]; ok {
+		return false
+	}
+	a.remembered++
+	if a.remembered%sweepEvery == 0 {
+		for n, exp := range a.seen {
+			if now.After(exp) {
+				delete(a.seen, n)
+			}
+		}
+	}
+	a.seen[nonce] = expires
+
+	return true
+}
+
+// Sign adds the headers that authenticate r as signed by key at time at,
+// with a nonce that must not be used again. body is what r will send.
+func Sign(r *http.Request, body []byte, key Key, at time.Time, nonce string) {
+	bodyHash := sha256.Sum256(body)
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	r.Header.Set(KeyIDHeader, key.ID)
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(BodyHashHeader, hex.EncodeToString(bodyHash[:]))
+	r.Header.Set(SignatureHeader, hex.EncodeToString(sign(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, bodyHash[:])))
+}
+
+// sign is the HMAC-SHA256 over a request's method, path and query,
+// timestamp, nonce and body hash, one per line.
+func sign(secret, method, uri, timestamp, nonce string, bodyHash []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, strings.Join([]string{method, uri, timestamp, nonce, hex.EncodeToString(bodyHash)}, "\n"))
+	return mac.Sum(nil)
+}
+
+// verifyBody reads r's body through, failing if it does not hash to want
+// or runs past limit bytes, and puts what it read in its place. Up to
+// maxMemory bytes are held in memory and the rest in a temporary file,
+// removed when the body is closed.
+func verifyBody(r *http.Request, want []byte, limit int64) error {
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+
+	hash := sha256.New()
+	var body io.Reader = r.Body
+	if limit > 0 {
+		body = &limitedBody{r: body, left: limit}
+	}
+	body = io.TeeReader(body, hash)
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, body, maxMemory+1)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	replacement := io.NopCloser(&buf)
+	if n > maxMemory {
+		f, err := spool(&buf, body)
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+		replacement = f
+	}
+
+	if !bytes.Equal(hash.Sum(nil), want) {
+		replacement.Close()
+		return ErrBodyMismatch
+	}
+	r.Body = replacement
+	return nil
+}
+
+// limitedBody reads up to left bytes from r, and then ErrBodyTooLarge if
+// there are more.
+type limitedBody struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left < 0 {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// spool writes head and then the rest of body to a temporary file, and
+// returns it ready to be read from the start.
+func spool(head io.Reader, body io.Reader) (*tempFile, error) {
+	f, err := os.CreateTemp("", "league-body-")
+	if err != nil {
+		return nil, err
+	}
+	tmp := &tempFile{f}
+	if _, err := io.Copy(f, io.MultiReader(head, body)); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// tempFile is a file removed once closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
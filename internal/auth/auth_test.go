@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	batch     = Key{ID: "batch", Secret: "batch-secret", Scopes: []string{"sum", "multiply"}}
+	dashboard = Key{ID: "dashboard", Secret: "dashboard-secret", Scopes: []string{AllScopes}}
+)
+
+func newAuthenticator(t *testing.T, now *time.Time) *Authenticator {
+	a, err := NewAuthenticator([]Key{batch, dashboard}, 5*time.Minute)
+	require.NoError(t, err)
+	a.now = func() time.Time { return *now }
+	return a
+}
+
+func TestLoadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte(`[{"id": "batch", "secret": "batch-secret", "scopes": ["sum", "multiply"]}]`), 0o600)
+	keys, err := LoadKeys(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{batch}, keys)
+
+	os.WriteFile(path, []byte(`{"id": "batch"}`), 0o600)
+	_, err = LoadKeys(path)
+	assert.ErrorIs(t, err, ErrInvalidKeys)
+}
+
+func TestNewAuthenticator_Invalid(t *testing.T) {
+	for _, keys := range [][]Key{
+		{{ID: "a"}},
+		{{Secret: "s"}},
+		{{ID: "a", Secret: "s"}, {ID: "a", Secret: "t"}},
+		{{ID: "a", Secret: "s"}, {ID: "b", Secret: "s"}},
+		{{ID: "a", CertSubject: "CN=a"}, {ID: "b", CertSubject: "CN=a"}},
+	} {
+		_, err := NewAuthenticator(keys, time.Minute)
+		assert.ErrorIs(t, err, ErrInvalidKeys, keys)
+	}
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := newAuthenticator(t, &now)
+
+	r := httptest.NewRequest("POST", "/sum", nil)
+	_, err := a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	r.Header.Set(KeyHeader, "wrong")
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	r.Header.Set(KeyHeader, batch.Secret)
+	p, err := a.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "batch", p.ID)
+	assert.True(t, p.Allows("sum"))
+	assert.False(t, p.Allows("invert"))
+
+	r.Header.Set(KeyHeader, dashboard.Secret)
+	p, err = a.Authenticate(r)
+	require.NoError(t, err)
+	assert.True(t, p.Allows("invert"))
+}
+
+func TestAuthenticate_Signed(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := newAuthenticator(t, &now)
+	body := "1,2\n3,4\n"
+	request := func(target, nonce string, at time.Time) (*Principal, error) {
+		r := httptest.NewRequest("POST", target, strings.NewReader(body))
+		Sign(r, []byte(body), batch, at, nonce)
+		return a.Authenticate(r)
+	}
+
+	p, err := request("/sum?axis=rows", "n1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "batch", p.ID)
+
+	// The same nonce is refused while its request could still be fresh.
+	_, err = request("/sum?axis=rows", "n1", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrReplayed)
+
+	// So are timestamps too far either side of now.
+	_, err = request("/sum", "n2", now.Add(-6*time.Minute))
+	assert.ErrorIs(t, err, ErrStaleRequest)
+	_, err = request("/sum", "n3", now.Add(6*time.Minute))
+	assert.ErrorIs(t, err, ErrStaleRequest)
+
+	// Changing anything signed breaks the signature.
+	r := httptest.NewRequest("POST", "/sum?axis=rows", strings.NewReader(body))
+	Sign(r, []byte(body), batch, now, "n4")
+	r.URL.RawQuery = "axis=columns"
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	r = httptest.NewRequest("POST", "/sum", strings.NewReader(body))
+	Sign(r, []byte(body), Key{ID: batch.ID, Secret: "guess"}, now, "n5")
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	r = httptest.NewRequest("POST", "/sum", strings.NewReader(body))
+	Sign(r, []byte(body), batch, now, "n6")
+	r.Header.Set(KeyIDHeader, "nobody")
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestAuthenticate_SignedBody(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := newAuthenticator(t, &now)
+	defer func(n int64) { maxMemory = n }(maxMemory)
+	maxMemory = 4
+
+	// Bodies are checked up front, and ones too big for memory are read
+	// back from a file that is gone once the body is closed.
+	for _, body := range []string{"", "1,2\n", "1,2\n3,4\n"} {
+		r := httptest.NewRequest("POST", "/sum", strings.NewReader(body))
+		Sign(r, []byte(body), batch, now, "n"+body)
+		_, err := a.Authenticate(r)
+		require.NoError(t, err, body)
+		data, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(data))
+		if f, ok := r.Body.(*tempFile); ok {
+			r.Body.Close()
+			_, err := os.Stat(f.Name())
+			assert.True(t, os.IsNotExist(err))
+		} else {
+			assert.LessOrEqual(t, int64(len(body)), maxMemory)
+		}
+	}
+
+	// A body swapped for another is refused.
+	for _, body := range []string{"9,9\n", "9,9\n9,9\n"} {
+		r := httptest.NewRequest("POST", "/sum", strings.NewReader(body))
+		Sign(r, []byte("1,2\n"), batch, now, "swapped"+body)
+		_, err := a.Authenticate(r)
+		assert.ErrorIs(t, err, ErrBodyMismatch)
+	}
+
+	// So is one over MaxBody, whether it would be held in memory or in a
+	// file.
+	for _, tc := range []struct {
+		limit int64
+		body  string
+		err   error
+	}{
+		{8, "1,2\n3,4\n", nil},
+		{8, "1,2\n3,4\n5", ErrBodyTooLarge},
+		{2, "1,2\n", ErrBodyTooLarge},
+	} {
+		a.MaxBody = tc.limit
+		r := httptest.NewRequest("POST", "/sum", strings.NewReader(tc.body))
+		Sign(r, []byte(tc.body), batch, now, "limited"+tc.body)
+		_, err := a.Authenticate(r)
+		if tc.err == nil {
+			assert.NoError(t, err, tc.body)
+			r.Body.Close()
+		} else {
+			assert.ErrorIs(t, err, tc.err, tc.body)
+		}
+	}
+}
+
+func TestAuthenticate_ClientCertificate(t *testing.T) {
+	a, err := NewAuthenticator([]Key{
+		{ID: "mtls", CertSubject: "CN=mtls,O=League", Scopes: []string{"sum"}},
+		{ID: "bound", Secret: "bound-secret", CertSubject: "CN=bound,O=League", Scopes: []string{"sum"}},
+	}, time.Minute)
+	require.NoError(t, err)
+	withCert := func(r *http.Request, cn string) *http.Request {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn, Organization: []string{"League"}}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	r := withCert(httptest.NewRequest("POST", "/sum", nil), "mtls")
+	subject, ok := ClientSubject(r)
+	assert.True(t, ok)
+	assert.Equal(t, "CN=mtls,O=League", subject)
+	p, err := a.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "mtls", p.ID)
+
+	_, err = a.Authenticate(withCert(httptest.NewRequest("POST", "/sum", nil), "stranger"))
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	// A key bound to a certificate needs it as well as its secret.
+	r = httptest.NewRequest("POST", "/sum", nil)
+	r.Header.Set(KeyHeader, "bound-secret")
+	_, err = a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrWrongCertificate)
+	_, err = a.Authenticate(withCert(r, "mtls"))
+	assert.ErrorIs(t, err, ErrWrongCertificate)
+	p, err = a.Authenticate(withCert(r, "bound"))
+	require.NoError(t, err)
+	assert.Equal(t, "bound", p.ID)
+}
+
+func TestRemember_SweepsExpiredNonces(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := newAuthenticator(t, &now)
+
+	a.remember("old", now.Add(time.Minute), now)
+	a.remember("new", now.Add(time.Hour), now)
+	now = now.Add(10 * time.Minute)
+	for i := 2; i < sweepEvery; i++ {
+		a.remember(strings.Repeat("x", i), now.Add(time.Minute), now)
+	}
+
+	_, ok := a.seen["old"]
+	assert.False(t, ok)
+	_, ok = a.seen["new"]
+	assert.True(t, ok)
+}
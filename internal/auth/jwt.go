@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrInvalidTokenKey = errors.New("invalid token key")
+
+// Tokens carry scopes named TokenScopePrefix followed by the scope they
+// grant, such as matrix:sum. AdminTokenScope grants every scope.
+const (
+	TokenScopePrefix = "matrix:"
+	AdminTokenScope  = TokenScopePrefix + "admin"
+)
+
+// minSecret is the shortest HS256 secret accepted: as many bytes as the
+// hash, below which the secret is the weak point.
+const minSecret = sha256.Size
+
+// TokenKey is a key that signs tokens, along with the one algorithm it
+// signs them with, so that a token cannot pick how its signature is read.
+type TokenKey struct {
+	// ID matches the kid in the header of the tokens it signed; an empty
+	// ID matches any.
+	ID        string
+	Algorithm string
+	key       any
+}
+
+// NewTokenKey makes a key for verifying tokens from an HS256 secret, an RSA
+// public key for RS256 or a P-256 ECDSA public key for ES256.
+func NewTokenKey(id string, key any) (TokenKey, error) {
+	switch k := key.(type) {
+	case []byte:
+		if len(k) < minSecret {
+			return TokenKey{}, fmt.Errorf("%w: HS256 secret needs at least %d bytes", ErrInvalidTokenKey, minSecret)
+		}
+		return TokenKey{ID: id, Algorithm: "HS256", key: k}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return TokenKey{}, fmt.Errorf("%w: RSA key needs at least 2048 bits", ErrInvalidTokenKey)
+		}
+		return TokenKey{ID: id, Algorithm: "RS256", key: k}, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return TokenKey{}, fmt.Errorf("%w: ECDSA key must be on P-256", ErrInvalidTokenKey)
+		}
+		return TokenKey{ID: id, Algorithm: "ES256", key: k}, nil
+	}
+
+	return TokenKey{}, fmt.Errorf("%w: unsupported key type %T", ErrInvalidTokenKey, key)
+}
+
+// LoadPublicKey reads a PEM-encoded RSA or ECDSA public key from path.
+func LoadPublicKey(path string) (TokenKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TokenKey{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return TokenKey{}, fmt.Errorf("%w: %s holds no PEM block", ErrInvalidTokenKey, path)
+	}
+	var key any
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return TokenKey{}, fmt.Errorf("%w: %s holds a %s, not a public key", ErrInvalidTokenKey, path, block.Type)
+	}
+	if err != nil {
+		return TokenKey{}, fmt.Errorf("%w: %v", ErrInvalidTokenKey, err)
+	}
+
+	return NewTokenKey("", key)
+}
+
+// jwk is a JSON Web Key, as in RFC 7517, with the members the supported
+// algorithms use.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// K is an oct key's secret.
+	K string `json:"k"`
+	// N and E are an RSA key's modulus and exponent.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Crv, X and Y are an EC key's curve and point.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS reads the signing keys from a JWK Set file. Keys for other uses
+// or algorithms are skipped.
+func LoadJWKS(path string) ([]TokenKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTokenKey, err)
+	}
+
+	var keys []TokenKey
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i+1, err)
+		}
+		if k.Alg != "" && k.Alg != key.Algorithm {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (k jwk) parse() (TokenKey, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "oct":
+		secret, err := b64.DecodeString(k.K)
+		if err != nil {
+			return TokenKey{}, fmt.Errorf("%w: k is not base64url", ErrInvalidTokenKey)
+		}
+		return NewTokenKey(k.Kid, secret)
+	case "RSA":
+		n, errN := b64.DecodeString(k.N)
+		e, errE := b64.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return TokenKey{}, fmt.Errorf("%w: malformed RSA key", ErrInvalidTokenKey)
+		}
+		return NewTokenKey(k.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	case "EC":
+		if k.Crv != "P-256" {
+			return TokenKey{}, fmt.Errorf("%w: unsupported curve %q", ErrInvalidTokenKey, k.Crv)
+		}
+		x, errX := b64.DecodeString(k.X)
+		y, errY := b64.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return TokenKey{}, fmt.Errorf("%w: malformed EC key", ErrInvalidTokenKey)
+		}
+		// ecdh rejects points that are not on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return TokenKey{}, fmt.Errorf("%w: %v", ErrInvalidTokenKey, err)
+		}
+		return NewTokenKey(k.Kid, &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)})
+	}
+
+	return TokenKey{}, fmt.Errorf("%w: unsupported key type %q", ErrInvalidTokenKey, k.Kty)
+}
+
+// TokenVerifier checks JSON Web Tokens signed by any of a set of keys.
+type TokenVerifier struct {
+	keys     []TokenKey
+	audience string
+	// scopes are the names a matrix: scope may grant.
+	scopes map[string]bool
+	// leeway is how far exp and nbf may be past, for clocks that differ.
+	leeway time.Duration
+	now    func() time.Time
+}
+
+// NewTokenVerifier accepts tokens signed by keys that are meant for
+// audience and valid now, give or take leeway. Tokens are granted only the
+// route scopes listed in scopes, and all of them for matrix:admin.
+func NewTokenVerifier(keys []TokenKey, audience string, leeway time.Duration, scopes []string) *TokenVerifier {
+	known := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		known[scope] = true
+	}
+	return &TokenVerifier{keys: keys, audience: audience, scopes: known, leeway: leeway, now: time.Now}
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject   string     `json:"sub"`
+	Audience  stringList `json:"aud"`
+	Expires   *float64   `json:"exp"`
+	NotBefore *float64   `json:"nbf"`
+	// Scope is space-separated, as in RFC 8693; some issuers send scp,
+	// a list, instead.
+	Scope string     `json:"scope"`
+	Scp   stringList `json:"scp"`
+}
+
+// stringList is a claim that may be a string or a list of them.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*l = stringList{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Verify checks token's signature and claims, and returns its subject with
+// the scopes its matrix: scopes grant. A token must have a subject, an
+// expiry, and the verifier's audience among its own.
+func (v *TokenVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64url", ErrInvalidToken)
+	}
+	if !v.verifySignature(header, parts[0]+"."+parts[1], signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	now := v.now()
+	switch {
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case claims.Expires == nil:
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	case now.Add(-v.leeway).After(numericDate(*claims.Expires)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case claims.NotBefore != nil && now.Add(v.leeway).Before(numericDate(*claims.NotBefore)):
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	case !slices.Contains(claims.Audience, v.audience):
+		return nil, fmt.Errorf("%w: not meant for this audience", ErrInvalidToken)
+	}
+
+	return &Principal{ID: claims.Subject, Scopes: v.grant(append(strings.Fields(claims.Scope), claims.Scp...))}, nil
+}
+
+// verifySignature reports whether a key named by the header, and made for
+// the algorithm it names, signed input.
+func (v *TokenVerifier) verifySignature(header tokenHeader, input string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(input))
+	for _, key := range v.keys {
+		if key.ID != "" && key.ID != header.Kid || key.Algorithm != header.Alg {
+			continue
+		}
+		switch k := key.key.(type) {
+		case []byte:
+			mac := hmac.New(sha256.New, k)
+			mac.Write([]byte(input))
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			// ES256 signatures are r and s as two 32-byte integers.
+			if len(signature) == 64 {
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				if ecdsa.Verify(k, digest[:], r, s) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// grant turns the matrix: scopes of a token into the scopes routes are
+// checked against, ignoring any others. Only matrix:admin grants
+// AllScopes; matrix:* and names no route checks grant nothing.
+func (v *TokenVerifier) grant(scopes []string) []string {
+	var granted []string
+	for _, scope := range scopes {
+		if scope == AdminTokenScope {
+			granted = append(granted, AllScopes)
+		} else if name, ok := strings.CutPrefix(scope, TokenScopePrefix); ok && name != AllScopes && v.scopes[name] {
+			granted = append(granted, name)
+		}
+	}
+	return granted
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("not base64url")
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT time, seconds since the Unix epoch.
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second)))
+}
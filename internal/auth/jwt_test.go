@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+var routeScopes = []string{"sum", "multiply", "invert", "jobs"}
+
+// makeToken signs claims with key, a secret or a private key, under alg
+// and kid.
+func makeToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	b64 := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64.EncodeToString(signature)
+}
+
+func TestTokenVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var keys []TokenKey
+	for id, key := range map[string]any{"hs": hmacSecret, "rs": &rsaKey.PublicKey, "es": &ecKey.PublicKey} {
+		k, err := NewTokenKey(id, key)
+		require.NoError(t, err)
+		keys = append(keys, k)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	v := NewTokenVerifier(keys, "league", 30*time.Second, routeScopes)
+	v.now = func() time.Time { return now }
+	claims := func(extra map[string]any) map[string]any {
+		c := map[string]any{"sub": "gateway", "aud": "league", "exp": now.Add(time.Minute).Unix(), "scope": "matrix:sum matrix:jobs openid"}
+		for k, value := range extra {
+			c[k] = value
+		}
+		return c
+	}
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      any
+	}{{"HS256", "hs", hmacSecret}, {"RS256", "rs", rsaKey}, {"ES256", "es", ecKey}} {
+		p, err := v.Verify(makeToken(t, tc.alg, tc.kid, tc.key, claims(nil)))
+		require.NoError(t, err, tc.alg)
+		assert.Equal(t, &Principal{ID: "gateway", Scopes: []string{"sum", "jobs"}}, p, tc.alg)
+	}
+
+	p, err := v.Verify(makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"scope": nil, "scp": []string{"matrix:admin"}})))
+	require.NoError(t, err)
+	assert.True(t, p.Allows("invert"))
+
+	// Only matrix:admin grants every scope, and unknown names grant none.
+	p, err = v.Verify(makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"scope": "matrix:* matrix:bogus matrix:"})))
+	require.NoError(t, err)
+	assert.Empty(t, p.Scopes)
+	assert.False(t, p.Allows("invert"))
+
+	_, err = v.Verify(makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"aud": []string{"other", "league"}})))
+	require.NoError(t, err)
+
+	// Within the leeway an expired token still passes, and a future one too.
+	_, err = v.Verify(makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"exp": now.Add(-20 * time.Second).Unix(), "nbf": now.Add(20 * time.Second).Unix()})))
+	assert.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"expired":       makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()})),
+		"not yet valid": makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()})),
+		"no expiry":     makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"exp": nil})),
+		"no subject":    makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"sub": ""})),
+		"other aud":     makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"aud": "other"})),
+		"no aud":        makeToken(t, "HS256", "hs", hmacSecret, claims(map[string]any{"aud": nil})),
+		"wrong secret":  makeToken(t, "HS256", "hs", []byte("an entirely different 32 byte secret"), claims(nil)),
+		"wrong kid":     makeToken(t, "RS256", "es", rsaKey, claims(nil)),
+		"alg none":      makeToken(t, "none", "hs", []byte{}, claims(nil)),
+		// A public key is no secret, so signing with it as one must fail.
+		"alg confusion": makeToken(t, "HS256", "rs", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), claims(nil)),
+		"garbage":       "not.a.token",
+	} {
+		_, err := v.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
+
+func TestNewTokenKey_Invalid(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []any{[]byte("short"), &small.PublicKey, &p384.PublicKey, "secret"} {
+		_, err := NewTokenKey("", key)
+		assert.ErrorIs(t, err, ErrInvalidTokenKey, fmt.Sprintf("%T", key))
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	set, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "k": b64(hmacSecret)},
+		{"kty": "RSA", "kid": "rs", "alg": "RS256", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, set, 0o600)
+
+	keys, err := LoadJWKS(path)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	v := NewTokenVerifier(keys, "league", 0, routeScopes)
+	claims := map[string]any{"sub": "gateway", "aud": "league", "exp": time.Now().Add(time.Minute).Unix()}
+	for _, token := range []string{
+		makeToken(t, "HS256", "hs", hmacSecret, claims),
+		makeToken(t, "RS256", "rs", rsaKey, claims),
+		makeToken(t, "ES256", "es", ecKey, claims),
+	} {
+		_, err := v.Verify(token)
+		assert.NoError(t, err)
+	}
+
+	// A point off the curve is refused.
+	os.WriteFile(path, []byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "`+b64(make([]byte, 32))+`", "y": "`+b64(make([]byte, 32))+`"}]}`), 0o600)
+	_, err = LoadJWKS(path)
+	assert.ErrorIs(t, err, ErrInvalidTokenKey)
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+
+	key, err := LoadPublicKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", key.Algorithm)
+
+	// A key without an ID verifies tokens whatever their kid.
+	v := NewTokenVerifier([]TokenKey{key}, "league", 0, routeScopes)
+	_, err = v.Verify(makeToken(t, "ES256", "rotated-1", ecKey, map[string]any{"sub": "gateway", "aud": "league", "exp": time.Now().Add(time.Minute).Unix()}))
+	assert.NoError(t, err)
+}
+
+func TestAuthenticate_Bearer(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := newAuthenticator(t, &now)
+	r := httptest.NewRequest("POST", "/sum", nil)
+	r.Header.Set("Authorization", "Bearer "+makeToken(t, "HS256", "", hmacSecret, map[string]any{"sub": "gateway", "aud": "league", "exp": time.Now().Add(time.Minute).Unix(), "scope": "matrix:sum"}))
+
+	_, err := a.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	key, err := NewTokenKey("", hmacSecret)
+	require.NoError(t, err)
+	a.Tokens = NewTokenVerifier([]TokenKey{key}, "league", 0, routeScopes)
+	p, err := a.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "gateway", p.ID)
+	assert.True(t, p.Allows("sum"))
+}
@@ -0,0 +1,188 @@
+// Package certs serves TLS with certificates that are reloaded from disk
+// when their files change, optionally verifying client certificates.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrInvalidVersion = errors.New("invalid TLS version")
+var ErrNoCertificates = errors.New("no certificates")
+
+// ParseVersion reads a TLS version written as 1.2 or 1.3, the ones still
+// worth accepting.
+func ParseVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("%w: %q (want 1.2 or 1.3)", ErrInvalidVersion, s)
+}
+
+// Options configures a Reloader.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of the CAs client certificates must
+	// chain to; empty asks for no client certificates. A client without
+	// one is turned away if RequireClientCert is set, and otherwise let
+	// through unidentified.
+	ClientCAFile      string
+	RequireClientCert bool
+	MinVersion        uint16
+	// CheckEvery is how often, at most, the files are checked for
+	// changes; they are checked as connections come in.
+	CheckEvery time.Duration
+}
+
+// stamp tells whether a file has changed since it was read.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader holds the certificate a server presents and the CAs it trusts
+// for clients, reading them again once their files change. A change that
+// fails to load, such as a certificate written before its key, keeps the
+// old ones until a later check succeeds.
+type Reloader struct {
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    map[string]stamp
+	checked   time.Time
+}
+
+// NewReloader loads the files opts names, failing if any of them does not.
+func NewReloader(opts Options) (*Reloader, error) {
+	r := &Reloader{opts: opts, now: time.Now}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(stamps); err != nil {
+		return nil, err
+	}
+	r.checked = r.now()
+
+	return r, nil
+}
+
+// TLSConfig returns the configuration to serve with. Each connection gets
+// the certificate and client CAs current when it arrives.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.opts.MinVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config(), nil
+		},
+	}
+}
+
+func (r *Reloader) config() *tls.Config {
+	cert, clientCAs := r.current()
+	c := &tls.Config{
+		MinVersion:   r.opts.MinVersion,
+		Certificates: []tls.Certificate{*cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if clientCAs != nil {
+		c.ClientCAs = clientCAs
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.opts.RequireClientCert {
+			c.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return c
+}
+
+// current returns the certificate and client CAs, reloading them first if
+// it is time to check and their files have changed.
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := r.now(); now.Sub(r.checked) >= r.opts.CheckEvery {
+		r.checked = now
+		if err := r.reload(); err != nil {
+			fmt.Printf("certificate reload failed: %v\n", err)
+		}
+	}
+
+	return r.cert, r.clientCAs
+}
+
+func (r *Reloader) reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for path, s := range stamps {
+		if r.stamps[path] != s {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := r.load(stamps); err != nil {
+		return err
+	}
+	fmt.Println("Reloaded TLS certificates")
+	return nil
+}
+
+// load reads the files, keeping what was loaded before if any of them
+// fails, and records stamps as what they were read at.
+func (r *Reloader) load(stamps map[string]stamp) error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w in %s", ErrNoCertificates, r.opts.ClientCAFile)
+		}
+	}
+
+	r.cert, r.clientCAs, r.stamps = &cert, clientCAs, stamps
+	return nil
+}
+
+func (r *Reloader) stat() (map[string]stamp, error) {
+	stamps := make(map[string]stamp)
+	for _, path := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[path] = stamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
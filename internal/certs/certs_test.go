@@ -0,0 +1,250 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authority is a self-signed CA that issues certificates for tests.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for name, for serving localhost or for a
+// client, with its key, both PEM-encoded.
+func (a *authority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"League"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+// serve runs a TLS server that responds with the client certificate's
+// subject, or "-" for none, until the test ends.
+func serve(t *testing.T, config *tls.Config) string {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.VerifiedChains) == 0 {
+				io.WriteString(w, "-")
+				return
+			}
+			io.WriteString(w, r.TLS.VerifiedChains[0][0].Subject.String())
+		}),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return "https://" + ln.Addr().String()
+}
+
+// get requests url trusting roots, presenting cert if it is not nil, and
+// returns the body or the error.
+func get(t *testing.T, url string, roots []byte, cert *tls.Certificate, maxVersion uint16) (string, error) {
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(roots)
+	config := &tls.Config{RootCAs: pool, MaxVersion: maxVersion}
+	if cert != nil {
+		// Offered whichever CAs the server asks for, so that the server
+		// is the one to judge it.
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func write(t *testing.T, path string, data []byte) {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("1.3")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+	_, err = ParseVersion("1.1")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
+
+func TestReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	first, second := newAuthority(t, "first"), newAuthority(t, "second")
+	cert, key := first.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	write(t, certFile, cert)
+	write(t, keyFile, key)
+
+	r, err := NewReloader(Options{CertFile: certFile, KeyFile: keyFile, MinVersion: tls.VersionTLS12, CheckEvery: time.Minute})
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	url := serve(t, r.TLSConfig())
+
+	_, err = get(t, url, first.pem, nil, 0)
+	assert.NoError(t, err)
+
+	// A certificate written without its key yet fails to load, and the
+	// old one stays.
+	cert, key = second.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	write(t, certFile, cert)
+	now = now.Add(time.Minute)
+	_, err = get(t, url, first.pem, nil, 0)
+	assert.NoError(t, err)
+
+	// Once the key follows, the next check picks both up.
+	write(t, keyFile, key)
+	now = now.Add(30 * time.Second)
+	_, err = get(t, url, first.pem, nil, 0)
+	assert.NoError(t, err, "checked again too soon")
+	now = now.Add(30 * time.Second)
+	_, err = get(t, url, second.pem, nil, 0)
+	assert.NoError(t, err)
+	_, err = get(t, url, first.pem, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestReloader_MinVersion(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, "ca")
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	write(t, filepath.Join(dir, "cert.pem"), cert)
+	write(t, filepath.Join(dir, "key.pem"), key)
+
+	r, err := NewReloader(Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), MinVersion: tls.VersionTLS13})
+	require.NoError(t, err)
+	url := serve(t, r.TLSConfig())
+
+	_, err = get(t, url, ca.pem, nil, tls.VersionTLS12)
+	assert.Error(t, err)
+	_, err = get(t, url, ca.pem, nil, 0)
+	assert.NoError(t, err)
+}
+
+func TestReloader_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca, clients, stranger := newAuthority(t, "ca"), newAuthority(t, "clients"), newAuthority(t, "stranger")
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	write(t, filepath.Join(dir, "cert.pem"), cert)
+	write(t, filepath.Join(dir, "key.pem"), key)
+	write(t, filepath.Join(dir, "clients.pem"), clients.pem)
+	pair := func(a *authority, usage x509.ExtKeyUsage) *tls.Certificate {
+		cert, key := a.issue(t, "batch", usage)
+		pair, err := tls.X509KeyPair(cert, key)
+		require.NoError(t, err)
+		return &pair
+	}
+	opts := Options{
+		CertFile:     filepath.Join(dir, "cert.pem"),
+		KeyFile:      filepath.Join(dir, "key.pem"),
+		ClientCAFile: filepath.Join(dir, "clients.pem"),
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	opts.RequireClientCert = true
+	r, err := NewReloader(opts)
+	require.NoError(t, err)
+	url := serve(t, r.TLSConfig())
+
+	subject, err := get(t, url, ca.pem, pair(clients, x509.ExtKeyUsageClientAuth), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "CN=batch,O=League", subject)
+	_, err = get(t, url, ca.pem, nil, 0)
+	assert.Error(t, err, "no certificate")
+	_, err = get(t, url, ca.pem, pair(stranger, x509.ExtKeyUsageClientAuth), 0)
+	assert.Error(t, err, "certificate from another CA")
+	_, err = get(t, url, ca.pem, pair(clients, x509.ExtKeyUsageServerAuth), 0)
+	assert.Error(t, err, "certificate not for clients")
+
+	// With client certificates optional, clients without one get through
+	// unidentified, but a bad one is still refused.
+	opts.RequireClientCert = false
+	r, err = NewReloader(opts)
+	require.NoError(t, err)
+	url = serve(t, r.TLSConfig())
+
+	subject, err = get(t, url, ca.pem, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "-", subject)
+	_, err = get(t, url, ca.pem, pair(stranger, x509.ExtKeyUsageClientAuth), 0)
+	assert.Error(t, err)
+}
+
+func TestNewReloader_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, "ca")
+	cert, key := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	write(t, filepath.Join(dir, "cert.pem"), cert)
+	write(t, filepath.Join(dir, "key.pem"), key)
+	write(t, filepath.Join(dir, "empty.pem"), nil)
+
+	_, err := NewReloader(Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+	_, err = NewReloader(Options{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem"), ClientCAFile: filepath.Join(dir, "empty.pem")})
+	assert.ErrorIs(t, err, ErrNoCertificates)
+}
@@ -0,0 +1,330 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"league/internal/certs"
+	"league/internal/matrixoperations"
+	"league/internal/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config holds the server settings read from the environment.
+type Config struct {
+	// ShutdownTimeout bounds how long shutdown waits for open requests
+	// and then for queued and running jobs.
+	ShutdownTimeout time.Duration
+	// JobWorkers is how many jobs run at once, and JobQueueSize how many
+	// more may wait for a worker.
+	JobWorkers   int
+	JobQueueSize int
+	// JobRetention is how long a finished job and its result are kept.
+	JobRetention time.Duration
+	// JobMaxUpload caps the bytes of a job submission, which is held in
+	// memory until the job runs.
+	JobMaxUpload int64
+	// MatrixStoreDir is where stored matrices are kept; empty keeps them
+	// in memory. They are evicted once unused for MatrixStoreTTL, or
+	// least recently used first when they exceed MatrixStoreMaxBytes.
+	MatrixStoreDir      string
+	MatrixStoreTTL      time.Duration
+	MatrixStoreMaxBytes int64
+	// ResultCacheBytes is the memory budget for cached operation
+	// responses; zero disables the cache.
+	ResultCacheBytes int64
+	// OperationTimeout bounds how long a synchronous operation may run;
+	// zero leaves it unbounded. OperationTimeouts overrides it for the
+	// operations it names.
+	OperationTimeout  time.Duration
+	OperationTimeouts map[string]time.Duration
+	// ParallelWorkers is how many goroutines an operation on a large
+	// matrix uses, and ParallelThreshold the number of cells from which
+	// it uses them.
+	ParallelWorkers   int
+	ParallelThreshold int
+	// MaxCells caps the cells of a matrix an upload declares the shape of.
+	MaxCells int
+	// AdmissionMaxInFlight caps the operations running at once, and
+	// AdmissionMaxBytes the upload bytes they hold between them; zero is
+	// no limit, and both zero turns admission control off. Up to
+	// AdmissionQueueSize more requests wait, each for at most
+	// AdmissionQueueTimeout, before being turned away.
+	AdmissionMaxInFlight  int
+	AdmissionMaxBytes     int64
+	AdmissionQueueSize    int
+	AdmissionQueueTimeout time.Duration
+	// RateLimit is how many requests each client may make to an
+	// operation; zero is no limit. RateLimits overrides it for the
+	// operations it names.
+	RateLimit  ratelimit.Limit
+	RateLimits map[string]ratelimit.Limit
+	// AuthKeysFile is a JSON file of the API keys allowed to call the
+	// service; empty leaves it open to everyone. A signed request's
+	// timestamp may be at most AuthMaxSkew either side of the server's
+	// clock.
+	AuthKeysFile string
+	AuthMaxSkew  time.Duration
+	// JWTJWKSFile, JWTSecret and JWTPublicKeyFile are the keys bearer
+	// tokens may be signed with: a JWK Set, an HS256 secret and a PEM
+	// public key. Any of them turns tokens on, and they must then be
+	// meant for JWTAudience. exp and nbf may be up to JWTLeeway past.
+	JWTJWKSFile      string
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWTAudience      string
+	JWTLeeway        time.Duration
+	// TLSCertFile and TLSKeyFile turn on TLS, accepting TLSMinVersion and
+	// up. They are read again when they change, checked for that at most
+	// every TLSReloadInterval. TLSClientCAFile asks clients for
+	// certificates from the CAs it holds, which they must present if
+	// TLSRequireClientCert is set.
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSMinVersion        uint16
+	TLSClientCAFile      string
+	TLSRequireClientCert bool
+	TLSReloadInterval    time.Duration
+}
+
+var Default = Config{
+	ShutdownTimeout: 30 * time.Second,
+	JobWorkers:      4,
+	JobQueueSize:    64,
+	JobRetention:    time.Hour,
+	JobMaxUpload:    64 << 20,
+
+	MatrixStoreTTL:      24 * time.Hour,
+	MatrixStoreMaxBytes: 1 << 30,
+
+	ResultCacheBytes: 64 << 20,
+
+	OperationTimeout: time.Minute,
+
+	ParallelWorkers:   matrixoperations.Workers,
+	ParallelThreshold: matrixoperations.ParallelThreshold,
+	MaxCells:          matrixoperations.MaxCells,
+
+	AdmissionMaxInFlight:  32,
+	AdmissionMaxBytes:     256 << 20,
+	AdmissionQueueSize:    128,
+	AdmissionQueueTimeout: 10 * time.Second,
+
+	AuthMaxSkew: 5 * time.Minute,
+	JWTLeeway:   30 * time.Second,
+
+	TLSMinVersion:        tls.VersionTLS12,
+	TLSRequireClientCert: true,
+	TLSReloadInterval:    10 * time.Second,
+}
+
+// Load reads the configuration through getenv, usually os.Getenv. Unset
+// variables keep their Default value.
+func Load(getenv func(string) string) (Config, error) {
+	c := Default
+	for _, v := range []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"SHUTDOWN_TIMEOUT", duration(&c.ShutdownTimeout)},
+		{"JOB_WORKERS", positiveInt(&c.JobWorkers)},
+		{"JOB_QUEUE_SIZE", nonNegativeInt(&c.JobQueueSize)},
+		{"JOB_RETENTION", duration(&c.JobRetention)},
+		{"JOB_MAX_UPLOAD_BYTES", positiveInt64(&c.JobMaxUpload)},
+		{"MATRIX_STORE_DIR", text(&c.MatrixStoreDir)},
+		{"MATRIX_STORE_TTL", duration(&c.MatrixStoreTTL)},
+		{"MATRIX_STORE_MAX_BYTES", positiveInt64(&c.MatrixStoreMaxBytes)},
+		{"RESULT_CACHE_BYTES", nonNegativeInt64(&c.ResultCacheBytes)},
+		{"OPERATION_TIMEOUT", duration(&c.OperationTimeout)},
+		{"OPERATION_TIMEOUTS", durations(&c.OperationTimeouts)},
+		{"PARALLEL_WORKERS", positiveInt(&c.ParallelWorkers)},
+		{"PARALLEL_THRESHOLD", nonNegativeInt(&c.ParallelThreshold)},
+		{"MAX_CELLS", positiveInt(&c.MaxCells)},
+		{"ADMISSION_MAX_IN_FLIGHT", nonNegativeInt(&c.AdmissionMaxInFlight)},
+		{"ADMISSION_MAX_BYTES", nonNegativeInt64(&c.AdmissionMaxBytes)},
+		{"ADMISSION_QUEUE_SIZE", nonNegativeInt(&c.AdmissionQueueSize)},
+		{"ADMISSION_QUEUE_TIMEOUT", duration(&c.AdmissionQueueTimeout)},
+		{"RATE_LIMIT", limit(&c.RateLimit)},
+		{"RATE_LIMITS", limits(&c.RateLimits)},
+		{"AUTH_KEYS_FILE", text(&c.AuthKeysFile)},
+		{"AUTH_MAX_SKEW", duration(&c.AuthMaxSkew)},
+		{"JWT_JWKS_FILE", text(&c.JWTJWKSFile)},
+		{"JWT_SECRET", text(&c.JWTSecret)},
+		{"JWT_PUBLIC_KEY_FILE", text(&c.JWTPublicKeyFile)},
+		{"JWT_AUDIENCE", text(&c.JWTAudience)},
+		{"JWT_LEEWAY", duration(&c.JWTLeeway)},
+		{"TLS_CERT_FILE", text(&c.TLSCertFile)},
+		{"TLS_KEY_FILE", text(&c.TLSKeyFile)},
+		{"TLS_MIN_VERSION", tlsVersion(&c.TLSMinVersion)},
+		{"TLS_CLIENT_CA_FILE", text(&c.TLSClientCAFile)},
+		{"TLS_CLIENT_CERT", clientCert(&c.TLSRequireClientCert)},
+		{"TLS_RELOAD_INTERVAL", duration(&c.TLSReloadInterval)},
+	} {
+		value := getenv(v.name)
+		if value == "" {
+			continue
+		}
+		if err := v.parse(value); err != nil {
+			return Config{}, fmt.Errorf("%w: %s=%q: %v", ErrInvalidConfig, v.name, value, err)
+		}
+	}
+	if c.JWTEnabled() && c.JWTAudience == "" {
+		return Config{}, fmt.Errorf("%w: JWT_AUDIENCE must be set to accept tokens", ErrInvalidConfig)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return Config{}, fmt.Errorf("%w: TLS_CERT_FILE and TLS_KEY_FILE must be set together", ErrInvalidConfig)
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return Config{}, fmt.Errorf("%w: TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE", ErrInvalidConfig)
+	}
+
+	return c, nil
+}
+
+func text(dst *string) func(string) error {
+	return func(s string) error {
+		*dst = s
+		return nil
+	}
+}
+
+func positiveInt(dst *int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return errors.New("want a positive integer")
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func nonNegativeInt(dst *int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return errors.New("want a non-negative integer")
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func positiveInt64(dst *int64) func(string) error {
+	return func(s string) error {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 1 {
+			return errors.New("want a positive integer")
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func nonNegativeInt64(dst *int64) func(string) error {
+	return func(s string) error {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("want a non-negative integer")
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func duration(dst *time.Duration) func(string) error {
+	return func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return errors.New("want a non-negative duration such as 30m")
+		}
+		*dst = d
+		return nil
+	}
+}
+
+// tlsVersion parses a minimum TLS version, 1.2 or 1.3.
+func tlsVersion(dst *uint16) func(string) error {
+	return func(s string) error {
+		v, err := certs.ParseVersion(s)
+		if err != nil {
+			return errors.New("want 1.2 or 1.3")
+		}
+		*dst = v
+		return nil
+	}
+}
+
+// clientCert parses whether client certificates are required or optional.
+func clientCert(dst *bool) func(string) error {
+	return func(s string) error {
+		switch s {
+		case "require":
+			*dst = true
+		case "optional":
+			*dst = false
+		default:
+			return errors.New("want require or optional")
+		}
+		return nil
+	}
+}
+
+// durations parses a comma-separated list of name=duration pairs.
+func durations(dst *map[string]time.Duration) func(string) error {
+	return func(s string) error {
+		m := make(map[string]time.Duration)
+		for _, pair := range strings.Split(s, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			d, err := time.ParseDuration(value)
+			if !ok || name == "" || err != nil || d < 0 {
+				return errors.New("want name=duration pairs such as invert=2m,stats=30s")
+			}
+			m[name] = d
+		}
+		*dst = m
+		return nil
+	}
+}
+
+// limit parses a rate limit such as 100/m, or 0 for none.
+func limit(dst *ratelimit.Limit) func(string) error {
+	return func(s string) error {
+		if s == "0" {
+			*dst = ratelimit.Limit{}
+			return nil
+		}
+		l, err := ratelimit.ParseLimit(s)
+		if err != nil {
+			return errors.New("want count/window such as 100/m, or 0 for no limit")
+		}
+		*dst = l
+		return nil
+	}
+}
+
+// limits parses a comma-separated list of name=limit pairs.
+func limits(dst *map[string]ratelimit.Limit) func(string) error {
+	return func(s string) error {
+		m := make(map[string]ratelimit.Limit)
+		for _, pair := range strings.Split(s, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			var l ratelimit.Limit
+			if !ok || name == "" || limit(&l)(value) != nil {
+				return errors.New("want name=limit pairs such as invert=10/m,sum=100/m")
+			}
+			m[name] = l
+		}
+		*dst = m
+		return nil
+	}
+}
+
+// JWTEnabled reports whether any key for bearer tokens is configured.
+func (c Config) JWTEnabled() bool {
+	return c.JWTJWKSFile != "" || c.JWTSecret != "" || c.JWTPublicKeyFile != ""
+}
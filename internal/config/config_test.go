@@ -0,0 +1,90 @@
+package config
+
+import (
+	"crypto/tls"
+	"league/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	cfg, err := Load(env(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, Default, cfg)
+
+	cfg, err = Load(env(map[string]string{"JOB_WORKERS": "8", "JOB_QUEUE_SIZE": "0", "JOB_RETENTION": "5m"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, cfg.JobWorkers)
+	assert.Equal(t, 0, cfg.JobQueueSize)
+	assert.Equal(t, 5*time.Minute, cfg.JobRetention)
+
+	_, err = Load(env(map[string]string{"JOB_WORKERS": "0"}))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.EqualError(t, err, `invalid configuration: JOB_WORKERS="0": want a positive integer`)
+
+	cfg, err = Load(env(map[string]string{"OPERATION_TIMEOUT": "0", "OPERATION_TIMEOUTS": "invert=2m, stats=30s"}))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), cfg.OperationTimeout)
+	assert.Equal(t, map[string]time.Duration{"invert": 2 * time.Minute, "stats": 30 * time.Second}, cfg.OperationTimeouts)
+
+	cfg, err = Load(env(map[string]string{"PARALLEL_WORKERS": "32", "PARALLEL_THRESHOLD": "0", "MAX_CELLS": "1000"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, cfg.ParallelWorkers)
+	assert.Equal(t, 0, cfg.ParallelThreshold)
+	assert.Equal(t, 1000, cfg.MaxCells)
+
+	cfg, err = Load(env(map[string]string{"ADMISSION_MAX_IN_FLIGHT": "0", "ADMISSION_MAX_BYTES": "1024", "ADMISSION_QUEUE_TIMEOUT": "0"}))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.AdmissionMaxInFlight)
+	assert.Equal(t, int64(1024), cfg.AdmissionMaxBytes)
+	assert.Equal(t, Default.AdmissionQueueSize, cfg.AdmissionQueueSize)
+	assert.Equal(t, time.Duration(0), cfg.AdmissionQueueTimeout)
+
+	cfg, err = Load(env(map[string]string{"RATE_LIMIT": "100/m", "RATE_LIMITS": "invert=10/30s,sum=0"}))
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Count: 100, Window: time.Minute}, cfg.RateLimit)
+	assert.Equal(t, map[string]ratelimit.Limit{"invert": {Count: 10, Window: 30 * time.Second}, "sum": {}}, cfg.RateLimits)
+
+	_, err = Load(env(map[string]string{"RATE_LIMIT": "100"}))
+	assert.EqualError(t, err, `invalid configuration: RATE_LIMIT="100": want count/window such as 100/m, or 0 for no limit`)
+	_, err = Load(env(map[string]string{"RATE_LIMITS": "invert=fast"}))
+	assert.EqualError(t, err, `invalid configuration: RATE_LIMITS="invert=fast": want name=limit pairs such as invert=10/m,sum=100/m`)
+
+	cfg, err = Load(env(map[string]string{"AUTH_KEYS_FILE": "/etc/league/keys.json", "AUTH_MAX_SKEW": "1m"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/league/keys.json", cfg.AuthKeysFile)
+	assert.Equal(t, time.Minute, cfg.AuthMaxSkew)
+
+	cfg, err = Load(env(map[string]string{"JWT_JWKS_FILE": "/etc/league/jwks.json", "JWT_AUDIENCE": "league", "JWT_LEEWAY": "0"}))
+	assert.NoError(t, err)
+	assert.True(t, cfg.JWTEnabled())
+	assert.Equal(t, "league", cfg.JWTAudience)
+	assert.Equal(t, time.Duration(0), cfg.JWTLeeway)
+
+	_, err = Load(env(map[string]string{"JWT_SECRET": "0123456789abcdef0123456789abcdef"}))
+	assert.EqualError(t, err, `invalid configuration: JWT_AUDIENCE must be set to accept tokens`)
+
+	cfg, err = Load(env(map[string]string{"TLS_CERT_FILE": "cert.pem", "TLS_KEY_FILE": "key.pem", "TLS_MIN_VERSION": "1.3", "TLS_CLIENT_CA_FILE": "ca.pem", "TLS_CLIENT_CERT": "optional"}))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.TLSMinVersion)
+	assert.False(t, cfg.TLSRequireClientCert)
+
+	_, err = Load(env(map[string]string{"TLS_CERT_FILE": "cert.pem"}))
+	assert.EqualError(t, err, `invalid configuration: TLS_CERT_FILE and TLS_KEY_FILE must be set together`)
+	_, err = Load(env(map[string]string{"TLS_CLIENT_CA_FILE": "ca.pem"}))
+	assert.EqualError(t, err, `invalid configuration: TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE`)
+	_, err = Load(env(map[string]string{"TLS_MIN_VERSION": "1.0"}))
+	assert.EqualError(t, err, `invalid configuration: TLS_MIN_VERSION="1.0": want 1.2 or 1.3`)
+
+	_, err = Load(env(map[string]string{"OPERATION_TIMEOUTS": "invert"}))
+	assert.EqualError(t, err, `invalid configuration: OPERATION_TIMEOUTS="invert": want name=duration pairs such as invert=2m,stats=30s`)
+
+	_, err = Load(env(map[string]string{"SHUTDOWN_TIMEOUT": "soon"}))
+	assert.EqualError(t, err, `invalid configuration: SHUTDOWN_TIMEOUT="soon": want a non-negative duration such as 30m`)
+}
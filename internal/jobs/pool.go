@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("job queue is full")
+var ErrShuttingDown = errors.New("job pool is shutting down")
+var ErrTaskPanicked = errors.New("internal server error")
+
+// Task is the work behind a job. It should stop early once ctx is done,
+// and may call ReportProgress with ctx as it goes.
+type Task func(ctx context.Context) (*Output, error)
+
+type progressKey struct{}
+
+// ReportProgress records that the job running under ctx is fraction done.
+// Progress never moves backwards, and outside a job this does nothing.
+func ReportProgress(ctx context.Context, fraction float64) {
+	if report, ok := ctx.Value(progressKey{}).(func(float64)); ok {
+		report(fraction)
+	}
+}
+
+type queuedTask struct {
+	id   string
+	ctx  context.Context
+	task Task
+}
+
+// Pool runs submitted tasks on a fixed number of workers, holding at most
+// queueSize more until a worker is free. Job state lives in a Store.
+type Pool struct {
+	store Store
+	// retention is how long a finished job is kept; zero keeps it until
+	// it is removed.
+	retention time.Duration
+	queue     chan queuedTask
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	cancels map[string]context.CancelFunc
+}
+
+func NewPool(store Store, workers, queueSize int, retention time.Duration) *Pool {
+	p := &Pool{
+		store:     store,
+		retention: retention,
+		queue:     make(chan queuedTask, queueSize),
+		cancels:   make(map[string]context.CancelFunc),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit queues task as a new job and returns it. It fails with
+// ErrQueueFull rather than waiting for room, and with ErrShuttingDown once
+// Shutdown has been called.
+func (p *Pool) Submit(operation string, task Task) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Operation: operation, Status: StatusQueued, Created: time.Now()}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		cancel()
+		return Job{}, ErrShuttingDown
+	}
+	if err := p.store.Create(job); err != nil {
+		cancel()
+		return Job{}, err
+	}
+	p.cancels[id] = cancel
+
+	select {
+	case p.queue <- queuedTask{id: id, ctx: ctx, task: task}:
+		return job, nil
+	default:
+		delete(p.cancels, id)
+		cancel()
+		p.store.Delete(id)
+		return Job{}, ErrQueueFull
+	}
+}
+
+func (p *Pool) Get(id string) (Job, error) {
+	return p.store.Get(id)
+}
+
+// Cancel stops a queued or running job and marks it canceled. A running
+// task's output is discarded when it returns. Finished jobs are returned
+// unchanged.
+func (p *Pool) Cancel(id string) (Job, error) {
+	p.mu.Lock()
+	cancel, active := p.cancels[id]
+	p.mu.Unlock()
+	if !active {
+		return p.store.Get(id)
+	}
+
+	cancel()
+	now := time.Now()
+	return p.store.Update(id, func(job *Job) {
+		if !job.Status.Done() {
+			job.Status = StatusCanceled
+			job.Finished = &now
+		}
+	})
+}
+
+// Remove deletes a job and its output from the store.
+func (p *Pool) Remove(id string) error {
+	return p.store.Delete(id)
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones
+// to finish. If ctx ends first, the remaining jobs are canceled and ctx's
+// error is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		ids := make([]string, 0, len(p.cancels))
+		for id := range p.cancels {
+			ids = append(ids, id)
+		}
+		p.mu.Unlock()
+		for _, id := range ids {
+			p.Cancel(id)
+		}
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for queued := range p.queue {
+		p.run(queued)
+	}
+}
+
+func (p *Pool) run(queued queuedTask) {
+	defer p.finish(queued.id)
+	// Cancel has already marked a job canceled while it was queued.
+	if queued.ctx.Err() != nil {
+		return
+	}
+
+	started := time.Now()
+	p.store.Update(queued.id, func(job *Job) {
+		job.Status = StatusRunning
+		job.Started = &started
+	})
+	ctx := context.WithValue(queued.ctx, progressKey{}, func(fraction float64) {
+		p.store.Update(queued.id, func(job *Job) {
+			if job.Status == StatusRunning && fraction > job.Progress {
+				job.Progress = min(fraction, 1)
+			}
+		})
+	})
+
+	output, err := runTask(ctx, queued.id, queued.task)
+	finished := time.Now()
+	p.store.Update(queued.id, func(job *Job) {
+		if job.Status == StatusCanceled {
+			return
+		}
+		job.Finished = &finished
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = StatusSucceeded
+		job.Progress = 1
+		job.Output = output
+	})
+}
+
+// runTask runs task, turning a panic into ErrTaskPanicked so that one bad
+// job fails on its own rather than taking the process down with it.
+func runTask(ctx context.Context, id string, task Task) (output *Output, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			fmt.Printf("job %s panicked: %v\n%s", id, v, debug.Stack())
+			output, err = nil, fmt.Errorf("%w: job panicked", ErrTaskPanicked)
+		}
+	}()
+	return task(ctx)
+}
+
+// finish forgets a job's cancel function and schedules its removal.
+func (p *Pool) finish(id string) {
+	p.mu.Lock()
+	if cancel, ok := p.cancels[id]; ok {
+		cancel()
+		delete(p.cancels, id)
+	}
+	p.mu.Unlock()
+
+	if p.retention > 0 {
+		time.AfterFunc(p.retention, func() {
+			p.store.Delete(id)
+		})
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
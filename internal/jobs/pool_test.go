@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor polls until the job reaches status and returns it.
+func waitFor(t *testing.T, p *Pool, id string, status Status) Job {
+	t.Helper()
+	var job Job
+	assert.Eventually(t, func() bool {
+		job, _ = p.Get(id)
+		return job.Status == status
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+// blockingTask runs until ctx is done or release is closed.
+func blockingTask(release chan struct{}) Task {
+	return func(ctx context.Context) (*Output, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return &Output{Body: []byte("done")}, nil
+		}
+	}
+}
+
+func TestPool_ProgressAndCancelRunning(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 1, 0)
+	defer p.Shutdown(context.Background())
+
+	reported := make(chan struct{})
+	job, err := p.Submit("sum", func(ctx context.Context) (*Output, error) {
+		ReportProgress(ctx, 0.5)
+		ReportProgress(ctx, 0.25)
+		close(reported)
+		<-ctx.Done()
+		return &Output{ContentType: "text/plain", Body: []byte("45\n")}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "sum", job.Operation)
+	assert.Len(t, job.ID, 32)
+
+	<-reported
+	running, err := p.Get(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRunning, running.Status)
+	assert.Equal(t, 0.5, running.Progress)
+	assert.NotNil(t, running.Started)
+
+	p.Cancel(job.ID)
+	canceled := waitFor(t, p, job.ID, StatusCanceled)
+	assert.Nil(t, canceled.Output)
+	assert.NotNil(t, canceled.Finished)
+}
+
+func TestPool_RecordsOutputAndErrors(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 2, 2, 0)
+	defer p.Shutdown(context.Background())
+
+	ok, _ := p.Submit("sum", func(ctx context.Context) (*Output, error) {
+		return &Output{ContentType: "text/csv", Body: []byte("45\n")}, nil
+	})
+	failed, _ := p.Submit("sum", func(ctx context.Context) (*Output, error) {
+		return nil, errors.New("integer overflow")
+	})
+
+	job := waitFor(t, p, ok.ID, StatusSucceeded)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.Equal(t, &Output{ContentType: "text/csv", Body: []byte("45\n")}, job.Output)
+
+	job = waitFor(t, p, failed.ID, StatusFailed)
+	assert.Equal(t, "integer overflow", job.Error)
+	assert.Nil(t, job.Output)
+}
+
+func TestPool_RecoversPanics(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 2, 0)
+	defer p.Shutdown(context.Background())
+
+	panicked, _ := p.Submit("reshape", func(ctx context.Context) (*Output, error) {
+		panic("makeslice: len out of range")
+	})
+	ok, _ := p.Submit("sum", func(ctx context.Context) (*Output, error) {
+		return &Output{Body: []byte("45\n")}, nil
+	})
+
+	job := waitFor(t, p, panicked.ID, StatusFailed)
+	assert.Equal(t, "internal server error: job panicked", job.Error)
+	// The worker that ran it carries on with the next job.
+	waitFor(t, p, ok.ID, StatusSucceeded)
+}
+
+func TestPool_CancelQueuedAndQueueFull(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 1, 0)
+	release := make(chan struct{})
+	defer p.Shutdown(context.Background())
+	defer close(release)
+
+	running, _ := p.Submit("invert", blockingTask(release))
+	waitFor(t, p, running.ID, StatusRunning)
+	queued, err := p.Submit("invert", blockingTask(release))
+	assert.NoError(t, err)
+
+	_, err = p.Submit("invert", blockingTask(release))
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	job, err := p.Cancel(queued.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+}
+
+func TestPool_ShutdownDrains(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 2, 0)
+	release := make(chan struct{})
+	first, _ := p.Submit("invert", blockingTask(release))
+	second, _ := p.Submit("invert", blockingTask(release))
+
+	done := make(chan error)
+	go func() { done <- p.Shutdown(context.Background()) }()
+	assert.Eventually(t, func() bool {
+		_, err := p.Submit("invert", blockingTask(release))
+		return errors.Is(err, ErrShuttingDown)
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	assert.NoError(t, <-done)
+	for _, id := range []string{first.ID, second.ID} {
+		job, _ := p.Get(id)
+		assert.Equal(t, StatusSucceeded, job.Status)
+	}
+}
+
+func TestPool_ShutdownTimeoutCancels(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 1, 0)
+	job, _ := p.Submit("invert", blockingTask(make(chan struct{})))
+	waitFor(t, p, job.ID, StatusRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Shutdown(ctx), context.DeadlineExceeded)
+	waitFor(t, p, job.ID, StatusCanceled)
+}
+
+func TestPool_Retention(t *testing.T) {
+	p := NewPool(NewMemoryStore(), 1, 1, 10*time.Millisecond)
+	defer p.Shutdown(context.Background())
+
+	job, _ := p.Submit("sum", func(ctx context.Context) (*Output, error) {
+		return &Output{}, nil
+	})
+	assert.Eventually(t, func() bool {
+		_, err := p.Get(job.ID)
+		return errors.Is(err, ErrJobNotFound)
+	}, time.Second, time.Millisecond)
+}
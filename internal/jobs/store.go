@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether a job in status s has finished, one way or another.
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Output is what a successful job produced.
+type Output struct {
+	ContentType string
+	Body        []byte
+}
+
+// Job is the state of one submitted task. Progress runs from 0 to 1.
+type Job struct {
+	ID        string     `json:"id"`
+	Operation string     `json:"operation"`
+	Status    Status     `json:"status"`
+	Progress  float64    `json:"progress"`
+	Error     string     `json:"error,omitempty"`
+	Created   time.Time  `json:"created"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Output    *Output    `json:"-"`
+}
+
+// Store keeps jobs by ID. Get returns a copy, so callers only change a
+// stored job through Update. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	Create(job Job) error
+	Get(id string) (Job, error)
+	// Update applies fn to the stored job and returns the result.
+	Update(id string, fn func(job *Job)) (Job, error)
+	Delete(id string) error
+}
+
+// MemoryStore is a Store held in a map; jobs are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryStore) Create(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = &job
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+func (s *MemoryStore) Update(id string, fn func(job *Job)) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	fn(job)
+	return *job, nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, id)
+	return nil
+}
@@ -0,0 +1,367 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrInvalidAxis = errors.New("invalid axis")
+var ErrEmptyMatrix = errors.New("matrix is empty")
+
+// Axis selects the lanes an aggregate is computed over: the whole matrix,
+// each row, or each column.
+type Axis int
+
+const (
+	AxisAll Axis = iota
+	AxisRow
+	AxisCol
+)
+
+func ParseAxis(s string) (Axis, error) {
+	switch s {
+	case "", "all":
+		return AxisAll, nil
+	case "row":
+		return AxisRow, nil
+	case "col":
+		return AxisCol, nil
+	}
+
+	return AxisAll, fmt.Errorf("%w: %q (want row, col or all)", ErrInvalidAxis, s)
+}
+
+func (a Axis) String() string {
+	switch a {
+	case AxisRow:
+		return "row"
+	case AxisCol:
+		return "col"
+	}
+
+	return "all"
+}
+
+// laneError tags err with the row or column it occurred in so callers can
+// tell which aggregate failed.
+func laneError(axis Axis, lane int, err error) error {
+	switch axis {
+	case AxisRow:
+		return fmt.Errorf("row %d: %w", lane+1, err)
+	case AxisCol:
+		return fmt.Errorf("col %d: %w", lane+1, err)
+	}
+
+	return err
+}
+
+// fold accumulates the values of a lane. step adds one value to an
+// accumulator, and combine joins the accumulators of two consecutive runs of
+// a lane, each folded from init, into the accumulator of the whole.
+type fold struct {
+	init    int64
+	step    func(acc, val int64) (int64, error)
+	combine func(a, b int64) (int64, error)
+}
+
+var (
+	sumFold     = fold{0, safeAdd, safeAdd}
+	productFold = fold{1, safeMultiply, safeMultiply}
+	minFold     = fold{math.MaxInt64, minOf, minOf}
+	maxFold     = fold{math.MinInt64, maxOf, maxOf}
+	// countFold counts the cells of a lane, whatever their values.
+	countFold = fold{0, func(acc, val int64) (int64, error) { return acc + 1, nil }, safeAdd}
+)
+
+func minOf(a, b int64) (int64, error) {
+	return min(a, b), nil
+}
+
+func maxOf(a, b int64) (int64, error) {
+	return max(a, b), nil
+}
+
+// laneAccumulators returns one accumulator per lane selected by axis, each
+// set to init.
+func laneAccumulators(axis Axis, rows, cols int, init int64) ([]int64, error) {
+	out, err := countCells(axis, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i] = init
+	}
+
+	return out, nil
+}
+
+// reduce folds every lane of m selected by axis with f. Each lane is folded
+// independently, so an error in one row or column is reported against that
+// lane only. Cells marked in null, which may be nil, are skipped.
+//
+// Large matrices are folded in parallel, and give the same results and the
+// same errors as the serial fold.
+func reduce(c *canceller, m NumericMatrix, null [][]bool, axis Axis, f fold) ([]int64, error) {
+	rows, cols := m.Shape()
+	out, err := laneAccumulators(axis, rows, cols, f.init)
+	if err != nil {
+		return nil, err
+	}
+
+	if shards := shardCount(rows, rows*cols); shards > 1 {
+		if out, err := reduceShards(c.ctx, m, null, axis, f, shards); err == nil {
+			return out, nil
+		}
+		// Something failed, or might have failed serially. The serial
+		// fold finds out which error to report.
+	}
+
+	if err := foldRows(c, m, null, axis, f, out, nil, 0, len(m)); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// laneBounds records the lowest and highest accumulator each lane passes
+// through.
+type laneBounds struct {
+	low, high []int64
+}
+
+// foldRows folds rows lo to hi of m into out, widening bounds, which may be
+// nil, to cover every accumulator.
+func foldRows(c *canceller, m NumericMatrix, null [][]bool, axis Axis, f fold, out []int64, bounds *laneBounds, lo, hi int) error {
+	for i := lo; i < hi; i++ {
+		row := m[i]
+		if err := c.step(len(row)); err != nil {
+			return err
+		}
+		for j, val := range row {
+			if null != nil && null[i][j] {
+				continue
+			}
+
+			lane := 0
+			switch axis {
+			case AxisRow:
+				lane = i
+			case AxisCol:
+				lane = j
+			}
+
+			x, err := f.step(out[lane], int64(val))
+			if err != nil {
+				return laneError(axis, lane, err)
+			}
+			out[lane] = x
+			if bounds != nil {
+				bounds.low[lane] = min(bounds.low[lane], x)
+				bounds.high[lane] = max(bounds.high[lane], x)
+			}
+		}
+	}
+
+	return nil
+}
+
+// reduceShards folds runs of rows in parallel. Row lanes lie within one run
+// and are folded in place. Other lanes are folded per run and combined in
+// order, and the serial fold would have reached combine(acc, x) for the
+// accumulator acc of the runs before and every x a run passed through. For
+// every fold here, the lowest and highest x decide whether any of those
+// overflowed, so checking them keeps overflow detection exactly as it is.
+func reduceShards(ctx context.Context, m NumericMatrix, null [][]bool, axis Axis, f fold, shards int) ([]int64, error) {
+	rows, cols := m.Shape()
+	if axis == AxisRow {
+		out, _ := laneAccumulators(axis, rows, cols, f.init)
+		err := runShards(ctx, rows, shards, func(_ int, c *canceller, lo, hi int) error {
+			return foldRows(c, m, null, axis, f, out, nil, lo, hi)
+		})
+		return out, err
+	}
+
+	accs := make([][]int64, shards)
+	bounds := make([]laneBounds, shards)
+	err := runShards(ctx, rows, shards, func(k int, c *canceller, lo, hi int) error {
+		accs[k], _ = laneAccumulators(axis, rows, cols, f.init)
+		bounds[k].low, _ = laneAccumulators(axis, rows, cols, f.init)
+		bounds[k].high, _ = laneAccumulators(axis, rows, cols, f.init)
+		return foldRows(c, m, null, axis, f, accs[k], &bounds[k], lo, hi)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := accs[0]
+	for k := 1; k < shards; k++ {
+		for lane := range out {
+			if _, err := f.combine(out[lane], bounds[k].low[lane]); err != nil {
+				return nil, err
+			}
+			if _, err := f.combine(out[lane], bounds[k].high[lane]); err != nil {
+				return nil, err
+			}
+			if out[lane], err = f.combine(out[lane], accs[k][lane]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (m *NumericMatrix) SumAxis(axis Axis) ([]int64, error) {
+	return m.SumAxisContext(context.Background(), axis)
+}
+
+func (m *NumericMatrix) SumAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return reduce(newCanceller(ctx), *m, nil, axis, sumFold)
+}
+
+func (m *NumericMatrix) MultiplyAxis(axis Axis) ([]int64, error) {
+	return m.MultiplyAxisContext(context.Background(), axis)
+}
+
+func (m *NumericMatrix) MultiplyAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return reduce(newCanceller(ctx), *m, nil, axis, productFold)
+}
+
+func (m *NumericMatrix) Min(axis Axis) ([]int64, error) {
+	return m.MinContext(context.Background(), axis)
+}
+
+func (m *NumericMatrix) MinContext(ctx context.Context, axis Axis) ([]int64, error) {
+	if rows, cols := m.Shape(); rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	return reduce(newCanceller(ctx), *m, nil, axis, minFold)
+}
+
+func (m *NumericMatrix) Max(axis Axis) ([]int64, error) {
+	return m.MaxContext(context.Background(), axis)
+}
+
+func (m *NumericMatrix) MaxContext(ctx context.Context, axis Axis) ([]int64, error) {
+	if rows, cols := m.Shape(); rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	return reduce(newCanceller(ctx), *m, nil, axis, maxFold)
+}
+
+func (m *NumericMatrix) Count(axis Axis) ([]int64, error) {
+	rows, cols := m.Shape()
+	return countCells(axis, rows, cols)
+}
+
+// CountContext never needs to stop early, since counts follow from the
+// shape alone.
+func (m *NumericMatrix) CountContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return m.Count(axis)
+}
+
+func (m *NumericMatrix) Mean(axis Axis) ([]float64, error) {
+	return m.MeanContext(context.Background(), axis)
+}
+
+// MeanContext divides each lane's overflow-checked sum by its cell count.
+func (m *NumericMatrix) MeanContext(ctx context.Context, axis Axis) ([]float64, error) {
+	if rows, cols := m.Shape(); rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	sums, err := m.SumAxisContext(ctx, axis)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := m.Count(axis)
+	if err != nil {
+		return nil, err
+	}
+
+	return means(sums, counts), nil
+}
+
+func means(sums, counts []int64) []float64 {
+	out := make([]float64, len(sums))
+	for i := range sums {
+		out[i] = float64(sums[i]) / float64(counts[i])
+	}
+
+	return out
+}
+
+func (a *AlphanumericMatrix) SumAxis(axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) SumAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) MultiplyAxis(axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) MultiplyAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) Min(axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) MinContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) Max(axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) MaxContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) Mean(axis Axis) ([]float64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) MeanContext(ctx context.Context, axis Axis) ([]float64, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) Count(axis Axis) ([]int64, error) {
+	rows, cols := a.Shape()
+	return countCells(axis, rows, cols)
+}
+
+func (a *AlphanumericMatrix) CountContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return a.Count(axis)
+}
+
+func countCells(axis Axis, rows, cols int) ([]int64, error) {
+	var out []int64
+	switch axis {
+	case AxisAll:
+		return []int64{int64(rows * cols)}, nil
+	case AxisRow:
+		out = make([]int64, rows)
+		for i := range out {
+			out[i] = int64(cols)
+		}
+	case AxisCol:
+		out = make([]int64, cols)
+		for i := range out {
+			out[i] = int64(rows)
+		}
+	default:
+		return nil, ErrInvalidAxis
+	}
+
+	return out, nil
+}
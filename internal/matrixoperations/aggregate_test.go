@@ -0,0 +1,148 @@
+package matrixoperations
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAxis(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Axis
+		wantErr  bool
+	}{
+		{"", AxisAll, false},
+		{"all", AxisAll, false},
+		{"row", AxisRow, false},
+		{"col", AxisCol, false},
+		{"diagonal", AxisAll, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			axis, err := ParseAxis(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAxis)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, axis)
+			}
+		})
+	}
+}
+
+func TestNumericMatrix_SumAxis(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   NumericMatrix
+		axis     Axis
+		expected []int64
+		errMsg   string
+	}{
+		{"All", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisAll, []int64{21}, ""},
+		{"Rows", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisRow, []int64{6, 15}, ""},
+		{"Cols", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisCol, []int64{5, 7, 9}, ""},
+		{"Empty rows", NumericMatrix{}, AxisRow, []int64{}, ""},
+		{"Overflow in second row", NumericMatrix{{1, 2}, {math.MaxInt64, 1}}, AxisRow, nil, "row 2: integer overflow encountered"},
+		{"Overflow in first col", NumericMatrix{{math.MaxInt64, 1}, {1, 1}}, AxisCol, nil, "col 1: integer overflow encountered"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sums, err := tt.matrix.SumAxis(tt.axis)
+			if tt.errMsg != "" {
+				assert.ErrorIs(t, err, ErrOverflow)
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, sums)
+			}
+		})
+	}
+}
+
+func TestNumericMatrix_MultiplyAxis(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   NumericMatrix
+		axis     Axis
+		expected []int64
+		wantErr  bool
+	}{
+		{"All", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisAll, []int64{720}, false},
+		{"Rows", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisRow, []int64{6, 120}, false},
+		{"Cols", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, AxisCol, []int64{4, 10, 18}, false},
+		{"Overflow in one col only", NumericMatrix{{math.MaxInt64, 1}, {2, 1}}, AxisCol, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, err := tt.matrix.MultiplyAxis(tt.axis)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrOverflow)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, products)
+			}
+		})
+	}
+}
+
+func TestNumericMatrix_MinMax(t *testing.T) {
+	matrix := NumericMatrix{{3, -1, 7}, {2, 8, 0}}
+
+	minAll, err := matrix.Min(AxisAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{-1}, minAll)
+
+	minRows, err := matrix.Min(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{-1, 0}, minRows)
+
+	maxCols, err := matrix.Max(AxisCol)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 8, 7}, maxCols)
+
+	empty := NumericMatrix{}
+	_, err = empty.Min(AxisAll)
+	assert.ErrorIs(t, err, ErrEmptyMatrix)
+	_, err = empty.Max(AxisCol)
+	assert.ErrorIs(t, err, ErrEmptyMatrix)
+}
+
+func TestNumericMatrix_MeanCount(t *testing.T) {
+	matrix := NumericMatrix{{1, 2}, {3, 5}}
+
+	means, err := matrix.Mean(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1.5, 4}, means)
+
+	means, err = matrix.Mean(AxisAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{2.75}, means)
+
+	counts, err := matrix.Count(AxisCol)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 2}, counts)
+
+	empty := NumericMatrix{}
+	_, err = empty.Mean(AxisAll)
+	assert.ErrorIs(t, err, ErrEmptyMatrix)
+}
+
+func TestAlphanumericMatrix_Aggregates(t *testing.T) {
+	matrix := AlphanumericMatrix{{"a", "b", "c"}, {"d", "e", "f"}}
+
+	_, err := matrix.SumAxis(AxisRow)
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+	_, err = matrix.Min(AxisAll)
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+	_, err = matrix.Mean(AxisCol)
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+
+	counts, err := matrix.Count(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 3}, counts)
+}
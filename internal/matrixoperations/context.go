@@ -0,0 +1,39 @@
+package matrixoperations
+
+import "context"
+
+// Every operation that visits cells has a variant with a Context suffix,
+// such as InvertContext or SumAxisContext, which stops once its context ends
+// and returns the context's error. The receiver is left unchanged when it
+// does, so a canceled operation never leaves a half-transformed matrix.
+
+// checkInterval is roughly how many cells an operation works through
+// between checks of its context. A check is cheap but not free, and at this
+// interval even a very large matrix stops within milliseconds of its
+// context ending.
+const checkInterval = 1 << 14
+
+// canceller lets the loops of a long-running operation notice that its
+// context has ended. One canceller is shared by every pass an operation
+// makes, so work is counted across them.
+type canceller struct {
+	ctx  context.Context
+	left int
+}
+
+func newCanceller(ctx context.Context) *canceller {
+	return &canceller{ctx: ctx}
+}
+
+// step records that n more cells were processed and, about once every
+// checkInterval cells, returns the context's error. The first call always
+// checks, so an operation started on an ended context does no work.
+func (c *canceller) step(n int) error {
+	c.left -= n
+	if c.left > 0 {
+		return nil
+	}
+
+	c.left = checkInterval
+	return c.ctx.Err()
+}
@@ -0,0 +1,148 @@
+package matrixoperations
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// contextual is the set of Context variants every matrix type implements.
+type contextual interface {
+	String() string
+	CellsContext(ctx context.Context) ([][]string, error)
+	FlattenContext(ctx context.Context) (string, error)
+	InvertContext(ctx context.Context) error
+	SumContext(ctx context.Context) (int64, error)
+	MultiplyContext(ctx context.Context) (int64, error)
+	SumAxisContext(ctx context.Context, axis Axis) ([]int64, error)
+	MultiplyAxisContext(ctx context.Context, axis Axis) ([]int64, error)
+	MinContext(ctx context.Context, axis Axis) ([]int64, error)
+	MaxContext(ctx context.Context, axis Axis) ([]int64, error)
+	MeanContext(ctx context.Context, axis Axis) ([]float64, error)
+	CountContext(ctx context.Context, axis Axis) ([]int64, error)
+	StatsContext(ctx context.Context, axis Axis, opts StatsOptions) ([]Stats, error)
+	RotateContext(ctx context.Context, degrees int) error
+	FlipHorizontalContext(ctx context.Context) error
+	FlipVerticalContext(ctx context.Context) error
+	AntiTransposeContext(ctx context.Context) error
+	ReshapeContext(ctx context.Context, rows, cols int) error
+}
+
+// countdownContext is live for its first n calls to Err and canceled after,
+// which stands in for a client that goes away mid-operation.
+type countdownContext struct {
+	context.Context
+	n atomic.Int64
+}
+
+func countdown(n int64) *countdownContext {
+	c := &countdownContext{Context: context.Background()}
+	c.n.Store(n)
+	return c
+}
+
+func (c *countdownContext) Err() error {
+	if c.n.Add(-1) >= 0 {
+		return nil
+	}
+	return context.Canceled
+}
+
+func TestCanceller(t *testing.T) {
+	c := newCanceller(countdown(1))
+	assert.NoError(t, c.step(1))
+	// Checks are spaced checkInterval cells apart.
+	assert.NoError(t, c.step(checkInterval-1))
+	assert.ErrorIs(t, c.step(1), context.Canceled)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, newCanceller(canceled).step(0), context.Canceled)
+}
+
+func TestContextVariants_Canceled(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dense := NumericMatrix{{1, 0, 3}, {0, 5, 6}}
+	matrices := map[string]contextual{
+		"numeric":  &NumericMatrix{{1, 0, 3}, {0, 5, 6}},
+		"nullable": NewNullableMatrix(NumericMatrix{{1, 0, 3}, {0, 5, 6}}, NullSkip, ""),
+		"sparse":   SparseFromDense(dense),
+	}
+	for name, m := range matrices {
+		before := m.String()
+		errs := map[string]error{}
+		_, errs["cells"] = m.CellsContext(canceled)
+		_, errs["flatten"] = m.FlattenContext(canceled)
+		errs["invert"] = m.InvertContext(canceled)
+		_, errs["sum"] = m.SumContext(canceled)
+		_, errs["multiply"] = m.MultiplyContext(canceled)
+		_, errs["sum axis"] = m.SumAxisContext(canceled, AxisRow)
+		_, errs["multiply axis"] = m.MultiplyAxisContext(canceled, AxisCol)
+		_, errs["min"] = m.MinContext(canceled, AxisAll)
+		_, errs["max"] = m.MaxContext(canceled, AxisAll)
+		_, errs["mean"] = m.MeanContext(canceled, AxisRow)
+		_, errs["stats"] = m.StatsContext(canceled, AxisAll, StatsOptions{Percentiles: DefaultPercentiles, Bins: 2})
+		errs["rotate"] = m.RotateContext(canceled, 90)
+		errs["flip horizontal"] = m.FlipHorizontalContext(canceled)
+		errs["flip vertical"] = m.FlipVerticalContext(canceled)
+		errs["anti-transpose"] = m.AntiTransposeContext(canceled)
+		errs["reshape"] = m.ReshapeContext(canceled, 3, 2)
+		for op, err := range errs {
+			assert.ErrorIs(t, err, context.Canceled, "%s %s", name, op)
+		}
+		assert.Equal(t, before, m.String(), name)
+	}
+
+	// Counts follow from the shape, so there is nothing to cancel, unless
+	// nulls have to be left out.
+	counts, err := matrices["sparse"].CountContext(canceled, AxisAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{6}, counts)
+	_, err = matrices["nullable"].CountContext(canceled, AxisAll)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	a := &AlphanumericMatrix{{"a", "b"}, {"c", "d"}}
+	assert.ErrorIs(t, a.InvertContext(canceled), context.Canceled)
+	assert.ErrorIs(t, a.RotateContext(canceled, 180), context.Canceled)
+	_, err = a.SumAxisContext(canceled, AxisAll)
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+	assert.Equal(t, "a,b\nc,d\n", a.String())
+}
+
+func TestContextVariants_StopMidOperation(t *testing.T) {
+	m := make(NumericMatrix, 4*checkInterval)
+	for i := range m {
+		m[i] = []int{i, 1}
+	}
+
+	// The context ends after the first check, long before the matrix is
+	// done, and the operation gives up at the next one.
+	ctx := countdown(1)
+	assert.ErrorIs(t, m.InvertContext(ctx), context.Canceled)
+	rows, cols := m.Shape()
+	assert.Equal(t, 4*checkInterval, rows)
+	assert.Equal(t, 2, cols)
+
+	ctx = countdown(1)
+	_, err := m.SumAxisContext(ctx, AxisCol)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextVariants_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	m := NumericMatrix{{1, 2}, {3, 4}}
+	_, err := m.MeanContext(ctx, AxisAll)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	sums, err := m.SumAxisContext(context.Background(), AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 7}, sums)
+}
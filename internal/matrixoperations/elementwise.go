@@ -0,0 +1,136 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrShapeMismatch = errors.New("shape mismatch")
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Operator is an element-wise arithmetic operation between two matrices of
+// the same shape, or between a matrix and a scalar.
+type Operator int
+
+const (
+	OpAdd Operator = iota
+	OpSubtract
+	OpHadamard
+	OpDivide
+)
+
+func (op Operator) String() string {
+	switch op {
+	case OpAdd:
+		return "add"
+	case OpSubtract:
+		return "subtract"
+	case OpHadamard:
+		return "hadamard"
+	case OpDivide:
+		return "divide"
+	}
+
+	return fmt.Sprintf("Operator(%d)", int(op))
+}
+
+func (op Operator) apply(a, b int64) (int64, error) {
+	switch op {
+	case OpAdd:
+		return safeAdd(a, b)
+	case OpSubtract:
+		return safeSubtract(a, b)
+	case OpHadamard:
+		return safeMultiply(a, b)
+	case OpDivide:
+		return safeDivide(a, b)
+	}
+
+	return 0, ErrUnsupportedOperation
+}
+
+func safeSubtract(a, b int64) (int64, error) {
+	if (b < 0 && a > math.MaxInt64+b) ||
+		(b > 0 && a < math.MinInt64+b) {
+		return 0, ErrOverflow
+	}
+	return a - b, nil
+}
+
+// safeDivide truncates toward zero, like Go's integer division.
+func safeDivide(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, ErrDivisionByZero
+	}
+	if a == math.MinInt64 && b == -1 {
+		return 0, ErrOverflow
+	}
+	return a / b, nil
+}
+
+// toInt narrows an int64 result back into the matrix element type.
+func toInt(x int64) (int, error) {
+	if int64(int(x)) != x {
+		return 0, ErrOverflow
+	}
+	return int(x), nil
+}
+
+func shapeString(rows, cols int) string {
+	return fmt.Sprintf("%dx%d", rows, cols)
+}
+
+// Elementwise combines m with other cell by cell. Both matrices must have the
+// same shape. On failure m is left unchanged and the error names the first
+// failing cell.
+func (m *NumericMatrix) Elementwise(op Operator, other NumericMatrix) error {
+	return m.ElementwiseContext(context.Background(), op, other)
+}
+
+func (m *NumericMatrix) ElementwiseContext(ctx context.Context, op Operator, other NumericMatrix) error {
+	rows, cols := m.Shape()
+	otherRows, otherCols := other.Shape()
+	if rows != otherRows || cols != otherCols {
+		return fmt.Errorf("%w: left matrix is %s, right matrix is %s",
+			ErrShapeMismatch, shapeString(rows, cols), shapeString(otherRows, otherCols))
+	}
+
+	return m.combine(newCanceller(ctx), op, func(i, j int) int64 {
+		return int64(other[i][j])
+	})
+}
+
+// ElementwiseScalar combines every cell of m with scalar.
+func (m *NumericMatrix) ElementwiseScalar(op Operator, scalar int64) error {
+	return m.ElementwiseScalarContext(context.Background(), op, scalar)
+}
+
+func (m *NumericMatrix) ElementwiseScalarContext(ctx context.Context, op Operator, scalar int64) error {
+	return m.combine(newCanceller(ctx), op, func(i, j int) int64 {
+		return scalar
+	})
+}
+
+func (m *NumericMatrix) combine(c *canceller, op Operator, operand func(i, j int) int64) error {
+	rows, cols := m.Shape()
+	result := NewNumericMatrix(rows, cols)
+	for i, row := range *m {
+		if err := c.step(len(row)); err != nil {
+			return err
+		}
+		for j, val := range row {
+			x, err := op.apply(int64(val), operand(i, j))
+			if err == nil {
+				result[i][j], err = toInt(x)
+			}
+			if err != nil {
+				return fmt.Errorf("%s failed at row %d col %d: %w", op, i+1, j+1, err)
+			}
+		}
+	}
+
+	*m = result
+	return nil
+}
@@ -0,0 +1,95 @@
+package matrixoperations
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericMatrix_Elementwise(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       Operator
+		left     NumericMatrix
+		right    NumericMatrix
+		expected NumericMatrix
+		errMsg   string
+	}{
+		{
+			"Add", OpAdd,
+			NumericMatrix{{1, 2}, {3, 4}}, NumericMatrix{{10, 20}, {30, 40}},
+			NumericMatrix{{11, 22}, {33, 44}}, "",
+		},
+		{
+			"Subtract", OpSubtract,
+			NumericMatrix{{1, 2}, {3, 4}}, NumericMatrix{{4, 3}, {2, 1}},
+			NumericMatrix{{-3, -1}, {1, 3}}, "",
+		},
+		{
+			"Hadamard", OpHadamard,
+			NumericMatrix{{1, 2}, {3, 4}}, NumericMatrix{{2, 2}, {-1, 0}},
+			NumericMatrix{{2, 4}, {-3, 0}}, "",
+		},
+		{
+			"Divide truncates toward zero", OpDivide,
+			NumericMatrix{{7, -7}, {9, 0}}, NumericMatrix{{2, 2}, {3, 5}},
+			NumericMatrix{{3, -3}, {3, 0}}, "",
+		},
+		{
+			"Empty matrices", OpAdd,
+			NumericMatrix{}, NumericMatrix{},
+			NumericMatrix{}, "",
+		},
+		{
+			"Shape mismatch", OpAdd,
+			NumericMatrix{{1, 2, 3}, {4, 5, 6}}, NumericMatrix{{1, 2}, {3, 4}},
+			nil, "shape mismatch: left matrix is 2x3, right matrix is 2x2",
+		},
+		{
+			"Add overflow reports cell", OpAdd,
+			NumericMatrix{{1, 2}, {3, math.MaxInt64}}, NumericMatrix{{1, 1}, {1, 1}},
+			nil, "add failed at row 2 col 2: integer overflow encountered",
+		},
+		{
+			"Subtract overflow reports cell", OpSubtract,
+			NumericMatrix{{math.MinInt64, 0}}, NumericMatrix{{1, 0}},
+			nil, "subtract failed at row 1 col 1: integer overflow encountered",
+		},
+		{
+			"Divide by zero reports cell", OpDivide,
+			NumericMatrix{{1, 2}}, NumericMatrix{{1, 0}},
+			nil, "divide failed at row 1 col 2: division by zero",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append(NumericMatrix(nil), tt.left...)
+			err := tt.left.Elementwise(tt.op, tt.right)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				assert.Equal(t, original, tt.left)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, tt.left)
+			}
+		})
+	}
+}
+
+func TestNumericMatrix_ElementwiseScalar(t *testing.T) {
+	matrix := NumericMatrix{{1, 2}, {3, 4}}
+	assert.NoError(t, matrix.ElementwiseScalar(OpHadamard, 3))
+	assert.Equal(t, NumericMatrix{{3, 6}, {9, 12}}, matrix)
+
+	assert.NoError(t, matrix.ElementwiseScalar(OpSubtract, 1))
+	assert.Equal(t, NumericMatrix{{2, 5}, {8, 11}}, matrix)
+
+	err := matrix.ElementwiseScalar(OpDivide, 0)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+
+	overflow := NumericMatrix{{math.MinInt64}}
+	err = overflow.ElementwiseScalar(OpDivide, -1)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
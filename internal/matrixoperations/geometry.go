@@ -0,0 +1,296 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidRotation = errors.New("invalid rotation")
+var ErrInvalidShape = errors.New("invalid shape")
+
+// transpose swaps the rows and columns of m with a blocked copy.
+func transpose[T any](c *canceller, m [][]T) ([][]T, error) {
+	return view(c, m, strided[T].transposed)
+}
+
+// antiTranspose mirrors m across its anti-diagonal, which runs from the top
+// right to the bottom left corner.
+func antiTranspose[T any](c *canceller, m [][]T) ([][]T, error) {
+	return view(c, m, strided[T].transposed, strided[T].flippedHorizontal, strided[T].flippedVertical)
+}
+
+// flipHorizontal mirrors m left to right.
+func flipHorizontal[T any](c *canceller, m [][]T) ([][]T, error) {
+	return view(c, m, strided[T].flippedHorizontal)
+}
+
+// flipVertical mirrors m top to bottom.
+func flipVertical[T any](c *canceller, m [][]T) ([][]T, error) {
+	return view(c, m, strided[T].flippedVertical)
+}
+
+// rotate turns m clockwise by degrees, which must be a multiple of 90.
+// Negative values rotate counterclockwise.
+func rotate[T any](c *canceller, m [][]T, degrees int) ([][]T, error) {
+	if degrees%90 != 0 {
+		return nil, fmt.Errorf("%w: %d degrees is not a multiple of 90", ErrInvalidRotation, degrees)
+	}
+
+	switch ((degrees % 360) + 360) % 360 {
+	case 90:
+		return view(c, m, strided[T].transposed, strided[T].flippedHorizontal)
+	case 180:
+		return view(c, m, strided[T].flippedHorizontal, strided[T].flippedVertical)
+	case 270:
+		return view(c, m, strided[T].transposed, strided[T].flippedVertical)
+	}
+	return view[T](c, m)
+}
+
+// view applies each transform to a strided view of m in turn, then copies
+// the result out once. An empty m is returned as it is.
+func view[T any](c *canceller, m [][]T, transforms ...func(strided[T]) strided[T]) ([][]T, error) {
+	if len(m) == 0 {
+		return m, nil
+	}
+
+	s, err := stridedOf(c, m)
+	if err != nil {
+		return nil, err
+	}
+	for _, transform := range transforms {
+		s = transform(s)
+	}
+
+	return s.materialize(c)
+}
+
+// reshape lays the elements of m out as rows x cols, preserving row-major
+// order.
+func reshape[T any](c *canceller, m [][]T, rows, cols int) ([][]T, error) {
+	count := 0
+	if len(m) > 0 {
+		count = len(m) * len(m[0])
+	}
+	if !holds(rows, cols, count) {
+		return nil, fmt.Errorf("%w: cannot reshape %d elements into %s", ErrInvalidShape, count, shapeString(rows, cols))
+	}
+
+	s, err := stridedOf(c, m)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.copyOut(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return rowsOf(data, rows, cols), nil
+}
+
+// holds reports whether a rows x cols matrix has exactly count cells,
+// without letting rows*cols overflow.
+func holds(rows, cols, count int) bool {
+	return rows >= 1 && cols >= 1 && rows <= count/cols && rows*cols == count
+}
+
+// selectCells keeps the given rows and columns of m, in the given order. A nil
+// index list keeps every row or column.
+// checkSelection refuses to select rows and cols from a matrix of shape
+// r x c when repeated indices would make more cells than CheckShape allows.
+func checkSelection(r, c int, rows, cols []int) error {
+	if rows != nil {
+		r = len(rows)
+	}
+	if cols != nil {
+		c = len(cols)
+	}
+	return CheckShape(r, c)
+}
+
+func selectCells[T any](m [][]T, rows, cols []int) [][]T {
+	if rows == nil {
+		rows = make([]int, len(m))
+		for i := range rows {
+			rows[i] = i
+		}
+	}
+
+	out := make([][]T, len(rows))
+	for i, r := range rows {
+		if cols == nil {
+			out[i] = append([]T(nil), m[r]...)
+			continue
+		}
+		out[i] = make([]T, len(cols))
+		for j, c := range cols {
+			out[i][j] = m[r][c]
+		}
+	}
+
+	return out
+}
+
+// Select keeps the given rows and columns. Indices must be in range; a nil
+// list keeps every row or column. Indices may repeat, up to what CheckShape
+// allows.
+func (m *NumericMatrix) Select(rows, cols []int) error {
+	r, c := m.Shape()
+	if err := checkSelection(r, c, rows, cols); err != nil {
+		return err
+	}
+	*m = selectCells(*m, rows, cols)
+	return nil
+}
+
+func (m *NumericMatrix) Rotate(degrees int) error {
+	return m.RotateContext(context.Background(), degrees)
+}
+
+func (m *NumericMatrix) RotateContext(ctx context.Context, degrees int) error {
+	rotated, err := rotate(newCanceller(ctx), *m, degrees)
+	if err != nil {
+		return err
+	}
+
+	*m = rotated
+	return nil
+}
+
+func (m *NumericMatrix) FlipHorizontal() {
+	m.FlipHorizontalContext(context.Background())
+}
+
+func (m *NumericMatrix) FlipHorizontalContext(ctx context.Context) error {
+	flipped, err := flipHorizontal(newCanceller(ctx), *m)
+	if err != nil {
+		return err
+	}
+
+	*m = flipped
+	return nil
+}
+
+func (m *NumericMatrix) FlipVertical() {
+	m.FlipVerticalContext(context.Background())
+}
+
+func (m *NumericMatrix) FlipVerticalContext(ctx context.Context) error {
+	flipped, err := flipVertical(newCanceller(ctx), *m)
+	if err != nil {
+		return err
+	}
+
+	*m = flipped
+	return nil
+}
+
+func (m *NumericMatrix) AntiTranspose() {
+	m.AntiTransposeContext(context.Background())
+}
+
+func (m *NumericMatrix) AntiTransposeContext(ctx context.Context) error {
+	mirrored, err := antiTranspose(newCanceller(ctx), *m)
+	if err != nil {
+		return err
+	}
+
+	*m = mirrored
+	return nil
+}
+
+func (m *NumericMatrix) Reshape(rows, cols int) error {
+	return m.ReshapeContext(context.Background(), rows, cols)
+}
+
+func (m *NumericMatrix) ReshapeContext(ctx context.Context, rows, cols int) error {
+	reshaped, err := reshape(newCanceller(ctx), *m, rows, cols)
+	if err != nil {
+		return err
+	}
+
+	*m = reshaped
+	return nil
+}
+
+// Select keeps the given rows and columns. Indices must be in range; a nil
+// list keeps every row or column.
+func (a *AlphanumericMatrix) Select(rows, cols []int) error {
+	r, c := a.Shape()
+	if err := checkSelection(r, c, rows, cols); err != nil {
+		return err
+	}
+	*a = selectCells(*a, rows, cols)
+	return nil
+}
+
+func (a *AlphanumericMatrix) Rotate(degrees int) error {
+	return a.RotateContext(context.Background(), degrees)
+}
+
+func (a *AlphanumericMatrix) RotateContext(ctx context.Context, degrees int) error {
+	rotated, err := rotate(newCanceller(ctx), *a, degrees)
+	if err != nil {
+		return err
+	}
+
+	*a = rotated
+	return nil
+}
+
+func (a *AlphanumericMatrix) FlipHorizontal() {
+	a.FlipHorizontalContext(context.Background())
+}
+
+func (a *AlphanumericMatrix) FlipHorizontalContext(ctx context.Context) error {
+	flipped, err := flipHorizontal(newCanceller(ctx), *a)
+	if err != nil {
+		return err
+	}
+
+	*a = flipped
+	return nil
+}
+
+func (a *AlphanumericMatrix) FlipVertical() {
+	a.FlipVerticalContext(context.Background())
+}
+
+func (a *AlphanumericMatrix) FlipVerticalContext(ctx context.Context) error {
+	flipped, err := flipVertical(newCanceller(ctx), *a)
+	if err != nil {
+		return err
+	}
+
+	*a = flipped
+	return nil
+}
+
+func (a *AlphanumericMatrix) AntiTranspose() {
+	a.AntiTransposeContext(context.Background())
+}
+
+func (a *AlphanumericMatrix) AntiTransposeContext(ctx context.Context) error {
+	mirrored, err := antiTranspose(newCanceller(ctx), *a)
+	if err != nil {
+		return err
+	}
+
+	*a = mirrored
+	return nil
+}
+
+func (a *AlphanumericMatrix) Reshape(rows, cols int) error {
+	return a.ReshapeContext(context.Background(), rows, cols)
+}
+
+func (a *AlphanumericMatrix) ReshapeContext(ctx context.Context, rows, cols int) error {
+	reshaped, err := reshape(newCanceller(ctx), *a, rows, cols)
+	if err != nil {
+		return err
+	}
+
+	*a = reshaped
+	return nil
+}
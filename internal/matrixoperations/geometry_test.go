@@ -0,0 +1,164 @@
+package matrixoperations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericMatrix_Rotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		degrees  int
+		expected NumericMatrix
+		wantErr  bool
+	}{
+		{"0 degrees", 0, NumericMatrix{{1, 2, 3}, {4, 5, 6}}, false},
+		{"90 degrees", 90, NumericMatrix{{4, 1}, {5, 2}, {6, 3}}, false},
+		{"180 degrees", 180, NumericMatrix{{6, 5, 4}, {3, 2, 1}}, false},
+		{"270 degrees", 270, NumericMatrix{{3, 6}, {2, 5}, {1, 4}}, false},
+		{"-90 degrees", -90, NumericMatrix{{3, 6}, {2, 5}, {1, 4}}, false},
+		{"450 degrees", 450, NumericMatrix{{4, 1}, {5, 2}, {6, 3}}, false},
+		{"45 degrees", 45, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix := NumericMatrix{{1, 2, 3}, {4, 5, 6}}
+			err := matrix.Rotate(tt.degrees)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRotation)
+				assert.Equal(t, NumericMatrix{{1, 2, 3}, {4, 5, 6}}, matrix)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, matrix)
+			}
+		})
+	}
+}
+
+func TestNumericMatrix_Flip(t *testing.T) {
+	matrix := NumericMatrix{{1, 2, 3}, {4, 5, 6}}
+	matrix.FlipHorizontal()
+	assert.Equal(t, NumericMatrix{{3, 2, 1}, {6, 5, 4}}, matrix)
+
+	matrix.FlipVertical()
+	assert.Equal(t, NumericMatrix{{6, 5, 4}, {3, 2, 1}}, matrix)
+
+	empty := NumericMatrix{}
+	empty.FlipHorizontal()
+	empty.FlipVertical()
+	assert.Equal(t, NumericMatrix{}, empty)
+}
+
+func TestNumericMatrix_AntiTranspose(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   NumericMatrix
+		expected NumericMatrix
+	}{
+		{"3x3 matrix", NumericMatrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}, NumericMatrix{{9, 6, 3}, {8, 5, 2}, {7, 4, 1}}},
+		{"2x3 matrix", NumericMatrix{{1, 2, 3}, {4, 5, 6}}, NumericMatrix{{6, 3}, {5, 2}, {4, 1}}},
+		{"Empty matrix", NumericMatrix{}, NumericMatrix{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.matrix.AntiTranspose()
+			assert.Equal(t, tt.expected, tt.matrix)
+		})
+	}
+}
+
+func TestNumericMatrix_Reshape(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows, cols int
+		expected   NumericMatrix
+		errMsg     string
+	}{
+		{"3x2", 3, 2, NumericMatrix{{1, 2}, {3, 4}, {5, 6}}, ""},
+		{"1x6", 1, 6, NumericMatrix{{1, 2, 3, 4, 5, 6}}, ""},
+		{"6x1", 6, 1, NumericMatrix{{1}, {2}, {3}, {4}, {5}, {6}}, ""},
+		{"Wrong element count", 4, 2, nil, "invalid shape: cannot reshape 6 elements into 4x2"},
+		{"Zero rows", 0, 6, nil, "invalid shape: cannot reshape 6 elements into 0x6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix := NumericMatrix{{1, 2, 3}, {4, 5, 6}}
+			err := matrix.Reshape(tt.rows, tt.cols)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, matrix)
+			}
+		})
+	}
+}
+
+func TestAlphanumericMatrix_Geometry(t *testing.T) {
+	matrix := AlphanumericMatrix{{"a", "b", "c"}, {"d", "e", "f"}}
+	assert.NoError(t, matrix.Rotate(90))
+	assert.Equal(t, AlphanumericMatrix{{"d", "a"}, {"e", "b"}, {"f", "c"}}, matrix)
+
+	matrix.FlipVertical()
+	assert.Equal(t, AlphanumericMatrix{{"f", "c"}, {"e", "b"}, {"d", "a"}}, matrix)
+
+	matrix.FlipHorizontal()
+	assert.Equal(t, AlphanumericMatrix{{"c", "f"}, {"b", "e"}, {"a", "d"}}, matrix)
+
+	matrix.AntiTranspose()
+	assert.Equal(t, AlphanumericMatrix{{"d", "e", "f"}, {"a", "b", "c"}}, matrix)
+
+	assert.NoError(t, matrix.Reshape(3, 2))
+	assert.Equal(t, AlphanumericMatrix{{"d", "e"}, {"f", "a"}, {"b", "c"}}, matrix)
+
+	assert.ErrorIs(t, matrix.Reshape(5, 1), ErrInvalidShape)
+	// 4611686018427387905 x 4 wraps around to 4 cells.
+	assert.ErrorIs(t, matrix.Reshape(1<<62+1, 4), ErrInvalidShape)
+}
+
+func TestSparseMatrix_ReshapeOverflow(t *testing.T) {
+	matrix, err := NewSparseMatrix(2, 2, []Entry{{Row: 0, Col: 0, Value: 1}})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, matrix.Reshape(1<<62+1, 4), ErrInvalidShape)
+}
+
+func TestNumericMatrix_SelectTooLarge(t *testing.T) {
+	defer func(n int) { MaxCells = n }(MaxCells)
+	MaxCells = 8
+
+	matrix := NumericMatrix{{1, 2}, {3, 4}}
+	assert.ErrorIs(t, matrix.Select([]int{0, 0, 1}, []int{0, 1, 0}), ErrTooManyCells)
+	assert.Equal(t, NumericMatrix{{1, 2}, {3, 4}}, matrix)
+	assert.NoError(t, matrix.Select([]int{0, 0, 1, 1}, nil))
+}
+
+func TestAlphanumericMatrix_InvertNonSquare(t *testing.T) {
+	matrix := AlphanumericMatrix{{"a", "b", "c"}, {"d", "e", "f"}}
+	matrix.Invert()
+	assert.Equal(t, AlphanumericMatrix{{"a", "d"}, {"b", "e"}, {"c", "f"}}, matrix)
+}
+
+func TestNumericMatrix_Select(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows, cols []int
+		expected   NumericMatrix
+	}{
+		{"Rows only", []int{2, 0}, nil, NumericMatrix{{7, 8, 9}, {1, 2, 3}}},
+		{"Cols only", nil, []int{1}, NumericMatrix{{2}, {5}, {8}}},
+		{"Rows and cols", []int{1, 2}, []int{2, 0}, NumericMatrix{{6, 4}, {9, 7}}},
+		{"Nothing", []int{}, nil, NumericMatrix{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix := NumericMatrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+			assert.NoError(t, matrix.Select(tt.rows, tt.cols))
+			assert.Equal(t, tt.expected, matrix)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package matrixoperations
+
+import "slices"
+
+// Labels names the rows and columns of a matrix. Either list may be nil when
+// the matrix has no labels on that side. Corner names the row label column
+// itself, such as "region" in a header of region,q1,q2.
+//
+// The transforming methods mirror the matrix operations of the same name, so
+// applying both keeps every label attached to its row or column.
+type Labels struct {
+	Corner string
+	Rows   []string
+	Cols   []string
+}
+
+func reversed(s []string) []string {
+	out := slices.Clone(s)
+	slices.Reverse(out)
+	return out
+}
+
+func (l *Labels) Invert() {
+	l.Rows, l.Cols = l.Cols, l.Rows
+}
+
+func (l *Labels) FlipHorizontal() {
+	l.Cols = reversed(l.Cols)
+}
+
+func (l *Labels) FlipVertical() {
+	l.Rows = reversed(l.Rows)
+}
+
+func (l *Labels) AntiTranspose() {
+	l.Rows, l.Cols = reversed(l.Cols), reversed(l.Rows)
+}
+
+// Rotate turns the labels clockwise by degrees, which the matrix rotation
+// has already validated as a multiple of 90.
+func (l *Labels) Rotate(degrees int) {
+	switch ((degrees % 360) + 360) % 360 {
+	case 90:
+		l.Rows, l.Cols = l.Cols, reversed(l.Rows)
+	case 180:
+		l.Rows, l.Cols = reversed(l.Rows), reversed(l.Cols)
+	case 270:
+		l.Rows, l.Cols = reversed(l.Cols), l.Rows
+	}
+}
+
+// Select keeps the labels of the given rows and columns; a nil index list
+// keeps every label on that side.
+func (l *Labels) Select(rows, cols []int) {
+	pick := func(labels []string, indices []int) []string {
+		if labels == nil || indices == nil {
+			return labels
+		}
+		out := make([]string, len(indices))
+		for i, idx := range indices {
+			out[i] = labels[idx]
+		}
+		return out
+	}
+
+	l.Rows = pick(l.Rows, rows)
+	l.Cols = pick(l.Cols, cols)
+}
+
+// ForAxis returns the labels of the lanes an aggregate over axis produces,
+// or nil when those lanes are unlabeled.
+func (l *Labels) ForAxis(axis Axis) []string {
+	switch axis {
+	case AxisRow:
+		return l.Rows
+	case AxisCol:
+		return l.Cols
+	}
+
+	return nil
+}
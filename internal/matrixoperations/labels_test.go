@@ -0,0 +1,63 @@
+package matrixoperations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels_FollowMatrix(t *testing.T) {
+	// Each case applies the same operation to a labeled 2x3 matrix whose
+	// cells encode their original row and column, then checks every label
+	// still names the row or column its cells came from.
+	tests := []struct {
+		name  string
+		apply func(*NumericMatrix, *Labels)
+	}{
+		{"Invert", func(m *NumericMatrix, l *Labels) { m.Invert(); l.Invert() }},
+		{"FlipHorizontal", func(m *NumericMatrix, l *Labels) { m.FlipHorizontal(); l.FlipHorizontal() }},
+		{"FlipVertical", func(m *NumericMatrix, l *Labels) { m.FlipVertical(); l.FlipVertical() }},
+		{"AntiTranspose", func(m *NumericMatrix, l *Labels) { m.AntiTranspose(); l.AntiTranspose() }},
+		{"Rotate 90", func(m *NumericMatrix, l *Labels) { _ = m.Rotate(90); l.Rotate(90) }},
+		{"Rotate 180", func(m *NumericMatrix, l *Labels) { _ = m.Rotate(180); l.Rotate(180) }},
+		{"Rotate 270", func(m *NumericMatrix, l *Labels) { _ = m.Rotate(270); l.Rotate(270) }},
+		{"Select", func(m *NumericMatrix, l *Labels) {
+			_ = m.Select([]int{1}, []int{2, 0})
+			l.Select([]int{1}, []int{2, 0})
+		}},
+	}
+
+	rowNames := []string{"r0", "r1"}
+	colNames := []string{"c0", "c1", "c2"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix := NumericMatrix{{0, 1, 2}, {10, 11, 12}}
+			labels := Labels{Rows: rowNames, Cols: colNames}
+			tt.apply(&matrix, &labels)
+
+			for i, row := range matrix {
+				for j, val := range row {
+					// A cell value of 10*r+c came from row r and column c,
+					// which may now sit on either axis.
+					names := []string{rowNames[val/10], colNames[val%10]}
+					assert.ElementsMatch(t, names, []string{labels.Rows[i], labels.Cols[j]})
+				}
+			}
+		})
+	}
+}
+
+func TestLabels_SelectUnlabeledSide(t *testing.T) {
+	labels := Labels{Cols: []string{"a", "b", "c"}}
+	labels.Select([]int{0, 1}, []int{2})
+	assert.Nil(t, labels.Rows)
+	assert.Equal(t, []string{"c"}, labels.Cols)
+}
+
+func TestLabels_ForAxis(t *testing.T) {
+	labels := Labels{Rows: []string{"r"}, Cols: []string{"c"}}
+	assert.Equal(t, []string{"r"}, labels.ForAxis(AxisRow))
+	assert.Equal(t, []string{"c"}, labels.ForAxis(AxisCol))
+	assert.Nil(t, labels.ForAxis(AxisAll))
+}
@@ -1,7 +1,10 @@
 package matrixoperations
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"math"
 	"strconv"
 	"strings"
@@ -9,6 +12,22 @@ import (
 
 var ErrUnsupportedOperation = errors.New("unsupported operation")
 var ErrOverflow = errors.New("integer overflow encountered")
+var ErrTooManyCells = errors.New("matrix has too many cells")
+
+// MaxCells caps the cells of a matrix whose shape is declared, as a Matrix
+// Market size line or the largest index of a sparse matrix does, rather
+// than spelled out cell by cell, so that a few bytes of input cannot make
+// the server allocate without bound. MAX_CELLS overrides it.
+var MaxCells = 1 << 24
+
+// CheckShape returns ErrTooManyCells if a rows x cols matrix has more than
+// MaxCells cells, or more than MaxCells rows or columns.
+func CheckShape(rows, cols int) error {
+	if rows > MaxCells || cols > MaxCells || (rows > 0 && cols > MaxCells/rows) {
+		return fmt.Errorf("%w: %dx%d exceeds %d", ErrTooManyCells, rows, cols, MaxCells)
+	}
+	return nil
+}
 
 type NumericMatrix [][]int
 
@@ -46,124 +65,230 @@ func safeAdd(a, b int64) (int64, error) {
 	return a + b, nil
 }
 
-func (m *NumericMatrix) String() string {
-	var output string
-	for _, row := range *m {
-		strRow := make([]string, len(row))
+func (m *NumericMatrix) Shape() (int, int) {
+	if len(*m) == 0 {
+		return 0, 0
+	}
+
+	return len(*m), len((*m)[0])
+}
+
+// Cells returns the matrix as text, one string per cell.
+func (m *NumericMatrix) Cells() [][]string {
+	cells, _ := m.CellsContext(context.Background())
+	return cells
+}
+
+func (m *NumericMatrix) CellsContext(ctx context.Context) ([][]string, error) {
+	c := newCanceller(ctx)
+	cells := make([][]string, len(*m))
+	for i, row := range *m {
+		if err := c.step(len(row)); err != nil {
+			return nil, err
+		}
+		cells[i] = make([]string, len(row))
 		for j, val := range row {
-			strRow[j] = strconv.Itoa(val)
+			cells[i][j] = strconv.Itoa(val)
 		}
-
-		output = output + strings.Join(strRow, ",") + "\n"
 	}
 
-	return output
+	return cells, nil
 }
 
-func (m *NumericMatrix) Invert() {
-	size := len(*m)
-	if size == 0 {
-		return
-	}
-	rowLen := len((*m)[0])
-	inverted := make(NumericMatrix, rowLen)
-	for i := 0; i < rowLen; i++ {
-		inverted[i] = make([]int, size)
-		for j := 0; j < size; j++ {
-			inverted[i][j] = (*m)[j][i]
+// String returns the matrix as CSV, as WriteTo writes it.
+func (m *NumericMatrix) String() string {
+	return writeString(m.WriteTo)
+}
+
+// WriteTo writes the matrix to w as CSV, one line per row.
+func (m *NumericMatrix) WriteTo(w io.Writer) (int64, error) {
+	return m.WriteToContext(context.Background(), w)
+}
+
+func (m *NumericMatrix) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	rows, cols := m.Shape()
+	return writeCSV(newCanceller(ctx), w, rows, cols, func(dst []byte, i int) []byte {
+		for j, val := range (*m)[i] {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			dst = strconv.AppendInt(dst, int64(val), 10)
 		}
+		return dst
+	})
+}
+
+func (m *NumericMatrix) Invert() {
+	m.InvertContext(context.Background())
+}
+
+func (m *NumericMatrix) InvertContext(ctx context.Context) error {
+	inverted, err := transpose(newCanceller(ctx), *m)
+	if err != nil {
+		return err
 	}
 
 	*m = inverted
+	return nil
 }
 
 func (m *NumericMatrix) Flatten() string {
-	var flat []string
-	for _, row := range *m {
-		for _, val := range row {
-			flat = append(flat, strconv.Itoa(val))
-		}
-	}
+	flat, _ := m.FlattenContext(context.Background())
+	return flat
+}
 
-	return strings.Join(flat, ",")
+func (m *NumericMatrix) FlattenContext(ctx context.Context) (string, error) {
+	rows, cols := m.Shape()
+	return flatten(newCanceller(ctx), rows, cols, func(dst []byte, i, j int) []byte {
+		return strconv.AppendInt(dst, int64((*m)[i][j]), 10)
+	})
 }
 
-func (m *NumericMatrix) Sum() (int64, error) {
-	var sum int64 = 0
-	for _, row := range *m {
-		for _, val := range row {
-			x, err := safeAdd(int64(sum), int64(val))
-			if err != nil {
-				return 0, err
+// flatten joins every cell, appended to a row's text by appendCell, with
+// commas in row-major order. The rows of a large matrix are formatted in
+// parallel.
+func flatten(c *canceller, rows, cols int, appendCell func(dst []byte, i, j int) []byte) (string, error) {
+	if rows == 0 || cols == 0 {
+		return "", nil
+	}
+
+	lines := make([]string, rows)
+	err := forRanges(c, rows, rows*cols, func(c *canceller, lo, hi int) error {
+		var line []byte
+		for i := lo; i < hi; i++ {
+			if err := c.step(cols); err != nil {
+				return err
 			}
-			sum = x
+			line = line[:0]
+			for j := 0; j < cols; j++ {
+				if j > 0 {
+					line = append(line, ',')
+				}
+				line = appendCell(line, i, j)
+			}
+			lines[i] = string(line)
 		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return sum, nil
+	return strings.Join(lines, ","), nil
+}
+
+func (m *NumericMatrix) Sum() (int64, error) {
+	return m.SumContext(context.Background())
+}
+
+func (m *NumericMatrix) SumContext(ctx context.Context) (int64, error) {
+	sums, err := m.SumAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return sums[0], nil
 }
 
 func (m *NumericMatrix) Multiply() (int64, error) {
-	var product int64 = 1
-	for _, row := range *m {
-		for _, val := range row {
-			x, err := safeMultiply(product, int64(val))
-			if err != nil {
-				return 0, err
-			}
-			product = x
+	return m.MultiplyContext(context.Background())
+}
+
+func (m *NumericMatrix) MultiplyContext(ctx context.Context) (int64, error) {
+	products, err := m.MultiplyAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return products[0], nil
+}
+
+func (a *AlphanumericMatrix) Shape() (int, int) {
+	if len(*a) == 0 {
+		return 0, 0
+	}
+
+	return len(*a), len((*a)[0])
+}
+
+func (a *AlphanumericMatrix) Cells() [][]string {
+	cells, _ := a.CellsContext(context.Background())
+	return cells
+}
+
+func (a *AlphanumericMatrix) CellsContext(ctx context.Context) ([][]string, error) {
+	c := newCanceller(ctx)
+	cells := make([][]string, len(*a))
+	for i, row := range *a {
+		if err := c.step(len(row)); err != nil {
+			return nil, err
 		}
+		cells[i] = append([]string(nil), row...)
 	}
 
-	return product, nil
+	return cells, nil
 }
 
+// String returns the matrix as CSV, as WriteTo writes it.
 func (a *AlphanumericMatrix) String() string {
-	var output string
-	for _, row := range *a {
-		strRow := make([]string, len(row))
-		for j, val := range row {
-			strRow[j] = val
-		}
+	return writeString(a.WriteTo)
+}
 
-		output = output + strings.Join(strRow, ",") + "\n"
-	}
+// WriteTo writes the matrix to w as CSV, one line per row, quoting cells
+// that need it.
+func (a *AlphanumericMatrix) WriteTo(w io.Writer) (int64, error) {
+	return a.WriteToContext(context.Background(), w)
+}
 
-	return output
+func (a *AlphanumericMatrix) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	rows, cols := a.Shape()
+	return writeCSV(newCanceller(ctx), w, rows, cols, func(dst []byte, i int) []byte {
+		for j, val := range (*a)[i] {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendField(dst, val)
+		}
+		return dst
+	})
 }
 
 func (a *AlphanumericMatrix) Flatten() string {
-	var flat []string
-	for _, row := range *a {
-		for _, val := range row {
-			flat = append(flat, val)
-		}
-	}
+	flat, _ := a.FlattenContext(context.Background())
+	return flat
+}
 
-	return strings.Join(flat, ",")
+func (a *AlphanumericMatrix) FlattenContext(ctx context.Context) (string, error) {
+	rows, cols := a.Shape()
+	return flatten(newCanceller(ctx), rows, cols, func(dst []byte, i, j int) []byte {
+		return append(dst, (*a)[i][j]...)
+	})
 }
 
 func (a *AlphanumericMatrix) Invert() {
-	size := len(*a)
-	if size == 0 {
-		return
-	}
+	a.InvertContext(context.Background())
+}
 
-	inverted := make(AlphanumericMatrix, size)
-	for i := 0; i < size; i++ {
-		inverted[i] = make([]string, size)
-		for j := 0; j < size; j++ {
-			inverted[i][j] = (*a)[j][i]
-		}
+func (a *AlphanumericMatrix) InvertContext(ctx context.Context) error {
+	inverted, err := transpose(newCanceller(ctx), *a)
+	if err != nil {
+		return err
 	}
 
 	*a = inverted
+	return nil
 }
 
 func (a *AlphanumericMatrix) Sum() (int64, error) {
 	return 0, ErrUnsupportedOperation
 }
 
+func (a *AlphanumericMatrix) SumContext(ctx context.Context) (int64, error) {
+	return 0, ErrUnsupportedOperation
+}
+
 func (a *AlphanumericMatrix) Multiply() (int64, error) {
 	return 0, ErrUnsupportedOperation
 }
+
+func (a *AlphanumericMatrix) MultiplyContext(ctx context.Context) (int64, error) {
+	return 0, ErrUnsupportedOperation
+}
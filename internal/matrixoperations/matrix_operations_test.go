@@ -180,6 +180,11 @@ func TestNumericMatrix_String(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestNumericMatrix_Cells(t *testing.T) {
+	matrix := NumericMatrix{{1, -2}, {3, 4}}
+	assert.Equal(t, [][]string{{"1", "-2"}, {"3", "4"}}, matrix.Cells())
+}
+
 func TestAlphanumericMatrix_Invert(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -261,3 +266,23 @@ func TestAlphanumericMatrix_String(t *testing.T) {
 	result := matrix.String()
 	assert.Equal(t, expected, result)
 }
+
+func TestAlphanumericMatrix_Cells(t *testing.T) {
+	matrix := AlphanumericMatrix{{"a", "b,c"}}
+	cells := matrix.Cells()
+	assert.Equal(t, [][]string{{"a", "b,c"}}, cells)
+
+	cells[0][0] = "z"
+	assert.Equal(t, "a", matrix[0][0], "Cells must not share storage with the matrix")
+}
+
+func TestCheckShape(t *testing.T) {
+	defer func(n int) { MaxCells = n }(MaxCells)
+	MaxCells = 100
+
+	assert.NoError(t, CheckShape(10, 10))
+	assert.NoError(t, CheckShape(0, 100))
+	assert.ErrorIs(t, CheckShape(11, 10), ErrTooManyCells)
+	assert.ErrorIs(t, CheckShape(101, 0), ErrTooManyCells)
+	assert.ErrorIs(t, CheckShape(1<<62+1, 4), ErrTooManyCells)
+}
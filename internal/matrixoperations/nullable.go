@@ -0,0 +1,473 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrNullValue = errors.New("null value")
+var ErrAllNull = errors.New("every value is null")
+var ErrInvalidNullPolicy = errors.New("invalid null policy")
+
+// NullPolicy decides how aggregates and arithmetic treat null cells.
+type NullPolicy int
+
+const (
+	// NullSkip leaves nulls out of aggregates and propagates them through
+	// element-wise arithmetic.
+	NullSkip NullPolicy = iota
+	// NullZero treats nulls as 0.
+	NullZero
+	// NullError fails on the first null cell.
+	NullError
+)
+
+func ParseNullPolicy(s string) (NullPolicy, error) {
+	switch s {
+	case "", "skip":
+		return NullSkip, nil
+	case "zero":
+		return NullZero, nil
+	case "error":
+		return NullError, nil
+	}
+
+	return NullSkip, fmt.Errorf("%w: %q (want skip, zero or error)", ErrInvalidNullPolicy, s)
+}
+
+// NullableMatrix is a numeric matrix in which some cells are null. Null
+// cells hold 0 in Values and are marked in Null, and both grids are kept in
+// step through every shape-changing operation. Nulls are written out as
+// Token.
+type NullableMatrix struct {
+	Values NumericMatrix
+	Null   [][]bool
+	Policy NullPolicy
+	Token  string
+}
+
+// NewNullableMatrix wraps values without any null cells.
+func NewNullableMatrix(values NumericMatrix, policy NullPolicy, token string) *NullableMatrix {
+	null := make([][]bool, len(values))
+	for i, row := range values {
+		null[i] = make([]bool, len(row))
+	}
+
+	return &NullableMatrix{Values: values, Null: null, Policy: policy, Token: token}
+}
+
+func (n *NullableMatrix) Shape() (int, int) {
+	return n.Values.Shape()
+}
+
+// At returns the value at row i, column j and whether it is null. Null cells
+// read as 0.
+func (n *NullableMatrix) At(i, j int) (int64, bool) {
+	if n.Null[i][j] {
+		return 0, true
+	}
+	return int64(n.Values[i][j]), false
+}
+
+func (n *NullableMatrix) firstNull(c *canceller) error {
+	for i, row := range n.Null {
+		if err := c.step(len(row)); err != nil {
+			return err
+		}
+		for j, null := range row {
+			if null {
+				return fmt.Errorf("%w at row %d col %d", ErrNullValue, i+1, j+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipMask returns the null mask the policy asks aggregates to skip, or an
+// error under NullError when a null is present.
+func (n *NullableMatrix) skipMask(c *canceller) ([][]bool, error) {
+	switch n.Policy {
+	case NullZero:
+		return nil, nil
+	case NullError:
+		return nil, n.firstNull(c)
+	}
+
+	return n.Null, nil
+}
+
+// Cells returns the matrix as text, with nulls written as Token.
+func (n *NullableMatrix) Cells() [][]string {
+	cells, _ := n.CellsContext(context.Background())
+	return cells
+}
+
+func (n *NullableMatrix) CellsContext(ctx context.Context) ([][]string, error) {
+	c := newCanceller(ctx)
+	cells := make([][]string, len(n.Values))
+	for i, row := range n.Values {
+		if err := c.step(len(row)); err != nil {
+			return nil, err
+		}
+		cells[i] = make([]string, len(row))
+		for j, val := range row {
+			if n.Null[i][j] {
+				cells[i][j] = n.Token
+			} else {
+				cells[i][j] = strconv.Itoa(val)
+			}
+		}
+	}
+
+	return cells, nil
+}
+
+// String returns the matrix as CSV, as WriteTo writes it.
+func (n *NullableMatrix) String() string {
+	return writeString(n.WriteTo)
+}
+
+// WriteTo writes the matrix to w as CSV, one line per row, with nulls
+// written as Token.
+func (n *NullableMatrix) WriteTo(w io.Writer) (int64, error) {
+	return n.WriteToContext(context.Background(), w)
+}
+
+func (n *NullableMatrix) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	rows, cols := n.Shape()
+	return writeCSV(newCanceller(ctx), w, rows, cols, func(dst []byte, i int) []byte {
+		for j, val := range n.Values[i] {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			if n.Null[i][j] {
+				dst = appendField(dst, n.Token)
+			} else {
+				dst = strconv.AppendInt(dst, int64(val), 10)
+			}
+		}
+		return dst
+	})
+}
+
+func (n *NullableMatrix) Flatten() string {
+	flat, _ := n.FlattenContext(context.Background())
+	return flat
+}
+
+func (n *NullableMatrix) FlattenContext(ctx context.Context) (string, error) {
+	rows, cols := n.Shape()
+	return flatten(newCanceller(ctx), rows, cols, func(dst []byte, i, j int) []byte {
+		if n.Null[i][j] {
+			return append(dst, n.Token...)
+		}
+		return strconv.AppendInt(dst, int64(n.Values[i][j]), 10)
+	})
+}
+
+// reshapeBoth applies the same shape-changing operation to Values and Null,
+// leaving n unchanged on error.
+func (n *NullableMatrix) reshapeBoth(ctx context.Context, values func(*canceller, [][]int) ([][]int, error), null func(*canceller, [][]bool) ([][]bool, error)) error {
+	c := newCanceller(ctx)
+	newValues, err := values(c, n.Values)
+	if err != nil {
+		return err
+	}
+	newNull, err := null(c, n.Null)
+	if err != nil {
+		return err
+	}
+
+	n.Values, n.Null = newValues, newNull
+	return nil
+}
+
+func (n *NullableMatrix) Invert() {
+	n.InvertContext(context.Background())
+}
+
+func (n *NullableMatrix) InvertContext(ctx context.Context) error {
+	return n.reshapeBoth(ctx, transpose[int], transpose[bool])
+}
+
+func (n *NullableMatrix) Sum() (int64, error) {
+	return n.SumContext(context.Background())
+}
+
+func (n *NullableMatrix) SumContext(ctx context.Context) (int64, error) {
+	sums, err := n.SumAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return sums[0], nil
+}
+
+func (n *NullableMatrix) Multiply() (int64, error) {
+	return n.MultiplyContext(context.Background())
+}
+
+func (n *NullableMatrix) MultiplyContext(ctx context.Context) (int64, error) {
+	products, err := n.MultiplyAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return products[0], nil
+}
+
+func (n *NullableMatrix) SumAxis(axis Axis) ([]int64, error) {
+	return n.SumAxisContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) SumAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	c := newCanceller(ctx)
+	mask, err := n.skipMask(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return reduce(c, n.Values, mask, axis, sumFold)
+}
+
+func (n *NullableMatrix) MultiplyAxis(axis Axis) ([]int64, error) {
+	return n.MultiplyAxisContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) MultiplyAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	c := newCanceller(ctx)
+	mask, err := n.skipMask(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return reduce(c, n.Values, mask, axis, productFold)
+}
+
+func (n *NullableMatrix) Count(axis Axis) ([]int64, error) {
+	return n.CountContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) CountContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return n.count(newCanceller(ctx), axis)
+}
+
+func (n *NullableMatrix) count(c *canceller, axis Axis) ([]int64, error) {
+	mask, err := n.skipMask(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return reduce(c, n.Values, mask, axis, countFold)
+}
+
+// nonEmpty fails when a lane has no values left once nulls are skipped.
+func (n *NullableMatrix) nonEmpty(c *canceller, axis Axis) error {
+	if rows, cols := n.Shape(); rows == 0 || cols == 0 {
+		return ErrEmptyMatrix
+	}
+
+	counts, err := n.count(c, axis)
+	if err != nil {
+		return err
+	}
+	for lane, count := range counts {
+		if count == 0 {
+			return laneError(axis, lane, ErrAllNull)
+		}
+	}
+
+	return nil
+}
+
+func (n *NullableMatrix) Min(axis Axis) ([]int64, error) {
+	return n.MinContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) MinContext(ctx context.Context, axis Axis) ([]int64, error) {
+	c := newCanceller(ctx)
+	if err := n.nonEmpty(c, axis); err != nil {
+		return nil, err
+	}
+
+	mask, _ := n.skipMask(c)
+	return reduce(c, n.Values, mask, axis, minFold)
+}
+
+func (n *NullableMatrix) Max(axis Axis) ([]int64, error) {
+	return n.MaxContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) MaxContext(ctx context.Context, axis Axis) ([]int64, error) {
+	c := newCanceller(ctx)
+	if err := n.nonEmpty(c, axis); err != nil {
+		return nil, err
+	}
+
+	mask, _ := n.skipMask(c)
+	return reduce(c, n.Values, mask, axis, maxFold)
+}
+
+func (n *NullableMatrix) Mean(axis Axis) ([]float64, error) {
+	return n.MeanContext(context.Background(), axis)
+}
+
+func (n *NullableMatrix) MeanContext(ctx context.Context, axis Axis) ([]float64, error) {
+	c := newCanceller(ctx)
+	if err := n.nonEmpty(c, axis); err != nil {
+		return nil, err
+	}
+
+	mask, _ := n.skipMask(c)
+	sums, err := reduce(c, n.Values, mask, axis, sumFold)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := n.count(c, axis)
+	if err != nil {
+		return nil, err
+	}
+
+	return means(sums, counts), nil
+}
+
+func (n *NullableMatrix) Stats(axis Axis, opts StatsOptions) ([]Stats, error) {
+	return n.StatsContext(context.Background(), axis, opts)
+}
+
+func (n *NullableMatrix) StatsContext(ctx context.Context, axis Axis, opts StatsOptions) ([]Stats, error) {
+	rows, cols := n.Shape()
+	if err := opts.validate(laneLength(rows, cols, axis)); err != nil {
+		return nil, err
+	}
+	if rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+	c := newCanceller(ctx)
+	mask, err := n.skipMask(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return describeLanes(c, n.Values, mask, axis, opts)
+}
+
+func (n *NullableMatrix) Rotate(degrees int) error {
+	return n.RotateContext(context.Background(), degrees)
+}
+
+func (n *NullableMatrix) RotateContext(ctx context.Context, degrees int) error {
+	return n.reshapeBoth(ctx,
+		func(c *canceller, m [][]int) ([][]int, error) { return rotate(c, m, degrees) },
+		func(c *canceller, m [][]bool) ([][]bool, error) { return rotate(c, m, degrees) })
+}
+
+func (n *NullableMatrix) FlipHorizontal() {
+	n.FlipHorizontalContext(context.Background())
+}
+
+func (n *NullableMatrix) FlipHorizontalContext(ctx context.Context) error {
+	return n.reshapeBoth(ctx, flipHorizontal[int], flipHorizontal[bool])
+}
+
+func (n *NullableMatrix) FlipVertical() {
+	n.FlipVerticalContext(context.Background())
+}
+
+func (n *NullableMatrix) FlipVerticalContext(ctx context.Context) error {
+	return n.reshapeBoth(ctx, flipVertical[int], flipVertical[bool])
+}
+
+func (n *NullableMatrix) AntiTranspose() {
+	n.AntiTransposeContext(context.Background())
+}
+
+func (n *NullableMatrix) AntiTransposeContext(ctx context.Context) error {
+	return n.reshapeBoth(ctx, antiTranspose[int], antiTranspose[bool])
+}
+
+func (n *NullableMatrix) Reshape(rows, cols int) error {
+	return n.ReshapeContext(context.Background(), rows, cols)
+}
+
+func (n *NullableMatrix) ReshapeContext(ctx context.Context, rows, cols int) error {
+	return n.reshapeBoth(ctx,
+		func(c *canceller, m [][]int) ([][]int, error) { return reshape(c, m, rows, cols) },
+		func(c *canceller, m [][]bool) ([][]bool, error) { return reshape(c, m, rows, cols) })
+}
+
+func (n *NullableMatrix) Select(rows, cols []int) error {
+	r, c := n.Shape()
+	if err := checkSelection(r, c, rows, cols); err != nil {
+		return err
+	}
+	n.Values = selectCells(n.Values, rows, cols)
+	n.Null = selectCells(n.Null, rows, cols)
+	return nil
+}
+
+// Elementwise combines n with other cell by cell under n's null policy:
+// NullSkip makes a cell null when either operand is null, NullZero treats
+// nulls as 0 and NullError fails on the first null operand.
+func (n *NullableMatrix) Elementwise(op Operator, other *NullableMatrix) error {
+	return n.ElementwiseContext(context.Background(), op, other)
+}
+
+func (n *NullableMatrix) ElementwiseContext(ctx context.Context, op Operator, other *NullableMatrix) error {
+	rows, cols := n.Shape()
+	otherRows, otherCols := other.Shape()
+	if rows != otherRows || cols != otherCols {
+		return fmt.Errorf("%w: left matrix is %s, right matrix is %s",
+			ErrShapeMismatch, shapeString(rows, cols), shapeString(otherRows, otherCols))
+	}
+
+	return n.combine(newCanceller(ctx), op, other.At)
+}
+
+func (n *NullableMatrix) ElementwiseScalar(op Operator, scalar int64) error {
+	return n.ElementwiseScalarContext(context.Background(), op, scalar)
+}
+
+func (n *NullableMatrix) ElementwiseScalarContext(ctx context.Context, op Operator, scalar int64) error {
+	return n.combine(newCanceller(ctx), op, func(i, j int) (int64, bool) {
+		return scalar, false
+	})
+}
+
+func (n *NullableMatrix) combine(c *canceller, op Operator, operand func(i, j int) (int64, bool)) error {
+	rows, cols := n.Shape()
+	values := NewNumericMatrix(rows, cols)
+	null := newRows[bool](rows, cols)
+	for i, row := range n.Values {
+		if err := c.step(len(row)); err != nil {
+			return err
+		}
+		for j := range row {
+			a, aNull := n.At(i, j)
+			b, bNull := operand(i, j)
+			if aNull || bNull {
+				switch n.Policy {
+				case NullSkip:
+					null[i][j] = true
+					continue
+				case NullError:
+					return fmt.Errorf("%s failed at row %d col %d: %w", op, i+1, j+1, ErrNullValue)
+				}
+			}
+
+			x, err := op.apply(a, b)
+			if err == nil {
+				values[i][j], err = toInt(x)
+			}
+			if err != nil {
+				return fmt.Errorf("%s failed at row %d col %d: %w", op, i+1, j+1, err)
+			}
+		}
+	}
+
+	n.Values = values
+	n.Null = null
+	return nil
+}
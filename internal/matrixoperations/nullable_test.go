@@ -0,0 +1,151 @@
+package matrixoperations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestNullable builds a 2x3 matrix with nulls at (0,1) and (1,2):
+//
+//	1,NA,3
+//	4,5,NA
+func newTestNullable(policy NullPolicy) *NullableMatrix {
+	return &NullableMatrix{
+		Values: NumericMatrix{{1, 0, 3}, {4, 5, 0}},
+		Null:   [][]bool{{false, true, false}, {false, false, true}},
+		Policy: policy,
+		Token:  "NA",
+	}
+}
+
+func TestParseNullPolicy(t *testing.T) {
+	for input, expected := range map[string]NullPolicy{"": NullSkip, "skip": NullSkip, "zero": NullZero, "error": NullError} {
+		policy, err := ParseNullPolicy(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := ParseNullPolicy("ignore")
+	assert.ErrorIs(t, err, ErrInvalidNullPolicy)
+}
+
+func TestNullableMatrix_Output(t *testing.T) {
+	matrix := newTestNullable(NullSkip)
+	assert.Equal(t, "1,NA,3\n4,5,NA\n", matrix.String())
+	assert.Equal(t, "1,NA,3,4,5,NA", matrix.Flatten())
+	assert.Equal(t, [][]string{{"1", "NA", "3"}, {"4", "5", "NA"}}, matrix.Cells())
+
+	matrix.Invert()
+	assert.Equal(t, "1,4\nNA,5\n3,NA\n", matrix.String())
+
+	matrix.FlipVertical()
+	assert.Equal(t, "3,NA\nNA,5\n1,4\n", matrix.String())
+
+	assert.NoError(t, matrix.Rotate(90))
+	assert.Equal(t, "1,NA,3\n4,5,NA\n", matrix.String())
+
+	assert.NoError(t, matrix.Reshape(3, 2))
+	assert.Equal(t, "1,NA\n3,4\n5,NA\n", matrix.String())
+
+	assert.NoError(t, matrix.Select([]int{0, 2}, []int{1}))
+	assert.Equal(t, "NA\nNA\n", matrix.String())
+}
+
+func TestNullableMatrix_AggregatesSkip(t *testing.T) {
+	matrix := newTestNullable(NullSkip)
+
+	sum, err := matrix.Sum()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), sum)
+
+	products, err := matrix.MultiplyAxis(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 20}, products)
+
+	counts, err := matrix.Count(AxisCol)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1, 1}, counts)
+
+	mins, err := matrix.Min(AxisCol)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 5, 3}, mins)
+
+	means, err := matrix.Mean(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{2, 4.5}, means)
+
+	stats, err := matrix.Stats(AxisAll, StatsOptions{Bins: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), stats[0].Count)
+	assert.Equal(t, 3.5, stats[0].Median)
+}
+
+func TestNullableMatrix_AggregatesZero(t *testing.T) {
+	matrix := newTestNullable(NullZero)
+
+	product, err := matrix.Multiply()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), product)
+
+	counts, err := matrix.Count(AxisAll)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{6}, counts)
+
+	mins, err := matrix.Min(AxisCol)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0, 0}, mins)
+}
+
+func TestNullableMatrix_AggregatesError(t *testing.T) {
+	matrix := newTestNullable(NullError)
+
+	_, err := matrix.Sum()
+	assert.EqualError(t, err, "null value at row 1 col 2")
+
+	_, err = matrix.Max(AxisRow)
+	assert.ErrorIs(t, err, ErrNullValue)
+
+	_, err = matrix.Stats(AxisAll, StatsOptions{Bins: 1})
+	assert.ErrorIs(t, err, ErrNullValue)
+}
+
+func TestNullableMatrix_AllNullLane(t *testing.T) {
+	matrix := &NullableMatrix{
+		Values: NumericMatrix{{1, 0}, {2, 0}},
+		Null:   [][]bool{{false, true}, {false, true}},
+	}
+
+	_, err := matrix.Mean(AxisCol)
+	assert.EqualError(t, err, "col 2: every value is null")
+
+	_, err = matrix.Stats(AxisCol, StatsOptions{Bins: 1})
+	assert.ErrorIs(t, err, ErrAllNull)
+
+	means, err := matrix.Mean(AxisRow)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 2}, means)
+}
+
+func TestNullableMatrix_Elementwise(t *testing.T) {
+	other := NewNullableMatrix(NumericMatrix{{10, 10, 10}, {10, 10, 10}}, NullSkip, "NA")
+	other.Null[0][0] = true
+
+	skip := newTestNullable(NullSkip)
+	assert.NoError(t, skip.Elementwise(OpAdd, other))
+	assert.Equal(t, "NA,NA,13\n14,15,NA\n", skip.String())
+
+	zero := newTestNullable(NullZero)
+	assert.NoError(t, zero.Elementwise(OpAdd, other))
+	assert.Equal(t, "1,10,13\n14,15,10\n", zero.String())
+
+	strict := newTestNullable(NullError)
+	assert.EqualError(t, strict.Elementwise(OpAdd, other), "add failed at row 1 col 1: null value")
+
+	scalar := newTestNullable(NullSkip)
+	assert.NoError(t, scalar.ElementwiseScalar(OpHadamard, 2))
+	assert.Equal(t, "2,NA,6\n8,10,NA\n", scalar.String())
+
+	mismatch := newTestNullable(NullSkip)
+	assert.ErrorIs(t, mismatch.Elementwise(OpAdd, NewNullableMatrix(NumericMatrix{{1}}, NullSkip, "")), ErrShapeMismatch)
+}
@@ -0,0 +1,89 @@
+package matrixoperations
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// outputBuffer is how many bytes of output writeCSV gathers before passing
+// them on to its writer.
+const outputBuffer = 64 << 10
+
+// writeCSV writes rows lines of CSV to w, each made by appendRow appending
+// the cells of row i, comma-separated, to dst. Output goes out in chunks of
+// about outputBuffer bytes, so memory use does not grow with the matrix. It
+// returns the number of bytes written.
+func writeCSV(c *canceller, w io.Writer, rows, cols int, appendRow func(dst []byte, i int) []byte) (int64, error) {
+	var written int64
+	buf := make([]byte, 0, outputBuffer)
+	flush := func() error {
+		n, err := w.Write(buf)
+		written += int64(n)
+		buf = buf[:0]
+		return err
+	}
+
+	for i := 0; i < rows; i++ {
+		if err := c.step(cols); err != nil {
+			return written, err
+		}
+		buf = append(appendRow(buf, i), '\n')
+		if len(buf) >= outputBuffer {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	if len(buf) > 0 {
+		if err := flush(); err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// writeString is the String form of a matrix that writes itself with
+// writeTo.
+func writeString(writeTo func(w io.Writer) (int64, error)) string {
+	var output strings.Builder
+	writeTo(&output)
+	return output.String()
+}
+
+// appendField appends s to dst as a CSV field, quoted the way encoding/csv
+// quotes it.
+func appendField(dst []byte, s string) []byte {
+	if !fieldNeedsQuotes(s) {
+		return append(dst, s...)
+	}
+
+	dst = append(dst, '"')
+	for {
+		i := strings.IndexByte(s, '"')
+		if i < 0 {
+			break
+		}
+		dst = append(dst, s[:i+1]...)
+		dst = append(dst, '"')
+		s = s[i+1:]
+	}
+
+	return append(append(dst, s...), '"')
+}
+
+// fieldNeedsQuotes mirrors the rule of encoding/csv's Writer with a comma
+// delimiter.
+func fieldNeedsQuotes(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s == `\.` || strings.ContainsAny(s, ",\"\r\n") {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,113 @@
+package matrixoperations
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// csvOf is what encoding/csv writes for cells.
+func csvOf(cells [][]string) string {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	writer.WriteAll(cells)
+	return output.String()
+}
+
+func TestWriteTo_MatchesEncodingCSV(t *testing.T) {
+	nullable := NewNullableMatrix(NumericMatrix{{1, -2}, {3, 4}}, NullSkip, "N,A")
+	nullable.Null[1][0] = true
+	matrices := map[string]contextual{
+		"numeric":  &NumericMatrix{{1, -20, 300}, {0, 5, -6}},
+		"nullable": nullable,
+		"sparse":   SparseFromDense(NumericMatrix{{0, 0, 7}, {0, 0, 0}, {-1, 0, 0}}),
+		"strings":  &AlphanumericMatrix{{"a,b", `say "hi"`, ""}, {" lead", "two\nlines", `\.`}, {"x", "y\r", "z"}},
+		"empty":    &NumericMatrix{},
+	}
+	for name, m := range matrices {
+		cells, err := m.CellsContext(context.Background())
+		assert.NoError(t, err)
+
+		var output strings.Builder
+		n, err := m.(io.WriterTo).WriteTo(&output)
+		assert.NoError(t, err)
+		assert.Equal(t, csvOf(cells), output.String(), name)
+		assert.Equal(t, int64(output.Len()), n, name)
+		assert.Equal(t, output.String(), m.String(), name)
+	}
+}
+
+// chunkWriter records the size of every write.
+type chunkWriter struct {
+	sizes []int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	return len(p), nil
+}
+
+func TestWriteTo_Chunked(t *testing.T) {
+	m := counting(4*outputBuffer/8, 2, true)
+	var w chunkWriter
+	n, err := m.WriteTo(&w)
+	assert.NoError(t, err)
+
+	total := 0
+	for _, size := range w.sizes {
+		assert.Less(t, size, outputBuffer+64)
+		total += size
+	}
+	assert.Greater(t, len(w.sizes), 1)
+	assert.Equal(t, int64(total), n)
+}
+
+// failingWriter fails every write after the first.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, io.ErrClosedPipe
+	}
+	return len(p), nil
+}
+
+func TestWriteTo_Errors(t *testing.T) {
+	m := counting(4*outputBuffer/8, 2, true)
+	var w failingWriter
+	n, err := m.WriteTo(&w)
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+	assert.Equal(t, 2, w.writes)
+	assert.Greater(t, n, int64(0))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = m.WriteToContext(canceled, io.Discard)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(0), n)
+}
+
+// BenchmarkWriteTo times streaming a matrix out as CSV, which String used
+// to build by repeated concatenation. The 10000x10000 case needs about
+// 800MB and is skipped with -short.
+func BenchmarkWriteTo(b *testing.B) {
+	for _, side := range []int{1000, 4000, 10000} {
+		if side == 10000 && testing.Short() {
+			continue
+		}
+		m := counting(side, side, true)
+		b.Run(fmt.Sprintf("%dx%d", side, side), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.WriteTo(io.Discard)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package matrixoperations
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Workers is how many goroutines an operation on a large matrix spreads its
+// rows over; PARALLEL_WORKERS overrides the default of one per CPU.
+var Workers = runtime.GOMAXPROCS(0)
+
+// ParallelThreshold is the number of cells below which operations stay on
+// a single goroutine, because starting and joining workers costs more than
+// they save. The benchmarks in parallel_test.go time both paths across the
+// crossover, and are the way to tune this for a given machine.
+var ParallelThreshold = 1 << 16
+
+// shardCount is how many shards an operation over cells cells, split
+// between lanes lanes, is divided into. One shard means it runs serially.
+func shardCount(lanes, cells int) int {
+	if Workers < 2 || cells < ParallelThreshold {
+		return 1
+	}
+
+	return min(Workers, lanes)
+}
+
+// runShards splits [0, n) into shards contiguous, nearly equal ranges and
+// runs fn on each in its own goroutine, with its own canceller on ctx. It
+// returns the error of the first failing shard in order. Since each shard
+// works through its range in order, that is the error a serial loop would
+// have met first, when the shards fail the same way.
+func runShards(ctx context.Context, n, shards int, fn func(shard int, c *canceller, lo, hi int) error) error {
+	errs := make([]error, shards)
+	var wg sync.WaitGroup
+	for k := 0; k < shards; k++ {
+		lo, hi := k*n/shards, (k+1)*n/shards
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[k] = fn(k, newCanceller(ctx), lo, hi)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forRanges runs fn over [0, n) on c when an operation over cells cells is
+// too small to split, and otherwise over shards of it in parallel.
+func forRanges(c *canceller, n, cells int, fn func(c *canceller, lo, hi int) error) error {
+	shards := shardCount(n, cells)
+	if shards == 1 {
+		return fn(c, 0, n)
+	}
+
+	return runShards(c.ctx, n, shards, func(_ int, c *canceller, lo, hi int) error {
+		return fn(c, lo, hi)
+	})
+}
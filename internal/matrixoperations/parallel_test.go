@@ -0,0 +1,141 @@
+package matrixoperations
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setParallelism sets Workers and ParallelThreshold for the rest of the test.
+func setParallelism(tb testing.TB, workers, threshold int) {
+	savedWorkers, savedThreshold := Workers, ParallelThreshold
+	tb.Cleanup(func() { Workers, ParallelThreshold = savedWorkers, savedThreshold })
+	Workers, ParallelThreshold = workers, threshold
+}
+
+// result is the outcome of an operation, comparable between runs.
+type result struct {
+	value any
+	err   string
+}
+
+func outcome(value any, err error) result {
+	if err != nil {
+		return result{err: err.Error()}
+	}
+	return result{value: value}
+}
+
+func operationResults(m NumericMatrix) map[string]result {
+	results := map[string]result{}
+	results["sum"] = outcome(m.Sum())
+	results["multiply"] = outcome(m.Multiply())
+	for _, axis := range []Axis{AxisAll, AxisRow, AxisCol} {
+		results["sum "+axis.String()] = outcome(m.SumAxis(axis))
+		results["multiply "+axis.String()] = outcome(m.MultiplyAxis(axis))
+		results["min "+axis.String()] = outcome(m.Min(axis))
+		results["max "+axis.String()] = outcome(m.Max(axis))
+		results["mean "+axis.String()] = outcome(m.Mean(axis))
+
+		nullable := NewNullableMatrix(m, NullSkip, "")
+		nullable.Null[0][0] = true
+		results["nullable count "+axis.String()] = outcome(nullable.Count(axis))
+		results["nullable sum "+axis.String()] = outcome(nullable.SumAxis(axis))
+	}
+	results["flatten"] = result{value: m.Flatten()}
+
+	inverted := NumericMatrix{}
+	for _, row := range m {
+		inverted = append(inverted, append([]int(nil), row...))
+	}
+	inverted.Invert()
+	results["invert"] = result{value: inverted}
+
+	return results
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	matrices := map[string]NumericMatrix{
+		// Serially, the sum overflows on the second row and comes back.
+		"overflow and back": {{math.MaxInt64}, {1}, {-1}},
+		// Each run's sum overflows on its own but the whole never does.
+		"runs overflow": {{-math.MaxInt64}, {math.MaxInt64}, {math.MaxInt64}, {-math.MaxInt64}},
+		// The product passes through zero before it would overflow.
+		"zero first": {{0}, {math.MaxInt64}, {2}},
+		"zero last":  {{3037000500}, {3037000500}, {0}},
+		"min int":    {{math.MinInt64}, {1}, {1}},
+	}
+	for _, shape := range [][2]int{{1, 1}, {1, 40}, {40, 1}, {7, 9}, {33, 5}} {
+		for _, scale := range []int{3, math.MaxInt64 / 8} {
+			m := make(NumericMatrix, shape[0])
+			for i := range m {
+				m[i] = make([]int, shape[1])
+				for j := range m[i] {
+					m[i][j] = random.Intn(2*scale+1) - scale
+				}
+			}
+			matrices[fmt.Sprintf("%dx%d up to %d", shape[0], shape[1], scale)] = m
+		}
+	}
+
+	for name, m := range matrices {
+		setParallelism(t, 1, 0)
+		serial := operationResults(m)
+		for _, workers := range []int{2, 3, 8} {
+			setParallelism(t, workers, 0)
+			assert.Equal(t, serial, operationResults(m), "%s with %d workers", name, workers)
+		}
+	}
+}
+
+func TestShardCount(t *testing.T) {
+	setParallelism(t, 4, 100)
+	assert.Equal(t, 1, shardCount(10, 99))
+	assert.Equal(t, 4, shardCount(10, 100))
+	assert.Equal(t, 2, shardCount(2, 1000))
+
+	setParallelism(t, 1, 0)
+	assert.Equal(t, 1, shardCount(10, 1000))
+}
+
+// BenchmarkParallel times each parallel operation serially and spread over
+// every CPU at sizes around ParallelThreshold. The crossover is the
+// smallest size at which the parallel run is faster.
+func BenchmarkParallel(b *testing.B) {
+	operations := map[string]func(m NumericMatrix){
+		"sum": func(m NumericMatrix) { m.Sum() },
+		"multiply": func(m NumericMatrix) {
+			m.MultiplyAxis(AxisCol)
+		},
+		"flatten": func(m NumericMatrix) { m.Flatten() },
+		"invert":  func(m NumericMatrix) { m.Invert() },
+	}
+	for name, operation := range operations {
+		for _, side := range []int{64, 128, 256, 512, 1024} {
+			m := make(NumericMatrix, side)
+			for i := range m {
+				m[i] = make([]int, side)
+				for j := range m[i] {
+					m[i][j] = (i+j)%3 - 1
+				}
+			}
+			for _, mode := range []string{"serial", "parallel"} {
+				b.Run(fmt.Sprintf("%s/%dx%d/%s", name, side, side, mode), func(b *testing.B) {
+					if mode == "serial" {
+						setParallelism(b, 1, 0)
+					} else {
+						setParallelism(b, runtime.GOMAXPROCS(0), 0)
+					}
+					for i := 0; i < b.N; i++ {
+						operation(m)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,681 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrDuplicateEntry = errors.New("duplicate entry")
+var ErrEntryOutOfRange = errors.New("entry out of range")
+
+// Entry is one cell of a matrix in coordinate (COO) form, indexed from 0.
+type Entry struct {
+	Row, Col, Value int
+}
+
+// SparseMatrix is an integer matrix in compressed sparse row (CSR) form. The
+// nonzero values of row i are Values[RowPtr[i]:RowPtr[i+1]], at the columns
+// in ColIdx, which increase within each row. Zeros are never stored, so
+// Sum, Multiply, Invert and the other structural operations cost O(nnz)
+// rather than O(rows*cols).
+type SparseMatrix struct {
+	Rows, Cols int
+	RowPtr     []int
+	ColIdx     []int
+	Values     []int
+}
+
+// NewSparseMatrix builds a rows x cols matrix from entries in any order.
+// Zero entries are dropped; entries outside the matrix and repeated cells
+// are errors, as is a shape CheckShape refuses.
+func NewSparseMatrix(rows, cols int, entries []Entry) (*SparseMatrix, error) {
+	if err := CheckShape(rows, cols); err != nil {
+		return nil, err
+	}
+	s := &SparseMatrix{Rows: rows, Cols: cols, RowPtr: make([]int, rows+1)}
+	for _, e := range entries {
+		if e.Row < 0 || e.Row >= rows || e.Col < 0 || e.Col >= cols {
+			return nil, fmt.Errorf("%w: row %d col %d is outside the %s matrix", ErrEntryOutOfRange, e.Row+1, e.Col+1, shapeString(rows, cols))
+		}
+		if e.Value != 0 {
+			s.RowPtr[e.Row+1]++
+		}
+	}
+	for i := 0; i < rows; i++ {
+		s.RowPtr[i+1] += s.RowPtr[i]
+	}
+
+	// Place entries with a counting sort by row, then order each row by
+	// column.
+	nnz := s.RowPtr[rows]
+	s.ColIdx = make([]int, nnz)
+	s.Values = make([]int, nnz)
+	next := slices.Clone(s.RowPtr[:rows])
+	for _, e := range entries {
+		if e.Value == 0 {
+			continue
+		}
+		s.ColIdx[next[e.Row]] = e.Col
+		s.Values[next[e.Row]] = e.Value
+		next[e.Row]++
+	}
+	for i := 0; i < rows; i++ {
+		start, end := s.RowPtr[i], s.RowPtr[i+1]
+		if err := s.sortRow(i, start, end); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *SparseMatrix) sortRow(row, start, end int) error {
+	cols, values := s.ColIdx[start:end], s.Values[start:end]
+	if !slices.IsSorted(cols) {
+		order := make([]int, len(cols))
+		for k := range order {
+			order[k] = k
+		}
+		slices.SortFunc(order, func(a, b int) int { return cols[a] - cols[b] })
+
+		sortedCols := make([]int, len(cols))
+		sortedValues := make([]int, len(values))
+		for k, o := range order {
+			sortedCols[k], sortedValues[k] = cols[o], values[o]
+		}
+		copy(cols, sortedCols)
+		copy(values, sortedValues)
+	}
+
+	for k := 1; k < len(cols); k++ {
+		if cols[k] == cols[k-1] {
+			return fmt.Errorf("%w at row %d col %d", ErrDuplicateEntry, row+1, cols[k]+1)
+		}
+	}
+
+	return nil
+}
+
+// SparseFromDense stores the nonzero cells of m.
+func SparseFromDense(m NumericMatrix) *SparseMatrix {
+	rows, cols := m.Shape()
+	s := &SparseMatrix{Rows: rows, Cols: cols, RowPtr: make([]int, rows+1)}
+	for i, row := range m {
+		for j, val := range row {
+			if val != 0 {
+				s.ColIdx = append(s.ColIdx, j)
+				s.Values = append(s.Values, val)
+			}
+		}
+		s.RowPtr[i+1] = len(s.Values)
+	}
+
+	return s
+}
+
+// Density returns the share of cells in m that are nonzero.
+func (m *NumericMatrix) Density() float64 {
+	rows, cols := m.Shape()
+	if rows == 0 || cols == 0 {
+		return 1
+	}
+
+	nnz := 0
+	for _, row := range *m {
+		for _, val := range row {
+			if val != 0 {
+				nnz++
+			}
+		}
+	}
+
+	return float64(nnz) / float64(rows*cols)
+}
+
+// NNZ returns the number of stored, nonzero cells.
+func (s *SparseMatrix) NNZ() int {
+	return len(s.Values)
+}
+
+// Density returns the share of cells in s that are nonzero.
+func (s *SparseMatrix) Density() float64 {
+	if s.Rows == 0 || s.Cols == 0 {
+		return 1
+	}
+	return float64(s.NNZ()) / float64(s.Rows*s.Cols)
+}
+
+// Entries returns the nonzero cells in row-major order.
+func (s *SparseMatrix) Entries() []Entry {
+	entries := make([]Entry, 0, s.NNZ())
+	for i := 0; i < s.Rows; i++ {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			entries = append(entries, Entry{Row: i, Col: s.ColIdx[k], Value: s.Values[k]})
+		}
+	}
+
+	return entries
+}
+
+// Dense expands s into a NumericMatrix.
+func (s *SparseMatrix) Dense() NumericMatrix {
+	m, _ := s.dense(newCanceller(context.Background()))
+	return m
+}
+
+func (s *SparseMatrix) dense(c *canceller) (NumericMatrix, error) {
+	m := NewNumericMatrix(s.Rows, s.Cols)
+	for i := range m {
+		if err := c.step(s.Cols); err != nil {
+			return nil, err
+		}
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			m[i][s.ColIdx[k]] = s.Values[k]
+		}
+	}
+
+	return m, nil
+}
+
+// remap moves every entry (i, j) to f(i, j) in a rows x cols matrix.
+func (s *SparseMatrix) remap(ctx context.Context, rows, cols int, f func(i, j int) (int, int)) error {
+	c := newCanceller(ctx)
+	entries := s.Entries()
+	for k, e := range entries {
+		if err := c.step(1); err != nil {
+			return err
+		}
+		entries[k].Row, entries[k].Col = f(e.Row, e.Col)
+	}
+
+	// A bijection cannot produce duplicates or leave the matrix.
+	remapped, _ := NewSparseMatrix(rows, cols, entries)
+	*s = *remapped
+	return nil
+}
+
+func (s *SparseMatrix) Shape() (int, int) {
+	if s.Rows == 0 {
+		return 0, 0
+	}
+
+	return s.Rows, s.Cols
+}
+
+func (s *SparseMatrix) Cells() [][]string {
+	cells, _ := s.CellsContext(context.Background())
+	return cells
+}
+
+func (s *SparseMatrix) CellsContext(ctx context.Context) ([][]string, error) {
+	c := newCanceller(ctx)
+	cells := make([][]string, s.Rows)
+	for i := range cells {
+		if err := c.step(s.Cols); err != nil {
+			return nil, err
+		}
+		cells[i] = make([]string, s.Cols)
+		for j := range cells[i] {
+			cells[i][j] = "0"
+		}
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			cells[i][s.ColIdx[k]] = strconv.Itoa(s.Values[k])
+		}
+	}
+
+	return cells, nil
+}
+
+// String returns the matrix as CSV, as WriteTo writes it.
+func (s *SparseMatrix) String() string {
+	return writeString(s.WriteTo)
+}
+
+// WriteTo writes the matrix to w as CSV, one line per row, zeros included.
+func (s *SparseMatrix) WriteTo(w io.Writer) (int64, error) {
+	return s.WriteToContext(context.Background(), w)
+}
+
+func (s *SparseMatrix) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	return writeCSV(newCanceller(ctx), w, s.Rows, s.Cols, func(dst []byte, i int) []byte {
+		k := s.RowPtr[i]
+		for j := 0; j < s.Cols; j++ {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			if k < s.RowPtr[i+1] && s.ColIdx[k] == j {
+				dst = strconv.AppendInt(dst, int64(s.Values[k]), 10)
+				k++
+			} else {
+				dst = append(dst, '0')
+			}
+		}
+		return dst
+	})
+}
+
+func (s *SparseMatrix) Flatten() string {
+	flat, _ := s.FlattenContext(context.Background())
+	return flat
+}
+
+// FlattenContext writes runs of zeros without visiting them one by one, so
+// its cost is O(nnz) plus the length of the output.
+func (s *SparseMatrix) FlattenContext(ctx context.Context) (string, error) {
+	c := newCanceller(ctx)
+	var output strings.Builder
+	zeros := func(n int) {
+		for ; n > 0; n-- {
+			if output.Len() > 0 {
+				output.WriteString(",0")
+			} else {
+				output.WriteString("0")
+			}
+		}
+	}
+
+	for i := 0; i < s.Rows; i++ {
+		if err := c.step(s.Cols); err != nil {
+			return "", err
+		}
+		j := 0
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			zeros(s.ColIdx[k] - j)
+			if output.Len() > 0 {
+				output.WriteString(",")
+			}
+			output.WriteString(strconv.Itoa(s.Values[k]))
+			j = s.ColIdx[k] + 1
+		}
+		zeros(s.Cols - j)
+	}
+
+	return output.String(), nil
+}
+
+func (s *SparseMatrix) Invert() {
+	s.InvertContext(context.Background())
+}
+
+// InvertContext transposes s with a counting sort over columns in
+// O(nnz + cols). Walking rows in order leaves each new row's columns
+// increasing.
+func (s *SparseMatrix) InvertContext(ctx context.Context) error {
+	c := newCanceller(ctx)
+	t := &SparseMatrix{Rows: s.Cols, Cols: s.Rows, RowPtr: make([]int, s.Cols+1)}
+	for _, j := range s.ColIdx {
+		t.RowPtr[j+1]++
+	}
+	for j := 0; j < s.Cols; j++ {
+		t.RowPtr[j+1] += t.RowPtr[j]
+	}
+
+	t.ColIdx = make([]int, s.NNZ())
+	t.Values = make([]int, s.NNZ())
+	next := slices.Clone(t.RowPtr[:s.Cols])
+	for i := 0; i < s.Rows; i++ {
+		if err := c.step(1 + s.RowPtr[i+1] - s.RowPtr[i]); err != nil {
+			return err
+		}
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			j := s.ColIdx[k]
+			t.ColIdx[next[j]] = i
+			t.Values[next[j]] = s.Values[k]
+			next[j]++
+		}
+	}
+
+	*s = *t
+	return nil
+}
+
+func (s *SparseMatrix) Sum() (int64, error) {
+	return s.SumContext(context.Background())
+}
+
+func (s *SparseMatrix) SumContext(ctx context.Context) (int64, error) {
+	sums, err := s.SumAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return sums[0], nil
+}
+
+func (s *SparseMatrix) Multiply() (int64, error) {
+	return s.MultiplyContext(context.Background())
+}
+
+func (s *SparseMatrix) MultiplyContext(ctx context.Context) (int64, error) {
+	products, err := s.MultiplyAxisContext(ctx, AxisAll)
+	if err != nil {
+		return 0, err
+	}
+	return products[0], nil
+}
+
+// laneSize is the number of cells in each lane selected by axis.
+func (s *SparseMatrix) laneSize(axis Axis) int {
+	rows, cols := s.Shape()
+	switch axis {
+	case AxisRow:
+		return cols
+	case AxisCol:
+		return rows
+	}
+	return rows * cols
+}
+
+// reduceSparse folds the stored values of every lane like reduce does for a
+// dense matrix, visiting them in the same row-major order. Lanes listed in
+// skip are left at init.
+func (s *SparseMatrix) reduceSparse(c *canceller, axis Axis, init int64, skip []bool, fn func(acc, val int64) (int64, error)) ([]int64, error) {
+	rows, cols := s.Shape()
+	out, err := countCells(axis, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i] = init
+	}
+
+	for i := 0; i < s.Rows; i++ {
+		if err := c.step(1 + s.RowPtr[i+1] - s.RowPtr[i]); err != nil {
+			return nil, err
+		}
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			lane := 0
+			switch axis {
+			case AxisRow:
+				lane = i
+			case AxisCol:
+				lane = s.ColIdx[k]
+			}
+			if skip != nil && skip[lane] {
+				continue
+			}
+
+			x, err := fn(out[lane], int64(s.Values[k]))
+			if err != nil {
+				return nil, laneError(axis, lane, err)
+			}
+			out[lane] = x
+		}
+	}
+
+	return out, nil
+}
+
+// implicitZeros reports, for each lane selected by axis, whether it holds a
+// zero that is not stored.
+func (s *SparseMatrix) implicitZeros(c *canceller, axis Axis) ([]bool, error) {
+	rows, cols := s.Shape()
+	counts, err := countCells(axis, rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	for i := range counts {
+		counts[i] = 0
+	}
+	for i := 0; i < s.Rows; i++ {
+		if err := c.step(1 + s.RowPtr[i+1] - s.RowPtr[i]); err != nil {
+			return nil, err
+		}
+		switch axis {
+		case AxisAll:
+			counts[0] += int64(s.RowPtr[i+1] - s.RowPtr[i])
+		case AxisRow:
+			counts[i] = int64(s.RowPtr[i+1] - s.RowPtr[i])
+		case AxisCol:
+			for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+				counts[s.ColIdx[k]]++
+			}
+		}
+	}
+
+	zeros := make([]bool, len(counts))
+	size := int64(s.laneSize(axis))
+	for i, count := range counts {
+		zeros[i] = count < size
+	}
+
+	return zeros, nil
+}
+
+func (s *SparseMatrix) SumAxis(axis Axis) ([]int64, error) {
+	return s.SumAxisContext(context.Background(), axis)
+}
+
+func (s *SparseMatrix) SumAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return s.reduceSparse(newCanceller(ctx), axis, 0, nil, safeAdd)
+}
+
+func (s *SparseMatrix) MultiplyAxis(axis Axis) ([]int64, error) {
+	return s.MultiplyAxisContext(context.Background(), axis)
+}
+
+// MultiplyAxisContext short-circuits every lane holding a zero to 0 without
+// multiplying its stored values.
+func (s *SparseMatrix) MultiplyAxisContext(ctx context.Context, axis Axis) ([]int64, error) {
+	c := newCanceller(ctx)
+	zeros, err := s.implicitZeros(c, axis)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.reduceSparse(c, axis, 1, zeros, safeMultiply)
+	if err != nil {
+		return nil, err
+	}
+	for i, zero := range zeros {
+		if zero {
+			products[i] = 0
+		}
+	}
+
+	return products, nil
+}
+
+// extreme folds the stored values with pick, then lets each lane holding a
+// zero compare against it.
+func (s *SparseMatrix) extreme(ctx context.Context, axis Axis, init int64, pick func(a, b int64) int64) ([]int64, error) {
+	if rows, cols := s.Shape(); rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	c := newCanceller(ctx)
+	out, err := s.reduceSparse(c, axis, init, nil, func(acc, val int64) (int64, error) {
+		return pick(acc, val), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	zeros, err := s.implicitZeros(c, axis)
+	if err != nil {
+		return nil, err
+	}
+	for i, zero := range zeros {
+		if zero {
+			out[i] = pick(out[i], 0)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *SparseMatrix) Min(axis Axis) ([]int64, error) {
+	return s.MinContext(context.Background(), axis)
+}
+
+func (s *SparseMatrix) MinContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return s.extreme(ctx, axis, math.MaxInt64, func(a, b int64) int64 { return min(a, b) })
+}
+
+func (s *SparseMatrix) Max(axis Axis) ([]int64, error) {
+	return s.MaxContext(context.Background(), axis)
+}
+
+func (s *SparseMatrix) MaxContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return s.extreme(ctx, axis, math.MinInt64, func(a, b int64) int64 { return max(a, b) })
+}
+
+func (s *SparseMatrix) Count(axis Axis) ([]int64, error) {
+	rows, cols := s.Shape()
+	return countCells(axis, rows, cols)
+}
+
+func (s *SparseMatrix) CountContext(ctx context.Context, axis Axis) ([]int64, error) {
+	return s.Count(axis)
+}
+
+func (s *SparseMatrix) Mean(axis Axis) ([]float64, error) {
+	return s.MeanContext(context.Background(), axis)
+}
+
+func (s *SparseMatrix) MeanContext(ctx context.Context, axis Axis) ([]float64, error) {
+	if rows, cols := s.Shape(); rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	sums, err := s.SumAxisContext(ctx, axis)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.Count(axis)
+	if err != nil {
+		return nil, err
+	}
+
+	return means(sums, counts), nil
+}
+
+func (s *SparseMatrix) Stats(axis Axis, opts StatsOptions) ([]Stats, error) {
+	return s.StatsContext(context.Background(), axis, opts)
+}
+
+// StatsContext needs every value of a lane for its median, mode and
+// percentiles, so it works on the dense matrix.
+func (s *SparseMatrix) StatsContext(ctx context.Context, axis Axis, opts StatsOptions) ([]Stats, error) {
+	dense, err := s.dense(newCanceller(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return dense.StatsContext(ctx, axis, opts)
+}
+
+func (s *SparseMatrix) Rotate(degrees int) error {
+	return s.RotateContext(context.Background(), degrees)
+}
+
+func (s *SparseMatrix) RotateContext(ctx context.Context, degrees int) error {
+	if degrees%90 != 0 {
+		return fmt.Errorf("%w: %d degrees is not a multiple of 90", ErrInvalidRotation, degrees)
+	}
+
+	rows, cols := s.Rows, s.Cols
+	switch ((degrees % 360) + 360) % 360 {
+	case 90:
+		return s.remap(ctx, cols, rows, func(i, j int) (int, int) { return j, rows - 1 - i })
+	case 180:
+		return s.remap(ctx, rows, cols, func(i, j int) (int, int) { return rows - 1 - i, cols - 1 - j })
+	case 270:
+		return s.remap(ctx, cols, rows, func(i, j int) (int, int) { return cols - 1 - j, i })
+	}
+
+	return nil
+}
+
+func (s *SparseMatrix) FlipHorizontal() {
+	s.FlipHorizontalContext(context.Background())
+}
+
+func (s *SparseMatrix) FlipHorizontalContext(ctx context.Context) error {
+	return s.remap(ctx, s.Rows, s.Cols, func(i, j int) (int, int) { return i, s.Cols - 1 - j })
+}
+
+func (s *SparseMatrix) FlipVertical() {
+	s.FlipVerticalContext(context.Background())
+}
+
+func (s *SparseMatrix) FlipVerticalContext(ctx context.Context) error {
+	return s.remap(ctx, s.Rows, s.Cols, func(i, j int) (int, int) { return s.Rows - 1 - i, j })
+}
+
+func (s *SparseMatrix) AntiTranspose() {
+	s.AntiTransposeContext(context.Background())
+}
+
+func (s *SparseMatrix) AntiTransposeContext(ctx context.Context) error {
+	rows, cols := s.Rows, s.Cols
+	return s.remap(ctx, cols, rows, func(i, j int) (int, int) { return cols - 1 - j, rows - 1 - i })
+}
+
+func (s *SparseMatrix) Reshape(rows, cols int) error {
+	return s.ReshapeContext(context.Background(), rows, cols)
+}
+
+func (s *SparseMatrix) ReshapeContext(ctx context.Context, rows, cols int) error {
+	count := s.Rows * s.Cols
+	if !holds(rows, cols, count) {
+		return fmt.Errorf("%w: cannot reshape %d elements into %s", ErrInvalidShape, count, shapeString(rows, cols))
+	}
+
+	oldCols := s.Cols
+	return s.remap(ctx, rows, cols, func(i, j int) (int, int) {
+		k := i*oldCols + j
+		return k / cols, k % cols
+	})
+}
+
+// Select keeps the given rows and columns, in the given order. Indices must
+// be in range; a nil list keeps every row or column. Indices may repeat, up
+// to what CheckShape allows.
+func (s *SparseMatrix) Select(rows, cols []int) error {
+	if err := checkSelection(s.Rows, s.Cols, rows, cols); err != nil {
+		return err
+	}
+	if rows == nil {
+		rows = make([]int, s.Rows)
+		for i := range rows {
+			rows[i] = i
+		}
+	}
+
+	// targets lists the new positions of each old column, which may be
+	// selected more than once.
+	var targets [][]int
+	newCols := s.Cols
+	if cols != nil {
+		targets = make([][]int, s.Cols)
+		for newJ, j := range cols {
+			targets[j] = append(targets[j], newJ)
+		}
+		newCols = len(cols)
+	}
+
+	var entries []Entry
+	for newI, i := range rows {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			j := s.ColIdx[k]
+			if targets == nil {
+				entries = append(entries, Entry{Row: newI, Col: j, Value: s.Values[k]})
+				continue
+			}
+			for _, newJ := range targets[j] {
+				entries = append(entries, Entry{Row: newI, Col: newJ, Value: s.Values[k]})
+			}
+		}
+	}
+
+	selected, err := NewSparseMatrix(len(rows), newCols, entries)
+	if err != nil {
+		return err
+	}
+	*s = *selected
+	return nil
+}
@@ -0,0 +1,170 @@
+package matrixoperations
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSparseMatrix(t *testing.T) {
+	s, err := NewSparseMatrix(2, 3, []Entry{{1, 2, 6}, {0, 1, 2}, {1, 0, 4}, {0, 0, 0}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 3}, s.RowPtr)
+	assert.Equal(t, []int{1, 0, 2}, s.ColIdx)
+	assert.Equal(t, []int{2, 4, 6}, s.Values)
+	assert.Equal(t, 3, s.NNZ())
+	assert.Equal(t, NumericMatrix{{0, 2, 0}, {4, 0, 6}}, s.Dense())
+	assert.Equal(t, s, SparseFromDense(s.Dense()))
+
+	_, err = NewSparseMatrix(2, 2, []Entry{{0, 1, 1}, {0, 1, 2}})
+	assert.ErrorIs(t, err, ErrDuplicateEntry)
+	assert.EqualError(t, err, "duplicate entry at row 1 col 2")
+
+	_, err = NewSparseMatrix(2, 2, []Entry{{2, 0, 1}})
+	assert.ErrorIs(t, err, ErrEntryOutOfRange)
+	assert.EqualError(t, err, "entry out of range: row 3 col 1 is outside the 2x2 matrix")
+
+	_, err = NewSparseMatrix(1<<40, 1<<40, nil)
+	assert.ErrorIs(t, err, ErrTooManyCells)
+}
+
+func TestSparseMatrix_SelectTooLarge(t *testing.T) {
+	defer func(n int) { MaxCells = n }(MaxCells)
+	MaxCells = 8
+
+	s, err := NewSparseMatrix(2, 2, []Entry{{0, 0, 1}, {1, 1, 2}})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.Select([]int{0, 0, 0}, []int{1, 1, 0}), ErrTooManyCells)
+	assert.Equal(t, NumericMatrix{{1, 0}, {0, 2}}, s.Dense())
+}
+
+func TestSparseMatrix_Density(t *testing.T) {
+	dense := NumericMatrix{{0, 1}, {0, 0}}
+	assert.Equal(t, 0.25, dense.Density())
+	assert.Equal(t, 0.25, SparseFromDense(dense).Density())
+}
+
+func TestSparseMatrix_Output(t *testing.T) {
+	s := SparseFromDense(NumericMatrix{{0, 2, 0}, {0, 0, 0}, {4, 0, 0}})
+	assert.Equal(t, "0,2,0\n0,0,0\n4,0,0\n", s.String())
+	assert.Equal(t, "0,2,0,0,0,0,4,0,0", s.Flatten())
+	assert.Equal(t, [][]string{{"0", "2", "0"}, {"0", "0", "0"}, {"4", "0", "0"}}, s.Cells())
+}
+
+// sparseCases are matrices on which a SparseMatrix must agree with the
+// NumericMatrix it was built from.
+var sparseCases = map[string]NumericMatrix{
+	"Mostly zero":     {{0, 0, 3}, {0, 0, 0}, {-2, 0, 0}, {0, 5, 0}},
+	"No zeros":        {{1, 2}, {3, 4}},
+	"All zero":        {{0, 0, 0}, {0, 0, 0}},
+	"Negative values": {{0, -7}, {-1, 0}, {0, -3}},
+	"Single row":      {{0, 9, 0, 0, 1}},
+}
+
+func TestSparseMatrix_AggregatesMatchDense(t *testing.T) {
+	for name, dense := range sparseCases {
+		t.Run(name, func(t *testing.T) {
+			s := SparseFromDense(dense)
+			check := func(expected, expectedErr, actual, actualErr any) {
+				t.Helper()
+				assert.Equal(t, expected, actual)
+				assert.Equal(t, expectedErr, actualErr)
+			}
+
+			sum, err := dense.Sum()
+			sparseSum, sparseErr := s.Sum()
+			check(sum, err, sparseSum, sparseErr)
+			product, err := dense.Multiply()
+			sparseProduct, sparseErr := s.Multiply()
+			check(product, err, sparseProduct, sparseErr)
+
+			for _, axis := range []Axis{AxisAll, AxisRow, AxisCol} {
+				ints, err := dense.SumAxis(axis)
+				sparseInts, sparseErr := s.SumAxis(axis)
+				check(ints, err, sparseInts, sparseErr)
+				ints, err = dense.MultiplyAxis(axis)
+				sparseInts, sparseErr = s.MultiplyAxis(axis)
+				check(ints, err, sparseInts, sparseErr)
+				ints, err = dense.Min(axis)
+				sparseInts, sparseErr = s.Min(axis)
+				check(ints, err, sparseInts, sparseErr)
+				ints, err = dense.Max(axis)
+				sparseInts, sparseErr = s.Max(axis)
+				check(ints, err, sparseInts, sparseErr)
+				ints, err = dense.Count(axis)
+				sparseInts, sparseErr = s.Count(axis)
+				check(ints, err, sparseInts, sparseErr)
+				floats, err := dense.Mean(axis)
+				sparseFloats, sparseErr := s.Mean(axis)
+				check(floats, err, sparseFloats, sparseErr)
+				stats, err := dense.Stats(axis, StatsOptions{})
+				sparseStats, sparseErr := s.Stats(axis, StatsOptions{})
+				check(stats, err, sparseStats, sparseErr)
+			}
+		})
+	}
+}
+
+func TestSparseMatrix_GeometryMatchesDense(t *testing.T) {
+	type geometry interface {
+		Invert()
+		FlipHorizontal()
+		FlipVertical()
+		AntiTranspose()
+		Rotate(degrees int) error
+		Reshape(rows, cols int) error
+		Select(rows, cols []int) error
+	}
+	transforms := map[string]func(m geometry) error{
+		"Invert":         func(m geometry) error { m.Invert(); return nil },
+		"FlipHorizontal": func(m geometry) error { m.FlipHorizontal(); return nil },
+		"FlipVertical":   func(m geometry) error { m.FlipVertical(); return nil },
+		"AntiTranspose":  func(m geometry) error { m.AntiTranspose(); return nil },
+		"Rotate 90":      func(m geometry) error { return m.Rotate(90) },
+		"Rotate 180":     func(m geometry) error { return m.Rotate(180) },
+		"Rotate -90":     func(m geometry) error { return m.Rotate(-90) },
+		"Rotate 45":      func(m geometry) error { return m.Rotate(45) },
+		"Reshape":        func(m geometry) error { return m.Reshape(1, 0) },
+		"Select":         func(m geometry) error { return m.Select([]int{0, 0}, []int{1, 0, 1}) },
+		"Select all":     func(m geometry) error { return m.Select(nil, nil) },
+	}
+
+	for name, input := range sparseCases {
+		for transform, apply := range transforms {
+			t.Run(name+"/"+transform, func(t *testing.T) {
+				dense := make(NumericMatrix, len(input))
+				for i := range input {
+					dense[i] = append([]int(nil), input[i]...)
+				}
+				s := SparseFromDense(dense)
+
+				err := apply(&dense)
+				assert.Equal(t, err, apply(s))
+				assert.Equal(t, dense, s.Dense())
+			})
+		}
+
+		t.Run(name+"/Reshape to one row", func(t *testing.T) {
+			dense := SparseFromDense(input).Dense()
+			s := SparseFromDense(dense)
+			rows, cols := dense.Shape()
+
+			assert.NoError(t, dense.Reshape(1, rows*cols))
+			assert.NoError(t, s.Reshape(1, rows*cols))
+			assert.Equal(t, dense, s.Dense())
+		})
+	}
+}
+
+func TestSparseMatrix_MultiplyShortCircuits(t *testing.T) {
+	// A dense fold overflows on the first row before reaching the zero.
+	s := SparseFromDense(NumericMatrix{{math.MaxInt64, 2}, {0, 1}})
+	product, err := s.Multiply()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), product)
+
+	products, err := s.MultiplyAxis(AxisRow)
+	assert.ErrorIs(t, err, ErrOverflow)
+	assert.Nil(t, products)
+}
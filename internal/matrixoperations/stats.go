@@ -0,0 +1,286 @@
+package matrixoperations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+var ErrInvalidStatsOption = errors.New("invalid stats option")
+
+var DefaultPercentiles = []float64{25, 50, 75}
+
+const DefaultHistogramBins = 10
+
+// MaxHistogramBins caps Bins, which is also held to the number of cells in
+// a lane, since every lane's bins are allocated and reported whether or not
+// they are empty.
+const MaxHistogramBins = 10000
+
+type StatsOptions struct {
+	// Percentiles lists the percentiles to report, each in [0, 100].
+	Percentiles []float64
+	// Bins is the number of equal-width histogram buckets between the lane
+	// minimum and maximum, at most the cells in a lane or MaxHistogramBins,
+	// whichever is fewer, though DefaultHistogramBins is always allowed.
+	Bins int
+}
+
+type Percentile struct {
+	P     float64 `json:"p"`
+	Value float64 `json:"value"`
+}
+
+// Bucket counts the values in [Lower, Upper). The last bucket of a histogram
+// also includes its upper bound.
+type Bucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int64   `json:"count"`
+}
+
+// Stats describes one lane of a matrix. Variance and StdDev are population
+// statistics.
+type Stats struct {
+	Label       string       `json:"label,omitempty"`
+	Count       int64        `json:"count"`
+	Mean        float64      `json:"mean"`
+	Median      float64      `json:"median"`
+	Mode        int64        `json:"mode"`
+	Variance    float64      `json:"variance"`
+	StdDev      float64      `json:"stddev"`
+	Percentiles []Percentile `json:"percentiles"`
+	Histogram   []Bucket     `json:"histogram"`
+}
+
+// RunningStats accumulates count, mean and variance in a single pass using
+// Welford's algorithm, so values can be fed in as they are parsed without
+// holding the whole matrix or losing precision to a large running sum.
+type RunningStats struct {
+	n    int64
+	mean float64
+	m2   float64
+}
+
+func (s *RunningStats) Add(x float64) {
+	s.n++
+	delta := x - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (x - s.mean)
+}
+
+func (s *RunningStats) Count() int64 {
+	return s.n
+}
+
+func (s *RunningStats) Mean() float64 {
+	return s.mean
+}
+
+func (s *RunningStats) Variance() float64 {
+	if s.n == 0 {
+		return 0
+	}
+
+	return s.m2 / float64(s.n)
+}
+
+func (s *RunningStats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// validate checks the options for lanes of length cells each.
+func (o StatsOptions) validate(length int) error {
+	for _, p := range o.Percentiles {
+		if math.IsNaN(p) || p < 0 || p > 100 {
+			return fmt.Errorf("%w: percentile %v is outside [0, 100]", ErrInvalidStatsOption, p)
+		}
+	}
+	if o.Bins < 1 {
+		return fmt.Errorf("%w: histogram needs at least 1 bin, got %d", ErrInvalidStatsOption, o.Bins)
+	}
+	if limit := max(min(length, MaxHistogramBins), DefaultHistogramBins); o.Bins > limit {
+		return fmt.Errorf("%w: histogram may have at most %d bins, got %d", ErrInvalidStatsOption, limit, o.Bins)
+	}
+
+	return nil
+}
+
+// lanes copies the values of every lane of m selected by axis, leaving out
+// cells marked in null, which may be nil.
+func lanes(c *canceller, m NumericMatrix, null [][]bool, axis Axis) ([][]int64, error) {
+	rows, cols := m.Shape()
+
+	var out [][]int64
+	switch axis {
+	case AxisAll:
+		out = [][]int64{make([]int64, 0, rows*cols)}
+	case AxisRow:
+		out = make([][]int64, rows)
+	case AxisCol:
+		out = make([][]int64, cols)
+	default:
+		return nil, ErrInvalidAxis
+	}
+
+	for i, row := range m {
+		if err := c.step(len(row)); err != nil {
+			return nil, err
+		}
+		for j, val := range row {
+			if null != nil && null[i][j] {
+				continue
+			}
+
+			lane := 0
+			switch axis {
+			case AxisRow:
+				lane = i
+			case AxisCol:
+				lane = j
+			}
+			out[lane] = append(out[lane], int64(val))
+		}
+	}
+
+	return out, nil
+}
+
+func (m *NumericMatrix) Stats(axis Axis, opts StatsOptions) ([]Stats, error) {
+	return m.StatsContext(context.Background(), axis, opts)
+}
+
+func (m *NumericMatrix) StatsContext(ctx context.Context, axis Axis, opts StatsOptions) ([]Stats, error) {
+	rows, cols := m.Shape()
+	if err := opts.validate(laneLength(rows, cols, axis)); err != nil {
+		return nil, err
+	}
+	if rows == 0 || cols == 0 {
+		return nil, ErrEmptyMatrix
+	}
+
+	return describeLanes(newCanceller(ctx), *m, nil, axis, opts)
+}
+
+// laneLength is the number of cells in each lane of a rows x cols matrix.
+func laneLength(rows, cols int, axis Axis) int {
+	switch axis {
+	case AxisRow:
+		return cols
+	case AxisCol:
+		return rows
+	}
+	return rows * cols
+}
+
+func describeLanes(c *canceller, m NumericMatrix, null [][]bool, axis Axis, opts StatsOptions) ([]Stats, error) {
+	laneValues, err := lanes(c, m, null, axis)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]Stats, len(laneValues))
+	for i, lane := range laneValues {
+		if err := c.step(len(lane)); err != nil {
+			return nil, err
+		}
+		if len(lane) == 0 {
+			return nil, laneError(axis, i, ErrAllNull)
+		}
+		stats[i] = describe(lane, opts)
+	}
+
+	return stats, nil
+}
+
+func (a *AlphanumericMatrix) Stats(axis Axis, opts StatsOptions) ([]Stats, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+func (a *AlphanumericMatrix) StatsContext(ctx context.Context, axis Axis, opts StatsOptions) ([]Stats, error) {
+	return nil, ErrUnsupportedOperation
+}
+
+// describe computes the statistics of a non-empty lane. It sorts values in
+// place.
+func describe(values []int64, opts StatsOptions) Stats {
+	var running RunningStats
+	for _, val := range values {
+		running.Add(float64(val))
+	}
+
+	slices.Sort(values)
+
+	percentiles := make([]Percentile, len(opts.Percentiles))
+	for i, p := range opts.Percentiles {
+		percentiles[i] = Percentile{P: p, Value: percentile(values, p)}
+	}
+
+	return Stats{
+		Count:       running.Count(),
+		Mean:        running.Mean(),
+		Median:      percentile(values, 50),
+		Mode:        mode(values),
+		Variance:    running.Variance(),
+		StdDev:      running.StdDev(),
+		Percentiles: percentiles,
+		Histogram:   histogram(values, opts.Bins),
+	}
+}
+
+// percentile linearly interpolates between the closest ranks of sorted.
+func percentile(sorted []int64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	frac := rank - float64(lo)
+
+	return float64(sorted[lo]) + (float64(sorted[hi])-float64(sorted[lo]))*frac
+}
+
+// mode returns the most frequent value of sorted, preferring the smallest
+// value on ties.
+func mode(sorted []int64) int64 {
+	best, bestRun := sorted[0], 0
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+		if j-i > bestRun {
+			best, bestRun = sorted[i], j-i
+		}
+		i = j
+	}
+
+	return best
+}
+
+// histogram spreads sorted over bins equal-width buckets between its minimum
+// and maximum. A lane holding a single distinct value gets a single bucket.
+func histogram(sorted []int64, bins int) []Bucket {
+	lo, hi := float64(sorted[0]), float64(sorted[len(sorted)-1])
+	if lo == hi {
+		return []Bucket{{Lower: lo, Upper: hi, Count: int64(len(sorted))}}
+	}
+
+	width := (hi - lo) / float64(bins)
+	buckets := make([]Bucket, bins)
+	for i := range buckets {
+		buckets[i].Lower = lo + float64(i)*width
+		buckets[i].Upper = lo + float64(i+1)*width
+	}
+	buckets[bins-1].Upper = hi
+
+	for _, val := range sorted {
+		i := int((float64(val) - lo) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		buckets[i].Count++
+	}
+
+	return buckets
+}
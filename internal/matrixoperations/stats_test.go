@@ -0,0 +1,112 @@
+package matrixoperations
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunningStats(t *testing.T) {
+	var s RunningStats
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.Add(x)
+	}
+
+	assert.Equal(t, int64(8), s.Count())
+	assert.Equal(t, 5.0, s.Mean())
+	assert.Equal(t, 4.0, s.Variance())
+	assert.Equal(t, 2.0, s.StdDev())
+}
+
+func TestRunningStats_LargeOffset(t *testing.T) {
+	// A naive sum-of-squares variance loses every significant digit here.
+	var s RunningStats
+	for _, x := range []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16} {
+		s.Add(x)
+	}
+
+	assert.Equal(t, 1e9+10, s.Mean())
+	assert.InDelta(t, 22.5, s.Variance(), 1e-9)
+}
+
+func TestNumericMatrix_Stats(t *testing.T) {
+	matrix := NumericMatrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	opts := StatsOptions{Percentiles: []float64{0, 25, 100}, Bins: 4}
+
+	stats, err := matrix.Stats(AxisAll, opts)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+
+	s := stats[0]
+	assert.Equal(t, int64(9), s.Count)
+	assert.Equal(t, 5.0, s.Mean)
+	assert.Equal(t, 5.0, s.Median)
+	assert.Equal(t, int64(1), s.Mode)
+	assert.InDelta(t, 20.0/3, s.Variance, 1e-12)
+	assert.InDelta(t, math.Sqrt(20.0/3), s.StdDev, 1e-12)
+	assert.Equal(t, []Percentile{{0, 1}, {25, 3}, {100, 9}}, s.Percentiles)
+	assert.Equal(t, []Bucket{
+		{Lower: 1, Upper: 3, Count: 2},
+		{Lower: 3, Upper: 5, Count: 2},
+		{Lower: 5, Upper: 7, Count: 2},
+		{Lower: 7, Upper: 9, Count: 3},
+	}, s.Histogram)
+}
+
+func TestNumericMatrix_StatsAxis(t *testing.T) {
+	matrix := NumericMatrix{{1, 2, 2, 10}, {5, 5, 5, 5}}
+	opts := StatsOptions{Percentiles: []float64{50}, Bins: 3}
+
+	stats, err := matrix.Stats(AxisRow, opts)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+
+	assert.Equal(t, 3.75, stats[0].Mean)
+	assert.Equal(t, 2.0, stats[0].Median)
+	assert.Equal(t, int64(2), stats[0].Mode)
+	assert.Equal(t, []Bucket{{Lower: 5, Upper: 5, Count: 4}}, stats[1].Histogram)
+	assert.Equal(t, 0.0, stats[1].Variance)
+
+	stats, err = matrix.Stats(AxisCol, opts)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 4)
+	assert.Equal(t, 7.5, stats[3].Median)
+}
+
+func TestNumericMatrix_StatsErrors(t *testing.T) {
+	matrix := NumericMatrix{{1, 2}}
+
+	_, err := matrix.Stats(AxisAll, StatsOptions{Percentiles: []float64{101}, Bins: 1})
+	assert.ErrorIs(t, err, ErrInvalidStatsOption)
+
+	_, err = matrix.Stats(AxisAll, StatsOptions{Bins: 0})
+	assert.ErrorIs(t, err, ErrInvalidStatsOption)
+
+	// Bins are held to the cells in a lane, but never below the default.
+	_, err = matrix.Stats(AxisAll, StatsOptions{Bins: DefaultHistogramBins})
+	assert.NoError(t, err)
+	_, err = matrix.Stats(AxisAll, StatsOptions{Bins: DefaultHistogramBins + 1})
+	assert.ErrorIs(t, err, ErrInvalidStatsOption)
+	_, err = matrix.Stats(AxisAll, StatsOptions{Bins: 1 << 40})
+	assert.EqualError(t, err, "invalid stats option: histogram may have at most 10 bins, got 1099511627776")
+
+	tall := make(NumericMatrix, 2000)
+	for i := range tall {
+		tall[i] = []int{i, -i}
+	}
+	_, err = tall.Stats(AxisAll, StatsOptions{Bins: 4000})
+	assert.NoError(t, err)
+	_, err = tall.Stats(AxisRow, StatsOptions{Bins: 4000})
+	assert.EqualError(t, err, "invalid stats option: histogram may have at most 10 bins, got 4000")
+	_, err = tall.Stats(AxisCol, StatsOptions{Bins: 2000})
+	assert.NoError(t, err)
+
+	empty := NumericMatrix{}
+	_, err = empty.Stats(AxisAll, StatsOptions{Bins: 1})
+	assert.ErrorIs(t, err, ErrEmptyMatrix)
+
+	alpha := AlphanumericMatrix{{"a"}}
+	_, err = alpha.Stats(AxisAll, StatsOptions{Bins: 1})
+	assert.ErrorIs(t, err, ErrUnsupportedOperation)
+}
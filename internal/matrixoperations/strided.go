@@ -0,0 +1,169 @@
+package matrixoperations
+
+// blockSize is the side of the square tiles a strided copy works through,
+// small enough that a tile of the source and one of the destination stay in
+// cache together.
+const blockSize = 32
+
+// strided is a rows x cols view of a matrix stored in one slice, with cell
+// (i, j) at data[offset+i*rowStride+j*colStride]. Transposing or flipping a
+// view only changes its strides and offset, so any chain of them costs a
+// single copy when the result is materialized.
+type strided[T any] struct {
+	data                         []T
+	rows, cols                   int
+	offset, rowStride, colStride int
+}
+
+// newRows returns a rows x cols matrix whose rows are consecutive windows of
+// one backing slice.
+func newRows[T any](rows, cols int) [][]T {
+	return rowsOf(make([]T, rows*cols), rows, cols)
+}
+
+// rowsOf splits data into rows windows of cols cells. Each window's capacity
+// runs to the end of data, which is how stridedOf finds the slice again, so
+// a row must never be appended to in place.
+func rowsOf[T any](data []T, rows, cols int) [][]T {
+	m := make([][]T, rows)
+	for i := range m {
+		m[i] = data[i*cols : (i+1)*cols]
+	}
+
+	return m
+}
+
+// NewNumericMatrix returns a zeroed rows x cols matrix backed by a single
+// contiguous slice.
+func NewNumericMatrix(rows, cols int) NumericMatrix {
+	return newRows[int](rows, cols)
+}
+
+// stridedOf views m in row-major order. Rows that already sit back to back in
+// one slice, as those from newRows do, are used in place; any others are
+// packed into a fresh slice first.
+func stridedOf[T any](c *canceller, m [][]T) (strided[T], error) {
+	rows, cols := len(m), 0
+	if rows > 0 {
+		cols = len(m[0])
+	}
+	view := strided[T]{rows: rows, cols: cols, rowStride: cols, colStride: 1}
+	if data, ok := contiguous(m); ok {
+		view.data = data
+		return view, nil
+	}
+
+	view.data = make([]T, rows*cols)
+	for i, row := range m {
+		if err := c.step(cols); err != nil {
+			return strided[T]{}, err
+		}
+		copy(view.data[i*cols:], row)
+	}
+
+	return view, nil
+}
+
+// contiguous returns the slice holding the rows of m when each row starts
+// where the one before it ends.
+func contiguous[T any](m [][]T) ([]T, bool) {
+	if len(m) == 0 || len(m[0]) == 0 {
+		return nil, true
+	}
+
+	cols := len(m[0])
+	data := m[0][:cap(m[0])]
+	if len(data) < len(m)*cols {
+		return nil, false
+	}
+	data = data[:len(m)*cols]
+	for i, row := range m {
+		if len(row) != cols || &row[0] != &data[i*cols] {
+			return nil, false
+		}
+	}
+
+	return data, true
+}
+
+// transposed swaps the view's rows and columns.
+func (s strided[T]) transposed() strided[T] {
+	s.rows, s.cols = s.cols, s.rows
+	s.rowStride, s.colStride = s.colStride, s.rowStride
+	return s
+}
+
+// flippedHorizontal mirrors the view left to right.
+func (s strided[T]) flippedHorizontal() strided[T] {
+	if s.cols > 0 {
+		s.offset += (s.cols - 1) * s.colStride
+	}
+	s.colStride = -s.colStride
+	return s
+}
+
+// flippedVertical mirrors the view top to bottom.
+func (s strided[T]) flippedVertical() strided[T] {
+	if s.rows > 0 {
+		s.offset += (s.rows - 1) * s.rowStride
+	}
+	s.rowStride = -s.rowStride
+	return s
+}
+
+// materialize copies the view into a new matrix laid out like those from
+// newRows.
+func (s strided[T]) materialize(c *canceller) ([][]T, error) {
+	data, err := s.copyOut(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return rowsOf(data, s.rows, s.cols), nil
+}
+
+// copyOut copies the view into a new slice in row-major order. A view whose
+// rows run along the source is copied a row at a time; any other, such as a
+// transpose, is copied in blockSize tiles so that neither side is walked
+// against its layout for long. Bands of tiles are copied in parallel on a
+// large matrix.
+func (s strided[T]) copyOut(c *canceller) ([]T, error) {
+	data := make([]T, s.rows*s.cols)
+	if s.colStride == 1 {
+		for i := 0; i < s.rows; i++ {
+			if err := c.step(s.cols); err != nil {
+				return nil, err
+			}
+			start := s.offset + i*s.rowStride
+			copy(data[i*s.cols:(i+1)*s.cols], s.data[start:start+s.cols])
+		}
+		return data, nil
+	}
+
+	bands := (s.rows + blockSize - 1) / blockSize
+	err := forRanges(c, bands, s.rows*s.cols, func(c *canceller, lo, hi int) error {
+		for band := lo; band < hi; band++ {
+			top, bottom := band*blockSize, min((band+1)*blockSize, s.rows)
+			if err := c.step((bottom - top) * s.cols); err != nil {
+				return err
+			}
+			for left := 0; left < s.cols; left += blockSize {
+				right := min(left+blockSize, s.cols)
+				for i := top; i < bottom; i++ {
+					dst := data[i*s.cols+left : i*s.cols+right]
+					k := s.offset + i*s.rowStride + left*s.colStride
+					for j := range dst {
+						dst[j] = s.data[k]
+						k += s.colStride
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package matrixoperations
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counting returns a rows x cols matrix of distinct values whose rows are
+// allocated one by one, or from one slice when packed is set.
+func counting(rows, cols int, packed bool) NumericMatrix {
+	m := make(NumericMatrix, rows)
+	if packed {
+		m = NewNumericMatrix(rows, cols)
+	}
+	for i := range m {
+		if !packed {
+			m[i] = make([]int, cols)
+		}
+		for j := range m[i] {
+			m[i][j] = i*cols + j
+		}
+	}
+
+	return m
+}
+
+func TestStridedGeometry(t *testing.T) {
+	// Reference versions that index every cell directly.
+	naive := map[string]func(m NumericMatrix, i, j int) int{
+		"transpose":       func(m NumericMatrix, i, j int) int { return m[j][i] },
+		"anti-transpose":  func(m NumericMatrix, i, j int) int { return m[len(m)-1-j][len(m[0])-1-i] },
+		"flip horizontal": func(m NumericMatrix, i, j int) int { return m[i][len(m[0])-1-j] },
+		"flip vertical":   func(m NumericMatrix, i, j int) int { return m[len(m)-1-i][j] },
+		"rotate 90":       func(m NumericMatrix, i, j int) int { return m[len(m)-1-j][i] },
+		"rotate 180":      func(m NumericMatrix, i, j int) int { return m[len(m)-1-i][len(m[0])-1-j] },
+		"rotate 270":      func(m NumericMatrix, i, j int) int { return m[j][len(m[0])-1-i] },
+	}
+	apply := map[string]func(c *canceller, m [][]int) ([][]int, error){
+		"transpose":       transpose[int],
+		"anti-transpose":  antiTranspose[int],
+		"flip horizontal": flipHorizontal[int],
+		"flip vertical":   flipVertical[int],
+		"rotate 90":       func(c *canceller, m [][]int) ([][]int, error) { return rotate(c, m, 90) },
+		"rotate 180":      func(c *canceller, m [][]int) ([][]int, error) { return rotate(c, m, 180) },
+		"rotate 270":      func(c *canceller, m [][]int) ([][]int, error) { return rotate(c, m, 270) },
+	}
+
+	// Shapes on both sides of blockSize, with the tiled copy split between
+	// workers.
+	setParallelism(t, 3, 0)
+	for _, shape := range [][2]int{{1, 1}, {1, 7}, {7, 1}, {3, 5}, {blockSize, blockSize + 1}, {2*blockSize + 3, blockSize - 1}} {
+		for _, packed := range []bool{false, true} {
+			m := counting(shape[0], shape[1], packed)
+			for name, op := range apply {
+				got, err := op(newCanceller(context.Background()), m)
+				assert.NoError(t, err)
+
+				rows, cols := shape[0], shape[1]
+				if name == "transpose" || name == "anti-transpose" || name == "rotate 90" || name == "rotate 270" {
+					rows, cols = cols, rows
+				}
+				want := make(NumericMatrix, rows)
+				for i := range want {
+					want[i] = make([]int, cols)
+					for j := range want[i] {
+						want[i][j] = naive[name](m, i, j)
+					}
+				}
+				assert.Equal(t, want, NumericMatrix(got), "%s of %dx%d, packed %v", name, shape[0], shape[1], packed)
+			}
+		}
+	}
+}
+
+func TestStridedOf(t *testing.T) {
+	packed := counting(3, 4, true)
+	s, err := stridedOf(newCanceller(context.Background()), packed)
+	assert.NoError(t, err)
+	assert.Same(t, &packed[0][0], &s.data[0], "packed rows are used in place")
+	transposed, err := transpose(newCanceller(context.Background()), packed)
+	assert.NoError(t, err)
+	_, ok := contiguous(transposed)
+	assert.True(t, ok, "results are packed")
+
+	loose := counting(3, 4, false)
+	s, err = stridedOf(newCanceller(context.Background()), loose)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, s.data)
+
+	// Rows from one slice but out of order are not contiguous.
+	packed[0], packed[1] = packed[1], packed[0]
+	_, ok = contiguous(packed)
+	assert.False(t, ok)
+}
+
+func TestTranspose_NoAliasing(t *testing.T) {
+	m := counting(2, 3, true)
+	m.Invert()
+	m.Invert()
+	original := counting(2, 3, true)
+	assert.Equal(t, original, m)
+
+	// The result never shares storage with its source.
+	rotated, err := rotate(newCanceller(context.Background()), original, 0)
+	assert.NoError(t, err)
+	rotated[0][0] = 100
+	assert.Equal(t, 0, original[0][0])
+}
+
+// BenchmarkTranspose compares the tiled transpose with walking the source
+// column by column, as Invert used to. The 10000x10000 case needs about
+// 2GB and is skipped with -short.
+func BenchmarkTranspose(b *testing.B) {
+	columnMajor := func(m NumericMatrix) NumericMatrix {
+		out := make(NumericMatrix, len(m[0]))
+		for i := range out {
+			out[i] = make([]int, len(m))
+			for j := range out[i] {
+				out[i][j] = m[j][i]
+			}
+		}
+		return out
+	}
+
+	for _, side := range []int{1000, 4000, 10000} {
+		if side == 10000 && testing.Short() {
+			continue
+		}
+		m := counting(side, side, true)
+		b.Run(fmt.Sprintf("%dx%d/column-major", side, side), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				columnMajor(m)
+			}
+		})
+		b.Run(fmt.Sprintf("%dx%d/tiled", side, side), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				transpose(newCanceller(context.Background()), m)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is one registered series in the Prometheus text format.
+type metric struct {
+	name, help, kind string
+	value            func() float64
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]metric{}
+)
+
+func register(m metric) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registry[m.name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", m.name))
+	}
+	registry[m.name] = m
+}
+
+// Counter is a monotonically increasing count.
+type Counter struct {
+	n atomic.Int64
+}
+
+// NewCounter registers a counter served by Handler.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	register(metric{name: name, help: help, kind: "counter", value: func() float64 {
+		return float64(c.Value())
+	}})
+	return c
+}
+
+func (c *Counter) Inc() {
+	c.n.Add(1)
+}
+
+func (c *Counter) Value() int64 {
+	return c.n.Load()
+}
+
+// NewGaugeFunc registers a gauge whose value is read from value whenever
+// Handler is served.
+func NewGaugeFunc(name, help string, value func() float64) {
+	register(metric{name: name, help: help, kind: "gauge", value: value})
+}
+
+// Handler writes every registered metric in the Prometheus text format,
+// sorted by name.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	metrics := make([]metric, 0, len(registry))
+	for _, m := range registry {
+		metrics = append(metrics, m)
+	}
+	mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			m.name, m.help, m.name, m.kind, m.name, strconv.FormatFloat(m.value(), 'g', -1, 64))
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	saved := registry
+	registry = map[string]metric{}
+	defer func() { registry = saved }()
+
+	hits := NewCounter("test_hits_total", "Hits.")
+	hits.Inc()
+	hits.Inc()
+	NewGaugeFunc("test_bytes", "Bytes held.", func() float64 { return 1.5 })
+
+	rec := httptest.NewRecorder()
+	Handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, int64(2), hits.Value())
+	assert.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP test_bytes Bytes held.
+# TYPE test_bytes gauge
+test_bytes 1.5
+# HELP test_hits_total Hits.
+# TYPE test_hits_total counter
+test_hits_total 2
+`, rec.Body.String())
+
+	assert.Panics(t, func() { NewCounter("test_hits_total", "Again.") })
+}
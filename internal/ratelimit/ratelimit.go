@@ -0,0 +1,139 @@
+// Package ratelimit meters requests per client with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit allows Count requests per Window. A client that has been idle can
+// spend all Count at once; after that they come back evenly across Window.
+type Limit struct {
+	Count  int
+	Window time.Duration
+}
+
+// ParseLimit reads a limit written as count/window, where window is s, m, h
+// or a duration such as 30s: 100/m allows a hundred requests a minute.
+func ParseLimit(s string) (Limit, error) {
+	count, window, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%w: %q (want count/window such as 100/m)", ErrInvalidLimit, s)
+	}
+
+	d, ok := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[window]
+	if !ok {
+		if d, err = time.ParseDuration(window); err != nil || d <= 0 {
+			return Limit{}, fmt.Errorf("%w: %q (want count/window such as 100/m)", ErrInvalidLimit, s)
+		}
+	}
+
+	return Limit{Count: n, Window: d}, nil
+}
+
+// rate is the tokens the limit refills per second.
+func (l Limit) rate() float64 {
+	return float64(l.Count) / l.Window.Seconds()
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed bool
+	// Remaining is the whole tokens left after this request.
+	Remaining int
+	// Reset is how long until the bucket is full again, and RetryAfter,
+	// for a request that was refused, how long until it holds a token.
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps one token bucket per key. Take must check and update a
+// bucket atomically, so that a store shared between servers can stand in
+// for MemoryStore.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// sweepEvery is how many calls to Take pass between sweeps for idle
+// buckets.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// full is when the bucket will have refilled.
+	full time.Time
+}
+
+// MemoryStore is a Store held in a map. A bucket left alone long enough to
+// fill up is the same as no bucket, so such buckets are dropped as it goes.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Count), at: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Count), b.tokens+now.Sub(b.at).Seconds()*limit.rate())
+	b.at = now
+
+	d := Decision{Allowed: b.tokens >= 1}
+	if d.Allowed {
+		b.tokens--
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / limit.rate())
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((float64(limit.Count) - b.tokens) / limit.rate())
+	b.full = now.Add(d.Reset)
+
+	return d, nil
+}
+
+// Len returns the number of buckets held.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// sweep drops the buckets that have refilled.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
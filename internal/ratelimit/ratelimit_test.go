@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	for s, want := range map[string]Limit{
+		"100/m":     {100, time.Minute},
+		"5/s":       {5, time.Second},
+		"1000/h":    {1000, time.Hour},
+		"10/30s":    {10, 30 * time.Second},
+		"3/1h30m":   {3, 90 * time.Minute},
+		"1/250ms":   {1, 250 * time.Millisecond},
+		"12/2h0m0s": {12, 2 * time.Hour},
+	} {
+		limit, err := ParseLimit(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, limit, s)
+	}
+
+	for _, s := range []string{"", "100", "0/m", "-1/m", "x/m", "10/", "10/day", "10/0s", "10/-1m"} {
+		_, err := ParseLimit(s)
+		assert.ErrorIs(t, err, ErrInvalidLimit, s)
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Count: 3, Window: 3 * time.Second}
+	take := func(key string) Decision {
+		d, err := s.Take(context.Background(), key, limit)
+		assert.NoError(t, err)
+		return d
+	}
+
+	// A new client can spend the whole burst at once.
+	for remaining := 2; remaining >= 0; remaining-- {
+		d := take("a")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, remaining, d.Remaining)
+	}
+	d := take("a")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 3*time.Second, d.Reset)
+
+	// Other clients have buckets of their own.
+	assert.True(t, take("b").Allowed)
+
+	// Tokens come back at one a second.
+	now = now.Add(1500 * time.Millisecond)
+	d = take("a")
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 2500*time.Millisecond, d.Reset)
+	d = take("a")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	// An idle bucket refills no further than the limit.
+	now = now.Add(time.Hour)
+	assert.Equal(t, 2, take("a").Remaining)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	short := Limit{Count: 10, Window: time.Second}
+	long := Limit{Count: 10, Window: time.Hour}
+
+	s.Take(context.Background(), "short", short)
+	s.Take(context.Background(), "long", long)
+	now = now.Add(time.Minute)
+	for i := 2; i < sweepEvery; i++ {
+		s.Take(context.Background(), fmt.Sprintf("client %d", i), short)
+	}
+
+	// The sweep ran on the last call, before its own bucket was made, and
+	// kept only the buckets that had not yet refilled.
+	assert.Equal(t, sweepEvery-2, s.Len()-1)
+	_, ok := s.buckets["long"]
+	assert.True(t, ok)
+	_, ok = s.buckets["short"]
+	assert.False(t, ok)
+}
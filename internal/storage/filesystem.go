@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a Store that keeps each entry as a file named by its ID in
+// one directory. A file's modification time records its last use, so TTLs
+// and eviction order survive a restart.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+	lru *lru
+}
+
+// NewFileStore opens dir, creating it if needed, and indexes the entries
+// already in it, evicting any that have expired or do not fit.
+func NewFileStore(dir string, limits Limits) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir, lru: newLRU(limits)}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.Type().IsRegular() || checkID(file.Name()) != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		s.lru.add(file.Name(), info.Size(), info.ModTime())
+	}
+	for _, evicted := range s.lru.evict(0) {
+		os.Remove(s.path(evicted))
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// Put writes data under id through a temporary file, so a reader never
+// sees a partial entry.
+func (s *FileStore) Put(id string, data []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if s.lru.limits.MaxBytes > 0 && size > s.lru.limits.MaxBytes {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lru.remove(id)
+	for _, evicted := range s.lru.evict(size) {
+		os.Remove(s.path(evicted))
+	}
+
+	// Temporary names start with a dot, which IDs cannot, so a crash
+	// mid-write leaves nothing that is indexed on the next start.
+	tmp, err := os.CreateTemp(s.dir, ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path(id)); err != nil {
+		return err
+	}
+	s.lru.add(id, size, s.lru.now())
+
+	return nil
+}
+
+func (s *FileStore) Get(id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lru.touch(id) {
+		if _, ok := s.lru.items[id]; ok {
+			s.lru.remove(id)
+			os.Remove(s.path(id))
+		}
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		s.lru.remove(id)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.lru.now()
+	os.Chtimes(s.path(id), now, now)
+
+	return data, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lru.items[id]; !ok {
+		return ErrNotFound
+	}
+	s.lru.remove(id)
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
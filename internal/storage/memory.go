@@ -0,0 +1,87 @@
+package storage
+
+import "sync"
+
+// MemoryStore is a Store held in a map; entries are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	lru  *lru
+	data map[string][]byte
+}
+
+func NewMemoryStore(limits Limits) *MemoryStore {
+	return &MemoryStore{lru: newLRU(limits), data: make(map[string][]byte)}
+}
+
+// Put stores data under id, evicting older entries to make room. The store
+// keeps data itself, so the caller must not modify it afterwards.
+func (s *MemoryStore) Put(id string, data []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	size := int64(len(data))
+	if s.lru.limits.MaxBytes > 0 && size > s.lru.limits.MaxBytes {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lru.remove(id)
+	for _, evicted := range s.lru.evict(size) {
+		delete(s.data, evicted)
+	}
+	s.data[id] = data
+	s.lru.add(id, size, s.lru.now())
+
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lru.touch(id) {
+		s.lru.remove(id)
+		delete(s.data, id)
+		return nil, ErrNotFound
+	}
+
+	return s.data[id], nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[id]; !ok {
+		return ErrNotFound
+	}
+	s.lru.remove(id)
+	delete(s.data, id)
+
+	return nil
+}
+
+// Limits returns the limits the store was created with.
+func (s *MemoryStore) Limits() Limits {
+	return s.lru.limits
+}
+
+// Size returns the total bytes stored.
+func (s *MemoryStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.size
+}
+
+// Len returns the number of entries stored, including expired ones not
+// yet evicted.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data)
+}
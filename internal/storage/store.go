@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"container/list"
+	"errors"
+	"regexp"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+var ErrInvalidID = errors.New("invalid ID")
+var ErrTooLarge = errors.New("entry exceeds the store size limit")
+
+// Store keeps blobs by ID. Entries expire once they have gone unused for
+// the TTL, and the least recently used entries are evicted to keep the
+// total size within a limit. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	Put(id string, data []byte) error
+	// Get returns the entry stored under id and counts as a use.
+	Get(id string) ([]byte, error)
+	Delete(id string) error
+}
+
+// Limits bound a Store. A zero TTL or MaxBytes is no limit.
+type Limits struct {
+	TTL      time.Duration
+	MaxBytes int64
+}
+
+// validID accepts the IDs this package can store safely as file names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+func checkID(id string) error {
+	if !validID.MatchString(id) {
+		return ErrInvalidID
+	}
+	return nil
+}
+
+type entry struct {
+	id   string
+	size int64
+	used time.Time
+}
+
+// lru tracks entry sizes and use times, most recent first, and decides
+// what to evict. Callers hold their own lock around it.
+type lru struct {
+	limits Limits
+	now    func() time.Time
+	size   int64
+	order  *list.List
+	items  map[string]*list.Element
+}
+
+func newLRU(limits Limits) *lru {
+	return &lru{limits: limits, now: time.Now, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lru) expired(e *entry) bool {
+	return l.limits.TTL > 0 && l.now().Sub(e.used) >= l.limits.TTL
+}
+
+// add records an entry used at the given time, replacing any earlier one.
+func (l *lru) add(id string, size int64, used time.Time) {
+	l.remove(id)
+	el := l.order.PushFront(&entry{id: id, size: size, used: used})
+	// Entries loaded from disk arrive in no particular order; keep the
+	// list sorted by use time.
+	for el.Next() != nil && el.Next().Value.(*entry).used.After(used) {
+		l.order.MoveAfter(el, el.Next())
+	}
+	l.items[id] = el
+	l.size += size
+}
+
+// touch marks id as used now. It reports false when id is absent or has
+// expired, in which case the caller should delete it.
+func (l *lru) touch(id string) bool {
+	el, ok := l.items[id]
+	if !ok || l.expired(el.Value.(*entry)) {
+		return false
+	}
+	el.Value.(*entry).used = l.now()
+	l.order.MoveToFront(el)
+	return true
+}
+
+func (l *lru) remove(id string) {
+	if el, ok := l.items[id]; ok {
+		l.size -= el.Value.(*entry).size
+		l.order.Remove(el)
+		delete(l.items, id)
+	}
+}
+
+// evict removes expired entries, then the least recently used ones until
+// incoming more bytes fit, and returns the IDs removed.
+func (l *lru) evict(incoming int64) []string {
+	var evicted []string
+	// The list runs from most to least recently used, so eviction stops
+	// at the first entry that is neither expired nor needed for room.
+	for el := l.order.Back(); el != nil; el = l.order.Back() {
+		e := el.Value.(*entry)
+		over := l.limits.MaxBytes > 0 && l.size+incoming > l.limits.MaxBytes
+		if !over && !l.expired(e) {
+			break
+		}
+		l.remove(e.id)
+		evicted = append(evicted, e.id)
+	}
+
+	return evicted
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a settable time source for the lru.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+// backends opens every Store implementation with limits and the clock.
+func backends(t *testing.T, limits Limits, c *clock) map[string]Store {
+	memory := NewMemoryStore(limits)
+	memory.lru.now = c.now
+	files, err := NewFileStore(t.TempDir(), limits)
+	assert.NoError(t, err)
+	files.lru.now = c.now
+
+	return map[string]Store{"Memory": memory, "Filesystem": files}
+}
+
+func TestStore_PutGetDelete(t *testing.T) {
+	c := &clock{time.Unix(1000, 0)}
+	for name, store := range backends(t, Limits{}, c) {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, store.Put("abc", []byte("1,2\n")))
+			data, err := store.Get("abc")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("1,2\n"), data)
+
+			assert.NoError(t, store.Put("abc", []byte("3\n")))
+			data, _ = store.Get("abc")
+			assert.Equal(t, []byte("3\n"), data)
+
+			assert.NoError(t, store.Delete("abc"))
+			_, err = store.Get("abc")
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.ErrorIs(t, store.Delete("abc"), ErrNotFound)
+
+			assert.ErrorIs(t, store.Put("../etc", []byte("x")), ErrInvalidID)
+		})
+	}
+}
+
+func TestStore_TTL(t *testing.T) {
+	c := &clock{time.Unix(1000, 0)}
+	for name, store := range backends(t, Limits{TTL: time.Minute}, c) {
+		t.Run(name, func(t *testing.T) {
+			store.Put("old", []byte("1"))
+			store.Put("used", []byte("2"))
+
+			c.t = c.t.Add(40 * time.Second)
+			_, err := store.Get("used")
+			assert.NoError(t, err)
+
+			c.t = c.t.Add(40 * time.Second)
+			_, err = store.Get("old")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = store.Get("used")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStore_SizeEviction(t *testing.T) {
+	c := &clock{time.Unix(1000, 0)}
+	for name, store := range backends(t, Limits{MaxBytes: 10}, c) {
+		t.Run(name, func(t *testing.T) {
+			tick := func() { c.t = c.t.Add(time.Second) }
+			store.Put("a", []byte("1234"))
+			tick()
+			store.Put("b", []byte("1234"))
+			tick()
+			store.Get("a")
+			tick()
+
+			// Only b, the least recently used, has to go.
+			assert.NoError(t, store.Put("c", []byte("1234")))
+			_, err := store.Get("b")
+			assert.ErrorIs(t, err, ErrNotFound)
+			for _, id := range []string{"a", "c"} {
+				_, err := store.Get(id)
+				assert.NoError(t, err)
+			}
+
+			assert.ErrorIs(t, store.Put("d", []byte("12345678901")), ErrTooLarge)
+		})
+	}
+}
+
+func TestFileStore_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir, Limits{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put("kept", []byte("1,2\n")))
+	assert.NoError(t, store.Put("stale", []byte("3\n")))
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "stale"), old, old)
+	os.WriteFile(filepath.Join(dir, ".put-123"), []byte("partial"), 0o644)
+
+	reopened, err := NewFileStore(dir, Limits{TTL: time.Hour})
+	assert.NoError(t, err)
+	data, err := reopened.Get("kept")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1,2\n"), data)
+
+	_, err = reopened.Get("stale")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoFileExists(t, filepath.Join(dir, "stale"))
+}
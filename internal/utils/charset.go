@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var ErrUnsupportedCharset = errors.New("unsupported charset")
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// maxReportedOffsets caps the offsets listed in an ErrInvalidEncoding error.
+const maxReportedOffsets = 10
+
+// Charset is a character encoding DecodeText can convert to UTF-8.
+type Charset string
+
+const (
+	UTF8        Charset = "utf-8"
+	UTF16LE     Charset = "utf-16le"
+	UTF16BE     Charset = "utf-16be"
+	ISO88591    Charset = "iso-8859-1"
+	Windows1252 Charset = "windows-1252"
+)
+
+var charsetAliases = map[string]Charset{
+	"utf-8":        UTF8,
+	"utf8":         UTF8,
+	"us-ascii":     UTF8,
+	"utf-16le":     UTF16LE,
+	"utf-16be":     UTF16BE,
+	"iso-8859-1":   ISO88591,
+	"iso8859-1":    ISO88591,
+	"latin1":       ISO88591,
+	"latin-1":      ISO88591,
+	"windows-1252": Windows1252,
+	"cp1252":       Windows1252,
+}
+
+// ParseCharset accepts the charset names used in Content-Type headers. The
+// empty string means unknown and "utf-16" means UTF-16 of either byte order;
+// both are resolved from the byte order mark by DecodeText.
+func ParseCharset(s string) (Charset, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" || name == "utf-16" {
+		return Charset(name), nil
+	}
+	if charset, ok := charsetAliases[name]; ok {
+		return charset, nil
+	}
+
+	return "", fmt.Errorf("%w: %q (want utf-8, utf-16le, utf-16be, iso-8859-1 or windows-1252)", ErrUnsupportedCharset, s)
+}
+
+var boms = []struct {
+	charset Charset
+	mark    []byte
+}{
+	{UTF8, []byte{0xEF, 0xBB, 0xBF}},
+	{UTF16LE, []byte{0xFF, 0xFE}},
+	{UTF16BE, []byte{0xFE, 0xFF}},
+}
+
+// DecodeText converts data from charset to UTF-8 and strips any byte order
+// mark. A byte order mark decides the charset when none is given, and the
+// byte order when charset is "utf-16"; otherwise unknown text is read as
+// UTF-8 and UTF-16 without a mark as big endian. Every invalid byte sequence
+// is collected and reported by its offset in data.
+func DecodeText(data []byte, charset Charset) ([]byte, error) {
+	offset := 0
+	for _, bom := range boms {
+		if !bytes.HasPrefix(data, bom.mark) {
+			continue
+		}
+		switch {
+		case charset == "", charset == "utf-16" && bom.charset != UTF8:
+			charset = bom.charset
+		case charset != bom.charset:
+			continue
+		}
+		offset = len(bom.mark)
+		break
+	}
+
+	switch charset {
+	case "":
+		charset = UTF8
+	case "utf-16":
+		charset = UTF16BE
+	}
+
+	var decoded []byte
+	var invalid []int
+	switch charset {
+	case UTF8:
+		decoded, invalid = decodeUTF8(data[offset:])
+	case UTF16LE, UTF16BE:
+		decoded, invalid = decodeUTF16(data[offset:], charset == UTF16BE)
+	case ISO88591:
+		decoded, invalid = decodeSingleByte(data[offset:], nil)
+	case Windows1252:
+		decoded, invalid = decodeSingleByte(data[offset:], &windows1252)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCharset, charset)
+	}
+
+	if invalid != nil {
+		return nil, invalidEncodingError(charset, invalid, offset)
+	}
+	return decoded, nil
+}
+
+func invalidEncodingError(charset Charset, invalid []int, base int) error {
+	shown := invalid[:min(len(invalid), maxReportedOffsets)]
+	parts := make([]string, len(shown))
+	for i, off := range shown {
+		parts[i] = strconv.Itoa(base + off)
+	}
+
+	list := strings.Join(parts, ", ")
+	if more := len(invalid) - len(shown); more > 0 {
+		list += fmt.Sprintf(" and %d more", more)
+	}
+
+	return fmt.Errorf("%w: invalid %s at byte offsets %s", ErrInvalidEncoding, charset, list)
+}
+
+func decodeUTF8(data []byte) ([]byte, []int) {
+	if utf8.Valid(data) {
+		return data, nil
+	}
+
+	var invalid []int
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid = append(invalid, i)
+		}
+		i += size
+	}
+
+	return nil, invalid
+}
+
+func decodeUTF16(data []byte, bigEndian bool) ([]byte, []int) {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+
+	var invalid []int
+	var out bytes.Buffer
+	for i := 0; i < len(units); i++ {
+		unit := units[i]
+		switch {
+		case utf16.IsSurrogate(rune(unit)) && unit < 0xDC00 && i+1 < len(units):
+			r := utf16.DecodeRune(rune(unit), rune(units[i+1]))
+			if r == utf8.RuneError {
+				invalid = append(invalid, 2*i)
+				continue
+			}
+			out.WriteRune(r)
+			i++
+		case utf16.IsSurrogate(rune(unit)):
+			invalid = append(invalid, 2*i)
+		default:
+			out.WriteRune(rune(unit))
+		}
+	}
+
+	if len(data)%2 != 0 {
+		invalid = append(invalid, len(data)-1)
+	}
+	if invalid != nil {
+		return nil, invalid
+	}
+	return out.Bytes(), nil
+}
+
+// decodeSingleByte maps bytes below 0x80 to ASCII, bytes 0xA0 and above to
+// the Latin-1 code point of the same value, and bytes 0x80 to 0x9F through
+// high when given. A zero entry in high marks an undefined byte.
+func decodeSingleByte(data []byte, high *[32]rune) ([]byte, []int) {
+	var invalid []int
+	var out bytes.Buffer
+	out.Grow(len(data))
+	for i, b := range data {
+		r := rune(b)
+		if high != nil && b >= 0x80 && b < 0xA0 {
+			if r = high[b-0x80]; r == 0 {
+				invalid = append(invalid, i)
+				continue
+			}
+		}
+		out.WriteRune(r)
+	}
+
+	if invalid != nil {
+		return nil, invalid
+	}
+	return out.Bytes(), nil
+}
+
+// windows1252 maps bytes 0x80 to 0x9F. 0x81, 0x8D, 0x8F, 0x90 and 0x9D are
+// undefined.
+var windows1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCharset(t *testing.T) {
+	for input, expected := range map[string]Charset{
+		"":             "",
+		"UTF-8":        UTF8,
+		"us-ascii":     UTF8,
+		"utf-16":       "utf-16",
+		"UTF-16LE":     UTF16LE,
+		"utf-16be":     UTF16BE,
+		"latin1":       ISO88591,
+		"Windows-1252": Windows1252,
+	} {
+		charset, err := ParseCharset(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, charset)
+	}
+
+	_, err := ParseCharset("shift_jis")
+	assert.ErrorIs(t, err, ErrUnsupportedCharset)
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      []byte
+		charset    Charset
+		expected   string
+		errMessage string
+	}{
+		{
+			name:     "Plain UTF-8",
+			input:    []byte("1,2\n"),
+			expected: "1,2\n",
+		},
+		{
+			name:     "UTF-8 BOM is stripped",
+			input:    []byte("\xEF\xBB\xBF1,2\n"),
+			expected: "1,2\n",
+		},
+		{
+			name:     "UTF-8 BOM is stripped when declared",
+			input:    []byte("\xEF\xBB\xBF1,2\n"),
+			charset:  UTF8,
+			expected: "1,2\n",
+		},
+		{
+			name:     "UTF-16LE detected from BOM",
+			input:    []byte("\xFF\xFE1\x00,\x002\x00"),
+			expected: "1,2",
+		},
+		{
+			name:     "UTF-16BE detected from BOM",
+			input:    []byte("\xFE\xFF\x001\x00,\x002"),
+			expected: "1,2",
+		},
+		{
+			name:     "UTF-16 without BOM is big endian",
+			input:    []byte("\x001\x00,\x002"),
+			charset:  "utf-16",
+			expected: "1,2",
+		},
+		{
+			name:     "UTF-16LE surrogate pair",
+			input:    []byte("\x3D\xD8\x00\xDE"),
+			charset:  UTF16LE,
+			expected: "😀",
+		},
+		{
+			name:     "ISO-8859-1",
+			input:    []byte("caf\xE9,\x80"),
+			charset:  ISO88591,
+			expected: "café,\u0080",
+		},
+		{
+			name:     "Windows-1252",
+			input:    []byte("caf\xE9,\x80,\x93x\x94"),
+			charset:  Windows1252,
+			expected: "café,€,“x”",
+		},
+		{
+			name:       "Invalid UTF-8 offsets",
+			input:      []byte("1,\xFF\n\xC3,2\n"),
+			errMessage: "invalid encoding: invalid utf-8 at byte offsets 2, 4",
+		},
+		{
+			name:       "Offsets count the BOM",
+			input:      []byte("\xEF\xBB\xBF1,\xFF"),
+			errMessage: "invalid encoding: invalid utf-8 at byte offsets 5",
+		},
+		{
+			name:       "Unpaired surrogate and odd length",
+			input:      []byte("\x00\xD81\x00,"),
+			charset:    UTF16LE,
+			errMessage: "invalid encoding: invalid utf-16le at byte offsets 0, 4",
+		},
+		{
+			name:       "Undefined Windows-1252 byte",
+			input:      []byte("1,\x81"),
+			charset:    Windows1252,
+			errMessage: "invalid encoding: invalid windows-1252 at byte offsets 2",
+		},
+		{
+			name:       "Long offset lists are capped",
+			input:      []byte("\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF"),
+			errMessage: "invalid encoding: invalid utf-8 at byte offsets 0, 1, 2, 3, 4, 5, 6, 7, 8, 9 and 2 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeText(tt.input, tt.charset)
+			if tt.errMessage != "" {
+				assert.ErrorIs(t, err, ErrInvalidEncoding)
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(decoded))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"league/internal/matrixoperations"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidDialect = errors.New("invalid CSV dialect")
+
+// Dialect describes how a delimited text file is laid out.
+type Dialect struct {
+	Delimiter        rune
+	Comment          rune
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+	// Header marks the first row as column labels rather than data.
+	Header bool
+	// Index marks the first column as row labels rather than data.
+	Index bool
+}
+
+var DefaultDialect = Dialect{Delimiter: ','}
+
+// Table is a parsed delimited file. Header is nil unless the dialect has a
+// header row, and Index is nil unless it has an index column. When both are
+// present the top-left cell labels the index column and is kept in Corner.
+type Table struct {
+	Corner  string
+	Header  []string
+	Index   []string
+	Records [][]string
+	// Sparse is set instead of Records by readers of sparse formats whose
+	// values are all integers, so a mostly zero matrix is never expanded.
+	Sparse *matrixoperations.SparseMatrix
+}
+
+// DialectFromMediaType returns the dialect implied by a Content-Type:
+// text/tab-separated-values selects tabs, and the RFC 4180 header=present
+// parameter marks a header row. Anything else yields DefaultDialect.
+func DialectFromMediaType(contentType string) Dialect {
+	d := DefaultDialect
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return d
+	}
+
+	if mediaType == "text/tab-separated-values" {
+		d.Delimiter = '\t'
+	}
+	if strings.EqualFold(params["header"], "present") {
+		d.Header = true
+	}
+
+	return d
+}
+
+// ParseDelimiter accepts a single character, or tab written as "tab" or
+// "\t". It is used for both the delimiter and the comment character.
+func ParseDelimiter(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("%w: %q is not a single usable character", ErrInvalidDialect, s)
+	}
+
+	return r, nil
+}
+
+func ReadCSV(r io.Reader, d Dialect) (Table, error) {
+	if d.Delimiter == d.Comment {
+		return Table{}, fmt.Errorf("%w: delimiter and comment character are both %q", ErrInvalidDialect, d.Delimiter)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = d.Delimiter
+	reader.Comment = d.Comment
+	reader.LazyQuotes = d.LazyQuotes
+	reader.TrimLeadingSpace = d.TrimLeadingSpace
+	// Row lengths are checked by NormalizeRagged, which can report every
+	// ragged row instead of stopping at the first.
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return Table{}, err
+	}
+
+	var table Table
+	if d.Header && len(records) > 0 {
+		table.Header = records[0]
+		records = records[1:]
+	}
+	if d.Index {
+		table.Index = make([]string, len(records))
+		for i, record := range records {
+			table.Index[i] = record[0]
+			records[i] = record[1:]
+		}
+		if table.Header != nil {
+			table.Corner = table.Header[0]
+			table.Header = table.Header[1:]
+		}
+	}
+	table.Records = records
+
+	return table, nil
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectFromMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    Dialect
+	}{
+		{"", DefaultDialect},
+		{"application/octet-stream", DefaultDialect},
+		{"text/csv", DefaultDialect},
+		{"text/csv; header=present", Dialect{Delimiter: ',', Header: true}},
+		{"text/tab-separated-values", Dialect{Delimiter: '\t'}},
+		{"text/tab-separated-values; header=Present", Dialect{Delimiter: '\t', Header: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DialectFromMediaType(tt.contentType))
+		})
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected rune
+		wantErr  bool
+	}{
+		{";", ';', false},
+		{"|", '|', false},
+		{"tab", '\t', false},
+		{`\t`, '\t', false},
+		{"\t", '\t', false},
+		{"", 0, true},
+		{";;", 0, true},
+		{`"`, 0, true},
+		{"\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseDelimiter(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDialect)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, r)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  Dialect
+		expected Table
+		wantErr  bool
+	}{
+		{
+			name:     "Default dialect",
+			input:    "1,2\n3,4\n",
+			dialect:  DefaultDialect,
+			expected: Table{Records: [][]string{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:     "Semicolons with comments",
+			input:    "# exported 2024-01-01\n1;2\n# subtotal\n3;4\n",
+			dialect:  Dialect{Delimiter: ';', Comment: '#'},
+			expected: Table{Records: [][]string{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:     "Tabs with header",
+			input:    "a\tb\n1\t2\n",
+			dialect:  Dialect{Delimiter: '\t', Header: true},
+			expected: Table{Header: []string{"a", "b"}, Records: [][]string{{"1", "2"}}},
+		},
+		{
+			name:    "Header and index",
+			input:   "region,q1,q2\nnorth,1,2\nsouth,3,4\n",
+			dialect: Dialect{Delimiter: ',', Header: true, Index: true},
+			expected: Table{
+				Corner:  "region",
+				Header:  []string{"q1", "q2"},
+				Index:   []string{"north", "south"},
+				Records: [][]string{{"1", "2"}, {"3", "4"}},
+			},
+		},
+		{
+			name:     "Index without header",
+			input:    "north,1,2\nsouth,3,4\n",
+			dialect:  Dialect{Delimiter: ',', Index: true},
+			expected: Table{Index: []string{"north", "south"}, Records: [][]string{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:     "Trim leading space",
+			input:    "1, 2\n3, 4\n",
+			dialect:  Dialect{Delimiter: ',', TrimLeadingSpace: true},
+			expected: Table{Records: [][]string{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:     "Lazy quotes",
+			input:    "a \"quoted\" word,b\n",
+			dialect:  Dialect{Delimiter: ',', LazyQuotes: true},
+			expected: Table{Records: [][]string{{"a \"quoted\" word", "b"}}},
+		},
+		{
+			name:    "Bare quote without lazy quotes",
+			input:   "a \"quoted\" word,b\n",
+			dialect: DefaultDialect,
+			wantErr: true,
+		},
+		{
+			name:     "Ragged rows are left for NormalizeRagged",
+			input:    "1,2,3\n4,5\n",
+			dialect:  DefaultDialect,
+			expected: Table{Records: [][]string{{"1", "2", "3"}, {"4", "5"}}},
+		},
+		{
+			name:    "Delimiter equals comment",
+			input:   "1,2\n",
+			dialect: Dialect{Delimiter: '#', Comment: '#'},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadCSV(strings.NewReader(tt.input), tt.dialect)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, table)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// ErrUnrepresentable is returned by a writer when the table holds values its
+// format cannot express.
+var ErrUnrepresentable = errors.New("matrix cannot be written in this format")
+
+// Format reads and writes tables in one file format. Formats are looked up
+// by name, by media type and by file extension, so they can be chosen from
+// a format parameter, a Content-Type or Accept header, or an upload's name.
+type Format struct {
+	Name       string
+	MediaTypes []string
+	Extensions []string
+	// Binary formats are not decoded from a charset on the way in, nor
+	// followed by a newline on the way out.
+	Binary bool
+	// Delimiter is the separator a delimited format reads unless the caller
+	// asks for another, and the one it writes. It is zero for other formats.
+	Delimiter rune
+	// Read parses a table. The dialect only applies to delimited formats.
+	Read  func(r io.Reader, d Dialect) (Table, error)
+	Write func(w io.Writer, t Table) error
+}
+
+var CSVFormat = &Format{
+	Name:       "csv",
+	MediaTypes: []string{"text/csv"},
+	Extensions: []string{".csv"},
+	Delimiter:  ',',
+	Read:       ReadCSV,
+	Write: func(w io.Writer, t Table) error {
+		return writeDelimited(w, t, ',')
+	},
+}
+
+var TSVFormat = &Format{
+	Name:       "tsv",
+	MediaTypes: []string{"text/tab-separated-values"},
+	Extensions: []string{".tsv", ".tab"},
+	Delimiter:  '\t',
+	Read:       ReadCSV,
+	Write: func(w io.Writer, t Table) error {
+		return writeDelimited(w, t, '\t')
+	},
+}
+
+var JSONFormat = &Format{
+	Name:       "json",
+	MediaTypes: []string{"application/json"},
+	Extensions: []string{".json"},
+	Read:       ReadJSON,
+	Write:      WriteJSON,
+}
+
+var MatrixMarketFormat = &Format{
+	Name:       "mtx",
+	MediaTypes: []string{"application/x-matrix-market", "text/x-matrix-market"},
+	Extensions: []string{".mtx"},
+	Read:       ReadMatrixMarket,
+	Write:      WriteMatrixMarket,
+}
+
+var NPYFormat = &Format{
+	Name:       "npy",
+	MediaTypes: []string{"application/x-npy"},
+	Extensions: []string{".npy"},
+	Binary:     true,
+	Read:       ReadNPY,
+	Write:      WriteNPY,
+}
+
+var TriplesFormat = &Format{
+	Name:       "triples",
+	MediaTypes: []string{"text/x-triples"},
+	Extensions: []string{".triples", ".coo"},
+	Read:       ReadTriples,
+	Write:      WriteTriples,
+}
+
+var formats = []*Format{CSVFormat, TSVFormat, JSONFormat, MatrixMarketFormat, NPYFormat, TriplesFormat}
+
+// RegisterFormat adds f to the registry, replacing any format of the same
+// name.
+func RegisterFormat(f *Format) {
+	formats = slices.DeleteFunc(formats, func(existing *Format) bool {
+		return existing.Name == f.Name
+	})
+	formats = append(formats, f)
+}
+
+func LookupFormat(name string) (*Format, error) {
+	for _, f := range formats {
+		if strings.EqualFold(f.Name, name) {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q", ErrUnsupportedFormat, name)
+}
+
+// FormatForMediaType returns the format registered for mediaType, which
+// must not carry parameters, or nil.
+func FormatForMediaType(mediaType string) *Format {
+	for _, f := range formats {
+		if slices.Contains(f.MediaTypes, strings.ToLower(mediaType)) {
+			return f
+		}
+	}
+	return nil
+}
+
+// FormatForFilename returns the format registered for the extension of
+// name, or nil.
+func FormatForFilename(name string) *Format {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return nil
+	}
+	for _, f := range formats {
+		if slices.Contains(f.Extensions, ext) {
+			return f
+		}
+	}
+	return nil
+}
+
+// writeDelimited writes t in the layout ReadCSV reads with a header and an
+// index: a header line led by Corner when there are row labels, then each
+// record led by its row label.
+func writeDelimited(w io.Writer, t Table, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	if t.Header != nil {
+		header := t.Header
+		if t.Index != nil {
+			header = append([]string{t.Corner}, header...)
+		}
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	for i, record := range t.Records {
+		if t.Index != nil {
+			record = append([]string{t.Index[i]}, record...)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
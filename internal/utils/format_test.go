@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupFormat(t *testing.T) {
+	for _, name := range []string{"csv", "TSV", "json", "mtx"} {
+		format, err := LookupFormat(name)
+		assert.NoError(t, err)
+		assert.True(t, strings.EqualFold(name, format.Name))
+	}
+
+	_, err := LookupFormat("xml")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	assert.EqualError(t, err, `unsupported format "xml"`)
+}
+
+func TestFormatForMediaTypeAndFilename(t *testing.T) {
+	assert.Equal(t, TSVFormat, FormatForMediaType("text/tab-separated-values"))
+	assert.Equal(t, JSONFormat, FormatForMediaType("Application/JSON"))
+	assert.Nil(t, FormatForMediaType("application/octet-stream"))
+
+	assert.Equal(t, MatrixMarketFormat, FormatForFilename("bcsstk01.MTX"))
+	assert.Equal(t, CSVFormat, FormatForFilename("matrix.csv"))
+	assert.Equal(t, TriplesFormat, FormatForFilename("edges.coo"))
+	assert.Nil(t, FormatForFilename("matrix"))
+}
+
+func TestRegisterFormat(t *testing.T) {
+	saved := formats
+	defer func() { formats = saved }()
+
+	upper := &Format{
+		Name:       "csv",
+		MediaTypes: []string{"text/x-upper"},
+		Read:       ReadCSV,
+		Write: func(w io.Writer, t Table) error {
+			_, err := io.WriteString(w, strings.ToUpper(t.Records[0][0]))
+			return err
+		},
+	}
+	RegisterFormat(upper)
+
+	format, err := LookupFormat("csv")
+	assert.NoError(t, err)
+	assert.Same(t, upper, format)
+	assert.Equal(t, upper, FormatForMediaType("text/x-upper"))
+	assert.Nil(t, FormatForMediaType("text/csv"), "the replaced format is unregistered")
+}
+
+func TestDelimitedFormats(t *testing.T) {
+	table := Table{
+		Corner:  "region",
+		Header:  []string{"q1", "q2"},
+		Index:   []string{"north", "south"},
+		Records: [][]string{{"1", "a,b"}, {"3", ""}},
+	}
+
+	var csvOut strings.Builder
+	assert.NoError(t, CSVFormat.Write(&csvOut, table))
+	assert.Equal(t, "region,q1,q2\nnorth,1,\"a,b\"\nsouth,3,\n", csvOut.String())
+
+	var tsvOut strings.Builder
+	assert.NoError(t, TSVFormat.Write(&tsvOut, Table{Records: [][]string{{"1", "2"}, {"3", "4"}}}))
+	assert.Equal(t, "1\t2\n3\t4\n", tsvOut.String())
+
+	read, err := TSVFormat.Read(strings.NewReader(tsvOut.String()), Dialect{Delimiter: TSVFormat.Delimiter})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}}, read.Records)
+
+	read, err = TSVFormat.Read(strings.NewReader("1,2\n"), DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"1", "2"}}, read.Records, "the dialect's delimiter is used as given")
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrInvalidJSONMatrix = errors.New("invalid JSON matrix")
+
+// jsonTable is the labeled JSON layout, which follows pandas' "split"
+// orientation. A matrix without labels is a bare array of rows.
+type jsonTable struct {
+	Corner  string `json:"corner,omitempty"`
+	Columns []any  `json:"columns,omitempty"`
+	Index   []any  `json:"index,omitempty"`
+	Data    []any  `json:"data"`
+}
+
+// ReadJSON parses either an array of rows, such as [[1,2],[3,4]], or an
+// object with a data array of rows and optional columns, index and corner
+// labels. Numbers keep their literal text, null reads as an empty cell and
+// booleans as true or false. The dialect is ignored.
+func ReadJSON(r io.Reader, _ Dialect) (Table, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var raw any
+	if err := decoder.Decode(&raw); err != nil {
+		return Table{}, fmt.Errorf("%w: %v", ErrInvalidJSONMatrix, err)
+	}
+
+	var doc jsonTable
+	switch v := raw.(type) {
+	case []any:
+		doc.Data = v
+	case map[string]any:
+		var ok [4]bool
+		doc.Data, ok[0] = v["data"].([]any)
+		doc.Columns, ok[1] = v["columns"].([]any)
+		doc.Index, ok[2] = v["index"].([]any)
+		doc.Corner, ok[3] = v["corner"].(string)
+		for i, key := range []string{"data", "columns", "index", "corner"} {
+			if _, present := v[key]; present && !ok[i] {
+				return Table{}, fmt.Errorf("%w: %s has the wrong type", ErrInvalidJSONMatrix, key)
+			}
+		}
+		if doc.Data == nil {
+			return Table{}, fmt.Errorf("%w: object has no data array", ErrInvalidJSONMatrix)
+		}
+	default:
+		return Table{}, fmt.Errorf("%w: want an array of rows or an object with a data array", ErrInvalidJSONMatrix)
+	}
+
+	var table Table
+	var err error
+	table.Records = make([][]string, len(doc.Data))
+	for i, row := range doc.Data {
+		values, ok := row.([]any)
+		if !ok {
+			return Table{}, fmt.Errorf("%w: row %d is not an array", ErrInvalidJSONMatrix, i+1)
+		}
+		if table.Records[i], err = jsonCells(values, fmt.Sprintf("row %d", i+1)); err != nil {
+			return Table{}, err
+		}
+	}
+
+	if doc.Columns != nil {
+		if table.Header, err = jsonCells(doc.Columns, "columns"); err != nil {
+			return Table{}, err
+		}
+	}
+	if doc.Index != nil {
+		if len(doc.Index) != len(doc.Data) {
+			return Table{}, fmt.Errorf("%w: index has %d labels for %d rows", ErrInvalidJSONMatrix, len(doc.Index), len(doc.Data))
+		}
+		if table.Index, err = jsonCells(doc.Index, "index"); err != nil {
+			return Table{}, err
+		}
+		table.Corner = doc.Corner
+	}
+
+	return table, nil
+}
+
+func jsonCells(values []any, where string) ([]string, error) {
+	cells := make([]string, len(values))
+	for j, value := range values {
+		switch v := value.(type) {
+		case nil:
+			cells[j] = ""
+		case json.Number:
+			cells[j] = v.String()
+		case string:
+			cells[j] = v
+		case bool:
+			cells[j] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("%w: %s col %d is not a number, string, boolean or null", ErrInvalidJSONMatrix, where, j+1)
+		}
+	}
+
+	return cells, nil
+}
+
+// WriteJSON writes t as an array of rows, or as an object in the layout
+// ReadJSON accepts when t has labels. Integer cells in canonical form are
+// written as numbers, empty cells as null and anything else, including
+// integers such as 007, +5 or -0 that JSON numbers cannot spell, as strings.
+func WriteJSON(w io.Writer, t Table) error {
+	data := make([]any, len(t.Records))
+	for i, record := range t.Records {
+		row := make([]any, len(record))
+		for j, cell := range record {
+			if cell == "" {
+				continue
+			}
+			if n, err := strconv.Atoi(cell); err == nil && strconv.Itoa(n) == cell {
+				row[j] = json.Number(cell)
+			} else {
+				row[j] = cell
+			}
+		}
+		data[i] = row
+	}
+
+	if t.Header == nil && t.Index == nil {
+		return json.NewEncoder(w).Encode(data)
+	}
+
+	doc := jsonTable{Corner: t.Corner, Data: data}
+	for _, label := range t.Header {
+		doc.Columns = append(doc.Columns, label)
+	}
+	for _, label := range t.Index {
+		doc.Index = append(doc.Index, label)
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		expected   Table
+		errMessage string
+	}{
+		{
+			name:     "Array of rows",
+			input:    `[[1, 2], [3, -4]]`,
+			expected: Table{Records: [][]string{{"1", "2"}, {"3", "-4"}}},
+		},
+		{
+			name:     "Mixed scalars",
+			input:    `[["a", null, true, 1.5]]`,
+			expected: Table{Records: [][]string{{"a", "", "true", "1.5"}}},
+		},
+		{
+			name:     "Large integers keep their text",
+			input:    `[[9007199254740993]]`,
+			expected: Table{Records: [][]string{{"9007199254740993"}}},
+		},
+		{
+			name:  "Labeled object",
+			input: `{"corner": "region", "columns": ["q1", "q2"], "index": ["north"], "data": [[1, 2]]}`,
+			expected: Table{
+				Corner:  "region",
+				Header:  []string{"q1", "q2"},
+				Index:   []string{"north"},
+				Records: [][]string{{"1", "2"}},
+			},
+		},
+		{
+			name:       "Object without data",
+			input:      `{"columns": ["a"]}`,
+			errMessage: "invalid JSON matrix: object has no data array",
+		},
+		{
+			name:       "Row that is not an array",
+			input:      `[[1], 2]`,
+			errMessage: "invalid JSON matrix: row 2 is not an array",
+		},
+		{
+			name:       "Nested cell",
+			input:      `[[1, [2]]]`,
+			errMessage: "invalid JSON matrix: row 1 col 2 is not a number, string, boolean or null",
+		},
+		{
+			name:       "Index length mismatch",
+			input:      `{"index": ["a", "b"], "data": [[1]]}`,
+			errMessage: "invalid JSON matrix: index has 2 labels for 1 rows",
+		},
+		{
+			name:       "Scalar document",
+			input:      `42`,
+			errMessage: "invalid JSON matrix: want an array of rows or an object with a data array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadJSON(strings.NewReader(tt.input), DefaultDialect)
+			if tt.errMessage != "" {
+				assert.ErrorIs(t, err, ErrInvalidJSONMatrix)
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, table)
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out strings.Builder
+	assert.NoError(t, WriteJSON(&out, Table{Records: [][]string{{"1", ""}, {"x", "-4"}}}))
+	assert.Equal(t, "[[1,null],[\"x\",-4]]\n", out.String())
+
+	// Integers JSON cannot spell as numbers are kept as written.
+	out.Reset()
+	assert.NoError(t, WriteJSON(&out, Table{Records: [][]string{{"00501", "+5", "-0", "0"}}}))
+	assert.Equal(t, "[[\"00501\",\"+5\",\"-0\",0]]\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, WriteJSON(&out, Table{Header: []string{"q1"}, Records: [][]string{{"5"}}}))
+	assert.Equal(t, "{\"columns\":[\"q1\"],\"data\":[[5]]}\n", out.String())
+
+	table := Table{Corner: "r", Header: []string{"a", "b"}, Index: []string{"x"}, Records: [][]string{{"1", "NA"}}}
+	out.Reset()
+	assert.NoError(t, WriteJSON(&out, table))
+	roundTrip, err := ReadJSON(strings.NewReader(out.String()), DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, table, roundTrip)
+}
@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"league/internal/matrixoperations"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMatrixMarket = errors.New("invalid Matrix Market file")
+
+// mmHeader is the banner line of a Matrix Market file, such as
+// "%%MatrixMarket matrix coordinate integer general".
+type mmHeader struct {
+	layout   string // coordinate or array
+	field    string // integer, real or pattern
+	symmetry string // general, symmetric or skew-symmetric
+}
+
+func parseMMHeader(line string) (mmHeader, error) {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) != 5 || fields[0] != "%%matrixmarket" || fields[1] != "matrix" {
+		return mmHeader{}, fmt.Errorf("%w: want a %%%%MatrixMarket matrix banner, got %q", ErrInvalidMatrixMarket, line)
+	}
+
+	h := mmHeader{layout: fields[2], field: fields[3], symmetry: fields[4]}
+	switch {
+	case h.layout != "coordinate" && h.layout != "array":
+		return h, fmt.Errorf("%w: unsupported layout %q", ErrInvalidMatrixMarket, h.layout)
+	case h.field != "integer" && h.field != "real" && h.field != "pattern":
+		return h, fmt.Errorf("%w: unsupported field %q", ErrInvalidMatrixMarket, h.field)
+	case h.field == "pattern" && h.layout != "coordinate":
+		return h, fmt.Errorf("%w: pattern matrices must use the coordinate layout", ErrInvalidMatrixMarket)
+	case h.symmetry != "general" && h.symmetry != "symmetric" && h.symmetry != "skew-symmetric":
+		return h, fmt.Errorf("%w: unsupported symmetry %q", ErrInvalidMatrixMarket, h.symmetry)
+	}
+
+	return h, nil
+}
+
+// mmValue normalizes a value, writing integral reals such as 3.0 as
+// integers so they parse into a numeric matrix.
+func mmValue(s string, field string) string {
+	if field != "real" {
+		return s
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) >= math.MaxInt64 {
+		return s
+	}
+	return strconv.FormatInt(int64(f), 10)
+}
+
+func negate(s string) string {
+	if n, err := strconv.Atoi(s); err == nil {
+		return strconv.Itoa(-n)
+	}
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		return rest
+	}
+	return "-" + s
+}
+
+// ReadMatrixMarket parses the coordinate and array layouts of the Matrix
+// Market exchange format with integer, real or pattern values and general,
+// symmetric or skew-symmetric symmetry. Cells a coordinate file leaves out
+// read as 0. The dialect is ignored.
+func ReadMatrixMarket(r io.Reader, _ Dialect) (Table, error) {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	next := func() ([]string, bool) {
+		for scanner.Scan() {
+			lineNo++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "%") {
+				continue
+			}
+			return strings.Fields(line), true
+		}
+		return nil, false
+	}
+	fail := func(format string, args ...any) (Table, error) {
+		return Table{}, fmt.Errorf("%w: line %d: %s", ErrInvalidMatrixMarket, lineNo, fmt.Sprintf(format, args...))
+	}
+
+	if !scanner.Scan() {
+		return Table{}, fmt.Errorf("%w: file is empty", ErrInvalidMatrixMarket)
+	}
+	lineNo++
+	header, err := parseMMHeader(scanner.Text())
+	if err != nil {
+		return Table{}, err
+	}
+
+	size, ok := next()
+	if !ok {
+		return fail("missing size line")
+	}
+	wantSize := 2
+	if header.layout == "coordinate" {
+		wantSize = 3
+	}
+	if len(size) != wantSize {
+		return fail("size line has %d fields, want %d", len(size), wantSize)
+	}
+	dims := make([]int, len(size))
+	for i, field := range size {
+		if dims[i], err = strconv.Atoi(field); err != nil || dims[i] < 0 {
+			return fail("invalid size %q", field)
+		}
+	}
+	rows, cols := dims[0], dims[1]
+	if err := matrixoperations.CheckShape(rows, cols); err != nil {
+		return Table{}, fmt.Errorf("%w: line %d: %w", ErrInvalidMatrixMarket, lineNo, err)
+	}
+	if header.symmetry != "general" && rows != cols {
+		return fail("%s matrix must be square, got %dx%d", header.symmetry, rows, cols)
+	}
+
+	// Coordinate files are collected cell by cell, so that a mostly zero
+	// matrix can become a SparseMatrix without ever being expanded.
+	cells := make(map[[2]int]string)
+	set := func(i, j int, value string) {
+		cells[[2]int{i, j}] = value
+		switch header.symmetry {
+		case "symmetric":
+			cells[[2]int{j, i}] = value
+		case "skew-symmetric":
+			cells[[2]int{j, i}] = negate(value)
+		}
+	}
+
+	if header.layout == "coordinate" {
+		for n := 0; n < dims[2]; n++ {
+			entry, ok := next()
+			if !ok {
+				return fail("expected %d entries, found %d", dims[2], n)
+			}
+			want := 3
+			if header.field == "pattern" {
+				want = 2
+			}
+			if len(entry) != want {
+				return fail("entry has %d fields, want %d", len(entry), want)
+			}
+			i, errI := strconv.Atoi(entry[0])
+			j, errJ := strconv.Atoi(entry[1])
+			if errI != nil || errJ != nil || i < 1 || i > rows || j < 1 || j > cols {
+				return fail("entry (%s, %s) is outside the %dx%d matrix", entry[0], entry[1], rows, cols)
+			}
+			value := "1"
+			if header.field != "pattern" {
+				value = mmValue(entry[2], header.field)
+			}
+			set(i-1, j-1, value)
+		}
+	} else {
+		// Array files list values column by column; symmetric files hold
+		// only the lower triangle, without the diagonal when skew.
+		for j := 0; j < cols; j++ {
+			first := 0
+			switch header.symmetry {
+			case "symmetric":
+				first = j
+			case "skew-symmetric":
+				first = j + 1
+			}
+			for i := first; i < rows; i++ {
+				entry, ok := next()
+				if !ok {
+					return fail("missing value for row %d col %d", i+1, j+1)
+				}
+				if len(entry) != 1 {
+					return fail("entry has %d fields, want 1", len(entry))
+				}
+				set(i, j, mmValue(entry[0], header.field))
+			}
+		}
+	}
+
+	if _, ok := next(); ok {
+		return fail("unexpected data after the last entry")
+	}
+	if err := scanner.Err(); err != nil {
+		return Table{}, err
+	}
+
+	if header.layout == "coordinate" {
+		if sparse, ok := sparseFromCells(rows, cols, cells); ok {
+			return Table{Sparse: sparse}, nil
+		}
+	}
+
+	records := make([][]string, rows)
+	for i := range records {
+		records[i] = make([]string, cols)
+		for j := range records[i] {
+			records[i][j] = "0"
+		}
+	}
+	for at, value := range cells {
+		records[at[0]][at[1]] = value
+	}
+
+	return Table{Records: records}, nil
+}
+
+// sparseFromCells builds a SparseMatrix from the cells a coordinate file
+// lists, or reports false when any of them is not an integer.
+func sparseFromCells(rows, cols int, cells map[[2]int]string) (*matrixoperations.SparseMatrix, bool) {
+	entries := make([]matrixoperations.Entry, 0, len(cells))
+	for at, value := range cells {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, false
+		}
+		entries = append(entries, matrixoperations.Entry{Row: at[0], Col: at[1], Value: n})
+	}
+
+	// Cells are unique and in range, so building cannot fail.
+	sparse, err := matrixoperations.NewSparseMatrix(rows, cols, entries)
+	return sparse, err == nil
+}
+
+// WriteMatrixMarket writes t in the general integer array layout. Labels are
+// dropped, and any cell that is not an integer is ErrUnrepresentable.
+func WriteMatrixMarket(w io.Writer, t Table) error {
+	rows := len(t.Records)
+	cols := 0
+	if rows > 0 {
+		cols = len(t.Records[0])
+	}
+
+	for i, record := range t.Records {
+		for j, cell := range record {
+			if _, err := strconv.Atoi(cell); err != nil {
+				return fmt.Errorf("%w: Matrix Market holds integers only, row %d col %d is %q", ErrUnrepresentable, i+1, j+1, cell)
+			}
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "%%MatrixMarket matrix array integer general")
+	fmt.Fprintf(out, "%d %d\n", rows, cols)
+	for j := 0; j < cols; j++ {
+		for i := 0; i < rows; i++ {
+			fmt.Fprintln(out, t.Records[i][j])
+		}
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMatrixMarket(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		expected   [][]string
+		sparse     bool
+		errMessage string
+	}{
+		{
+			name:     "Coordinate integer general",
+			input:    "%%MatrixMarket matrix coordinate integer general\n% a comment\n2 3 2\n1 1 5\n2 3 -7\n",
+			expected: [][]string{{"5", "0", "0"}, {"0", "0", "-7"}},
+			sparse:   true,
+		},
+		{
+			name:     "Coordinate real with integral values",
+			input:    "%%MatrixMarket matrix coordinate real general\n2 2 2\n1 2 3.0\n2 1 2.5\n",
+			expected: [][]string{{"0", "3"}, {"2.5", "0"}},
+		},
+		{
+			name:     "Coordinate pattern symmetric",
+			input:    "%%MatrixMarket matrix coordinate pattern symmetric\n2 2 1\n2 1\n",
+			expected: [][]string{{"0", "1"}, {"1", "0"}},
+			sparse:   true,
+		},
+		{
+			name:     "Coordinate skew-symmetric",
+			input:    "%%MatrixMarket matrix coordinate integer skew-symmetric\n2 2 1\n2 1 4\n",
+			expected: [][]string{{"0", "-4"}, {"4", "0"}},
+			sparse:   true,
+		},
+		{
+			name:     "Array general is column-major",
+			input:    "%%MatrixMarket matrix array integer general\n2 2\n1\n3\n2\n4\n",
+			expected: [][]string{{"1", "2"}, {"3", "4"}},
+		},
+		{
+			name:     "Array symmetric holds the lower triangle",
+			input:    "%%MatrixMarket matrix array integer symmetric\n2 2\n1\n2\n3\n",
+			expected: [][]string{{"1", "2"}, {"2", "3"}},
+		},
+		{
+			name:       "Missing banner",
+			input:      "2 2\n1\n2\n3\n4\n",
+			errMessage: "invalid Matrix Market file: want a %%MatrixMarket matrix banner, got \"2 2\"",
+		},
+		{
+			name:       "Complex values",
+			input:      "%%MatrixMarket matrix coordinate complex general\n1 1 0\n",
+			errMessage: "invalid Matrix Market file: unsupported field \"complex\"",
+		},
+		{
+			name:       "Entry outside the matrix",
+			input:      "%%MatrixMarket matrix coordinate integer general\n2 2 1\n3 1 1\n",
+			errMessage: "invalid Matrix Market file: line 3: entry (3, 1) is outside the 2x2 matrix",
+		},
+		{
+			name:       "Too few entries",
+			input:      "%%MatrixMarket matrix coordinate integer general\n2 2 2\n1 1 1\n",
+			errMessage: "invalid Matrix Market file: line 3: expected 2 entries, found 1",
+		},
+		{
+			name:       "Declared size too large",
+			input:      "%%MatrixMarket matrix coordinate real general\n100000 100000 1\n1 1 0.5\n",
+			errMessage: "invalid Matrix Market file: line 2: matrix has too many cells: 100000x100000 exceeds 16777216",
+		},
+		{
+			name:       "Declared size overflows",
+			input:      "%%MatrixMarket matrix array integer general\n4611686018427387905 4\n",
+			errMessage: "invalid Matrix Market file: line 2: matrix has too many cells: 4611686018427387905x4 exceeds 16777216",
+		},
+		{
+			name:       "Trailing data",
+			input:      "%%MatrixMarket matrix array integer general\n1 1\n1\n2\n",
+			errMessage: "invalid Matrix Market file: line 4: unexpected data after the last entry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadMatrixMarket(strings.NewReader(tt.input), DefaultDialect)
+			if tt.errMessage != "" {
+				assert.ErrorIs(t, err, ErrInvalidMatrixMarket)
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.sparse {
+				assert.Nil(t, table.Records)
+				assert.Equal(t, tt.expected, table.Sparse.Cells())
+			} else {
+				assert.Nil(t, table.Sparse)
+				assert.Equal(t, tt.expected, table.Records)
+			}
+		})
+	}
+}
+
+func TestWriteMatrixMarket(t *testing.T) {
+	var out strings.Builder
+	table := Table{Header: []string{"a", "b"}, Records: [][]string{{"1", "2"}, {"3", "4"}}}
+	assert.NoError(t, WriteMatrixMarket(&out, table))
+	assert.Equal(t, "%%MatrixMarket matrix array integer general\n2 2\n1\n3\n2\n4\n", out.String())
+
+	roundTrip, err := ReadMatrixMarket(strings.NewReader(out.String()), DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, table.Records, roundTrip.Records)
+
+	err = WriteMatrixMarket(&out, Table{Records: [][]string{{"1", "x"}}})
+	assert.ErrorIs(t, err, ErrUnrepresentable)
+	assert.EqualError(t, err, "matrix cannot be written in this format: Matrix Market holds integers only, row 1 col 2 is \"x\"")
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"league/internal/matrixoperations"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidNPY = errors.New("invalid .npy file")
+var ErrUnsupportedDType = errors.New("unsupported dtype")
+
+var npyMagic = []byte("\x93NUMPY")
+
+// npyMaxHeader caps the header length a file may declare. NumPy writes
+// headers of well under a kilobyte, and version 2 lengths could otherwise
+// make us allocate gigabytes before reading a byte of them.
+const npyMaxHeader = 64 << 10
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// npyDType is a parsed descr such as "<i8": byte order, kind and size.
+type npyDType struct {
+	descr string
+	order binary.ByteOrder
+	kind  byte // i, u or f
+	size  int
+}
+
+func parseNPYDType(descr string) (npyDType, error) {
+	unsupported := fmt.Errorf("%w %q (want a signed or unsigned integer, float32 or float64)", ErrUnsupportedDType, descr)
+	if len(descr) < 3 {
+		return npyDType{}, unsupported
+	}
+
+	dt := npyDType{descr: descr}
+	switch descr[0] {
+	case '<', '|', '=':
+		dt.order = binary.LittleEndian
+	case '>':
+		dt.order = binary.BigEndian
+	default:
+		return npyDType{}, unsupported
+	}
+
+	dt.kind = descr[1]
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return npyDType{}, unsupported
+	}
+	dt.size = size
+
+	switch {
+	case (dt.kind == 'i' || dt.kind == 'u') && (size == 1 || size == 2 || size == 4 || size == 8):
+	case dt.kind == 'f' && (size == 4 || size == 8):
+	default:
+		return npyDType{}, unsupported
+	}
+
+	return dt, nil
+}
+
+// cell formats one element. Integral floats are written as integers so they
+// parse into a numeric matrix, and NaN is written as an empty, null cell.
+// Other floats, and unsigned values past int64, have no matrix to hold them
+// and are an error.
+func (dt npyDType) cell(b []byte) (string, error) {
+	var bits uint64
+	switch dt.size {
+	case 1:
+		bits = uint64(b[0])
+	case 2:
+		bits = uint64(dt.order.Uint16(b))
+	case 4:
+		bits = uint64(dt.order.Uint32(b))
+	case 8:
+		bits = dt.order.Uint64(b)
+	}
+
+	switch dt.kind {
+	case 'i':
+		shift := 64 - 8*dt.size
+		return strconv.FormatInt(int64(bits<<shift)>>shift, 10), nil
+	case 'u':
+		if bits > math.MaxInt64 {
+			return "", fmt.Errorf("value %d exceeds int64", bits)
+		}
+		return strconv.FormatUint(bits, 10), nil
+	}
+
+	f := math.Float64frombits(bits)
+	bitSize := 64
+	if dt.size == 4 {
+		f = float64(math.Float32frombits(uint32(bits)))
+		bitSize = 32
+	}
+	switch {
+	case math.IsNaN(f):
+		return "", nil
+	case f == math.Trunc(f) && math.Abs(f) < math.MaxInt64:
+		return strconv.FormatInt(int64(f), 10), nil
+	}
+	return "", fmt.Errorf("value %s is not a whole number (want integers or NaN)", strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// ReadNPY parses a NumPy .npy file of version 1, 2 or 3 holding a 0, 1 or 2
+// dimensional array of integers or floats, in C or Fortran order. Floats
+// must be whole numbers or NaN. A one dimensional array becomes a single
+// row. The dialect is ignored.
+func ReadNPY(r io.Reader, _ Dialect) (Table, error) {
+	in := bufio.NewReader(r)
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(in, prefix); err != nil || !bytes.HasPrefix(prefix, npyMagic) {
+		return Table{}, fmt.Errorf("%w: missing magic string", ErrInvalidNPY)
+	}
+
+	var headerLen int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		var n uint16
+		if err := binary.Read(in, binary.LittleEndian, &n); err != nil {
+			return Table{}, fmt.Errorf("%w: truncated header", ErrInvalidNPY)
+		}
+		headerLen = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(in, binary.LittleEndian, &n); err != nil {
+			return Table{}, fmt.Errorf("%w: truncated header", ErrInvalidNPY)
+		}
+		headerLen = int(n)
+	default:
+		return Table{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidNPY, major)
+	}
+
+	if headerLen > npyMaxHeader {
+		return Table{}, fmt.Errorf("%w: header length %d exceeds %d", ErrInvalidNPY, headerLen, npyMaxHeader)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return Table{}, fmt.Errorf("%w: truncated header", ErrInvalidNPY)
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	fortran := npyFortran.FindSubmatch(header)
+	shape := npyShape.FindSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return Table{}, fmt.Errorf("%w: header %q lacks descr, fortran_order or shape", ErrInvalidNPY, strings.TrimSpace(string(header)))
+	}
+
+	dt, err := parseNPYDType(string(descr[1]))
+	if err != nil {
+		return Table{}, err
+	}
+
+	var dims []int
+	for _, field := range strings.Split(string(shape[1]), ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return Table{}, fmt.Errorf("%w: invalid shape (%s)", ErrInvalidNPY, shape[1])
+		}
+		dims = append(dims, n)
+	}
+	rows, cols := 1, 1
+	switch len(dims) {
+	case 0:
+	case 1:
+		cols = dims[0]
+	case 2:
+		rows, cols = dims[0], dims[1]
+	default:
+		return Table{}, fmt.Errorf("%w: %d-dimensional arrays are not matrices", ErrInvalidNPY, len(dims))
+	}
+
+	if err := matrixoperations.CheckShape(rows, cols); err != nil {
+		return Table{}, fmt.Errorf("%w: %w", ErrInvalidNPY, err)
+	}
+	if rows > 0 && cols > math.MaxInt32/rows/dt.size {
+		return Table{}, fmt.Errorf("%w: shape %dx%d is too large", ErrInvalidNPY, rows, cols)
+	}
+	// Read through a limit rather than allocating the declared size up
+	// front, so a lying header cannot force a large allocation.
+	want := rows * cols * dt.size
+	data, err := io.ReadAll(io.LimitReader(in, int64(want)))
+	if err != nil || len(data) != want {
+		return Table{}, fmt.Errorf("%w: expected %d bytes of data, found %d", ErrInvalidNPY, want, len(data))
+	}
+
+	records := make([][]string, rows)
+	for i := range records {
+		records[i] = make([]string, cols)
+	}
+	for k := 0; k < rows*cols; k++ {
+		i, j := k/cols, k%cols
+		if string(fortran[1]) == "True" {
+			i, j = k%rows, k/rows
+		}
+		if records[i][j], err = dt.cell(data[k*dt.size : (k+1)*dt.size]); err != nil {
+			return Table{}, fmt.Errorf("%w %q: row %d col %d: %v", ErrUnsupportedDType, dt.descr, i+1, j+1, err)
+		}
+	}
+
+	return Table{Records: records}, nil
+}
+
+// WriteNPY writes t as a version 1.0 .npy file in C order: int64 when every
+// cell is an integer, otherwise float64 with empty cells written as NaN.
+// Labels are dropped, and text cells are ErrUnrepresentable.
+func WriteNPY(w io.Writer, t Table) error {
+	rows := len(t.Records)
+	cols := 0
+	if rows > 0 {
+		cols = len(t.Records[0])
+	}
+
+	descr := "<i8"
+	ints := make([]int64, 0, rows*cols)
+	floats := make([]float64, 0, rows*cols)
+	for i, record := range t.Records {
+		for j, cell := range record {
+			if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+				ints = append(ints, n)
+				floats = append(floats, float64(n))
+				continue
+			}
+			descr = "<f8"
+			if cell == "" {
+				floats = append(floats, math.NaN())
+				continue
+			}
+			f, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return fmt.Errorf("%w: .npy holds numbers only, row %d col %d is %q", ErrUnrepresentable, i+1, j+1, cell)
+			}
+			floats = append(floats, f)
+		}
+	}
+
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }", descr, rows, cols)
+	// The magic string, version, length and header are padded with spaces
+	// to a multiple of 64 bytes, ending in a newline.
+	total := len(npyMagic) + 2 + 2 + len(header) + 1
+	header += strings.Repeat(" ", (64-total%64)%64) + "\n"
+
+	out := bufio.NewWriter(w)
+	out.Write(npyMagic)
+	out.Write([]byte{1, 0})
+	binary.Write(out, binary.LittleEndian, uint16(len(header)))
+	out.WriteString(header)
+	if descr == "<i8" {
+		binary.Write(out, binary.LittleEndian, ints)
+	} else {
+		binary.Write(out, binary.LittleEndian, floats)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// npyFile builds a .npy file of the given version around a header dict and
+// raw data.
+func npyFile(major byte, header string, data any, order binary.ByteOrder) []byte {
+	var buf bytes.Buffer
+	buf.Write(npyMagic)
+	buf.Write([]byte{major, 0})
+	if major == 1 {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	} else {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(header)))
+	}
+	buf.WriteString(header)
+	binary.Write(&buf, order, data)
+	return buf.Bytes()
+}
+
+func npyHeader(descr string, fortran bool, shape string) string {
+	order := "False"
+	if fortran {
+		order = "True"
+	}
+	return fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': %s, }\n", descr, order, shape)
+}
+
+func TestReadNPY(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      []byte
+		expected   [][]string
+		errMessage string
+	}{
+		{
+			name:     "int8 in C order",
+			input:    npyFile(1, npyHeader("|i1", false, "(2, 2)"), []int8{1, -2, 3, -128}, binary.LittleEndian),
+			expected: [][]string{{"1", "-2"}, {"3", "-128"}},
+		},
+		{
+			name:     "Big endian uint16",
+			input:    npyFile(1, npyHeader(">u2", false, "(1, 2)"), []uint16{1, 65535}, binary.BigEndian),
+			expected: [][]string{{"1", "65535"}},
+		},
+		{
+			name:     "int64 in Fortran order",
+			input:    npyFile(1, npyHeader("<i8", true, "(2, 3)"), []int64{1, 4, 2, 5, 3, 6}, binary.LittleEndian),
+			expected: [][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+		},
+		{
+			name:     "Whole float32 and NaN",
+			input:    npyFile(1, npyHeader("<f4", false, "(1, 3)"), []float32{2, -3, float32(math.NaN())}, binary.LittleEndian),
+			expected: [][]string{{"2", "-3", ""}},
+		},
+		{
+			name:       "Fractional float32",
+			input:      npyFile(1, npyHeader("<f4", false, "(1, 3)"), []float32{2, 0.1, float32(math.NaN())}, binary.LittleEndian),
+			errMessage: "unsupported dtype \"<f4\": row 1 col 2: value 0.1 is not a whole number (want integers or NaN)",
+		},
+		{
+			name:       "Fractional float64",
+			input:      npyFile(1, npyHeader("<f8", true, "(2, 1)"), []float64{1, math.Pi}, binary.LittleEndian),
+			errMessage: "unsupported dtype \"<f8\": row 2 col 1: value 3.141592653589793 is not a whole number (want integers or NaN)",
+		},
+		{
+			name:       "Infinite float64",
+			input:      npyFile(1, npyHeader("<f8", false, "(1, 1)"), []float64{math.Inf(1)}, binary.LittleEndian),
+			errMessage: "unsupported dtype \"<f8\": row 1 col 1: value +Inf is not a whole number (want integers or NaN)",
+		},
+		{
+			name:     "Version 2 header and one dimension",
+			input:    npyFile(2, npyHeader("<i4", false, "(3,)"), []int32{7, 8, 9}, binary.LittleEndian),
+			expected: [][]string{{"7", "8", "9"}},
+		},
+		{
+			name:     "uint64 within int64",
+			input:    npyFile(1, npyHeader("<u8", false, "(1, 1)"), []uint64{math.MaxInt64}, binary.LittleEndian),
+			expected: [][]string{{"9223372036854775807"}},
+		},
+		{
+			name:       "uint64 past int64",
+			input:      npyFile(1, npyHeader("<u8", false, "(1, 2)"), []uint64{1, math.MaxUint64}, binary.LittleEndian),
+			errMessage: "unsupported dtype \"<u8\": row 1 col 2: value 18446744073709551615 exceeds int64",
+		},
+		{
+			name:       "Unsupported dtype",
+			input:      npyFile(1, npyHeader("<c16", false, "(1, 1)"), []float64{0, 0}, binary.LittleEndian),
+			errMessage: "unsupported dtype \"<c16\" (want a signed or unsigned integer, float32 or float64)",
+		},
+		{
+			name:       "Three dimensions",
+			input:      npyFile(1, npyHeader("<i8", false, "(1, 1, 1)"), []int64{1}, binary.LittleEndian),
+			errMessage: "invalid .npy file: 3-dimensional arrays are not matrices",
+		},
+		{
+			name:       "Empty rows past the cell limit",
+			input:      npyFile(1, npyHeader("<i8", false, "(1000000000, 0)"), []int64{}, binary.LittleEndian),
+			errMessage: "invalid .npy file: matrix has too many cells: 1000000000x0 exceeds 16777216",
+		},
+		{
+			name:       "Truncated data",
+			input:      npyFile(1, npyHeader("<i8", false, "(2, 2)"), []int64{1, 2, 3}, binary.LittleEndian),
+			errMessage: "invalid .npy file: expected 32 bytes of data, found 24",
+		},
+		{
+			name:       "Huge version 2 header length",
+			input:      append([]byte("\x93NUMPY\x02\x00"), 0xff, 0xff, 0xff, 0x7f),
+			errMessage: "invalid .npy file: header length 2147483647 exceeds 65536",
+		},
+		{
+			name:       "Not a .npy file",
+			input:      []byte("1,2\n3,4\n"),
+			errMessage: "invalid .npy file: missing magic string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadNPY(bytes.NewReader(tt.input), DefaultDialect)
+			if tt.errMessage != "" {
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, table.Records)
+		})
+	}
+}
+
+func TestWriteNPY(t *testing.T) {
+	var out bytes.Buffer
+	ints := Table{Records: [][]string{{"1", "-2"}, {"3", "4"}}}
+	assert.NoError(t, WriteNPY(&out, ints))
+	assert.Zero(t, strings.Index(out.String(), "\x93NUMPY\x01\x00"))
+	assert.Contains(t, out.String(), "'descr': '<i8'")
+	assert.Zero(t, (out.Len()-4*8)%64, "header is padded to a multiple of 64 bytes")
+
+	roundTrip, err := ReadNPY(&out, DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, ints.Records, roundTrip.Records)
+
+	out.Reset()
+	nulls := Table{Records: [][]string{{"1", "", "-3"}}}
+	assert.NoError(t, WriteNPY(&out, nulls))
+	assert.Contains(t, out.String(), "'descr': '<f8'")
+	roundTrip, err = ReadNPY(&out, DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, nulls.Records, roundTrip.Records)
+
+	// Fractions are written, but do not read back.
+	out.Reset()
+	assert.NoError(t, WriteNPY(&out, Table{Records: [][]string{{"1", "2.5", ""}}}))
+	assert.Contains(t, out.String(), "'descr': '<f8'")
+	_, err = ReadNPY(&out, DefaultDialect)
+	assert.ErrorIs(t, err, ErrUnsupportedDType)
+
+	err = WriteNPY(&out, Table{Records: [][]string{{"a"}}})
+	assert.ErrorIs(t, err, ErrUnrepresentable)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"league/internal/matrixoperations"
+	"slices"
 	"strconv"
 )
 
@@ -11,22 +12,20 @@ func ParseIntMatrix(data [][]string) (matrixoperations.NumericMatrix, error) {
 		return matrixoperations.NumericMatrix{}, nil
 	}
 
-	rowLen := len(data[0])
-	matrix := make(matrixoperations.NumericMatrix, len(data))
+	if err := checkRectangular(data); err != nil {
+		return nil, err
+	}
+
+	matrix := matrixoperations.NewNumericMatrix(len(data), len(data[0]))
 
 	for i, row := range data {
-		if len(row) != rowLen {
-			return nil, fmt.Errorf("row %d has inconsistent length", i+1)
-		}
-		intRow := make([]int, rowLen)
 		for j, val := range row {
 			n, err := strconv.Atoi(val)
 			if err != nil {
 				return nil, fmt.Errorf("invalid int at row %d col %d: %w", i+1, j+1, err)
 			}
-			intRow[j] = n
+			matrix[i][j] = n
 		}
-		matrix[i] = intRow
 	}
 	return matrix, nil
 }
@@ -36,14 +35,55 @@ func ParseStringMatrix(data [][]string) (matrixoperations.AlphanumericMatrix, er
 		return matrixoperations.AlphanumericMatrix{}, nil
 	}
 
-	rowLen := len(data[0])
+	if err := checkRectangular(data); err != nil {
+		return nil, err
+	}
+
 	matrix := make(matrixoperations.AlphanumericMatrix, len(data))
 
 	for i, row := range data {
-		if len(row) != rowLen {
-			return nil, fmt.Errorf("row %d has inconsistent length", i+1)
-		}
 		matrix[i] = append([]string(nil), row...)
 	}
 	return matrix, nil
 }
+
+// ParseNullableMatrix parses data as integers, treating any cell equal to one
+// of nullTokens as null. Nulls are written back out as the first token.
+func ParseNullableMatrix(data [][]string, nullTokens []string, policy matrixoperations.NullPolicy) (*matrixoperations.NullableMatrix, error) {
+	token := ""
+	if len(nullTokens) > 0 {
+		token = nullTokens[0]
+	}
+	if len(data) == 0 {
+		return &matrixoperations.NullableMatrix{Values: matrixoperations.NumericMatrix{}, Null: [][]bool{}, Policy: policy, Token: token}, nil
+	}
+
+	if err := checkRectangular(data); err != nil {
+		return nil, err
+	}
+
+	rowLen := len(data[0])
+	matrix := &matrixoperations.NullableMatrix{
+		Values: matrixoperations.NewNumericMatrix(len(data), rowLen),
+		Null:   make([][]bool, len(data)),
+		Policy: policy,
+		Token:  token,
+	}
+
+	for i, row := range data {
+		nullRow := make([]bool, rowLen)
+		for j, val := range row {
+			if slices.Contains(nullTokens, val) {
+				nullRow[j] = true
+				continue
+			}
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid int at row %d col %d: %w", i+1, j+1, err)
+			}
+			matrix.Values[i][j] = n
+		}
+		matrix.Null[i] = nullRow
+	}
+	return matrix, nil
+}
@@ -85,3 +85,50 @@ func TestParseStringMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNullableMatrix(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     [][]string
+		tokens    []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Empty cells and NA",
+			input:    [][]string{{"1", ""}, {"NA", "4"}},
+			tokens:   []string{"", "NA"},
+			expected: "1,\n,4\n",
+		},
+		{
+			name:     "Custom token is written back",
+			input:    [][]string{{"1", "-"}, {"3", "4"}},
+			tokens:   []string{"-"},
+			expected: "1,-\n3,4\n",
+		},
+		{
+			name:      "Unlisted token",
+			input:     [][]string{{"1", "NA"}},
+			tokens:    []string{""},
+			expectErr: true,
+		},
+		{
+			name:      "Inconsistent row length",
+			input:     [][]string{{"1", "NA"}, {"3"}},
+			tokens:    []string{"NA"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseNullableMatrix(tt.input, tt.tokens, matrixoperations.NullSkip)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrRaggedRows = errors.New("ragged rows")
+var ErrInvalidRaggedMode = errors.New("invalid ragged mode")
+
+// RaggedMode decides what happens to rows whose length differs from the rest
+// of the matrix.
+type RaggedMode int
+
+const (
+	// RaggedError rejects any row whose length differs from the first row.
+	RaggedError RaggedMode = iota
+	// RaggedPad pads short rows up to the longest row.
+	RaggedPad
+	// RaggedTruncate cuts long rows down to the shortest row.
+	RaggedTruncate
+)
+
+func ParseRaggedMode(s string) (RaggedMode, error) {
+	switch s {
+	case "", "error":
+		return RaggedError, nil
+	case "pad":
+		return RaggedPad, nil
+	case "truncate":
+		return RaggedTruncate, nil
+	}
+
+	return RaggedError, fmt.Errorf("%w: %q (want error, pad or truncate)", ErrInvalidRaggedMode, s)
+}
+
+// RaggedRow reports a row, numbered from 1, whose length differs from the
+// matrix width.
+type RaggedRow struct {
+	Row    int `json:"row"`
+	Length int `json:"length"`
+}
+
+// FindRaggedRows lists every row whose length differs from width.
+func FindRaggedRows(data [][]string, width int) []RaggedRow {
+	var ragged []RaggedRow
+	for i, row := range data {
+		if len(row) != width {
+			ragged = append(ragged, RaggedRow{Row: i + 1, Length: len(row)})
+		}
+	}
+
+	return ragged
+}
+
+func raggedRowsError(width int, ragged []RaggedRow) error {
+	parts := make([]string, len(ragged))
+	for i, r := range ragged {
+		parts[i] = fmt.Sprintf("row %d has %d", r.Row, r.Length)
+	}
+
+	return fmt.Errorf("%w: expected %d fields per row, %s", ErrRaggedRows, width, strings.Join(parts, ", "))
+}
+
+// checkRectangular fails with every row whose length differs from the first.
+func checkRectangular(data [][]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	width := len(data[0])
+	if ragged := FindRaggedRows(data, width); ragged != nil {
+		return raggedRowsError(width, ragged)
+	}
+
+	return nil
+}
+
+// NormalizeRagged makes every row of table the same length according to
+// mode, filling padded cells with pad. It returns the rows that had to be
+// changed; under RaggedError those rows are reported as an error instead.
+// A header row is padded or truncated with the records, but is not reported.
+func NormalizeRagged(table Table, mode RaggedMode, pad string) (Table, []RaggedRow, error) {
+	if len(table.Records) == 0 {
+		return table, nil, nil
+	}
+
+	width := len(table.Records[0])
+	for _, row := range table.Records {
+		switch mode {
+		case RaggedPad:
+			width = max(width, len(row))
+		case RaggedTruncate:
+			width = min(width, len(row))
+		}
+	}
+
+	ragged := FindRaggedRows(table.Records, width)
+	if mode == RaggedError {
+		if ragged != nil {
+			return table, nil, raggedRowsError(width, ragged)
+		}
+		if table.Header != nil && len(table.Header) != width {
+			return table, nil, fmt.Errorf("%w: header has %d labels for %d columns", ErrRaggedRows, len(table.Header), width)
+		}
+		return table, nil, nil
+	}
+
+	fit := func(row []string) []string {
+		if len(row) >= width {
+			return row[:width]
+		}
+		out := append(make([]string, 0, width), row...)
+		for len(out) < width {
+			out = append(out, pad)
+		}
+		return out
+	}
+
+	normalized := table
+	normalized.Records = make([][]string, len(table.Records))
+	for i, row := range table.Records {
+		normalized.Records[i] = fit(row)
+	}
+	if table.Header != nil {
+		normalized.Header = fit(table.Header)
+	}
+
+	return normalized, ragged, nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRaggedMode(t *testing.T) {
+	for input, expected := range map[string]RaggedMode{
+		"":         RaggedError,
+		"error":    RaggedError,
+		"pad":      RaggedPad,
+		"truncate": RaggedTruncate,
+	} {
+		mode, err := ParseRaggedMode(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, mode)
+	}
+
+	_, err := ParseRaggedMode("fill")
+	assert.ErrorIs(t, err, ErrInvalidRaggedMode)
+}
+
+func TestNormalizeRagged(t *testing.T) {
+	tests := []struct {
+		name       string
+		table      Table
+		mode       RaggedMode
+		pad        string
+		expected   Table
+		ragged     []RaggedRow
+		errMessage string
+	}{
+		{
+			name:     "Rectangular table is unchanged",
+			table:    Table{Records: [][]string{{"1", "2"}, {"3", "4"}}},
+			mode:     RaggedError,
+			expected: Table{Records: [][]string{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:       "Error lists every ragged row",
+			table:      Table{Records: [][]string{{"1", "2", "3"}, {"4", "5", "6"}, {"7"}, {"8", "9"}}},
+			mode:       RaggedError,
+			errMessage: "ragged rows: expected 3 fields per row, row 3 has 1, row 4 has 2",
+		},
+		{
+			name:       "Error on header length",
+			table:      Table{Header: []string{"a", "b"}, Records: [][]string{{"1", "2", "3"}}},
+			mode:       RaggedError,
+			errMessage: "ragged rows: header has 2 labels for 3 columns",
+		},
+		{
+			name:     "Pad to the longest row",
+			table:    Table{Records: [][]string{{"1", "2"}, {"3", "4", "5"}, {"6"}}},
+			mode:     RaggedPad,
+			pad:      "0",
+			expected: Table{Records: [][]string{{"1", "2", "0"}, {"3", "4", "5"}, {"6", "0", "0"}}},
+			ragged:   []RaggedRow{{Row: 1, Length: 2}, {Row: 3, Length: 1}},
+		},
+		{
+			name:     "Pad header with records",
+			table:    Table{Header: []string{"a"}, Records: [][]string{{"1", "2"}, {"3"}}},
+			mode:     RaggedPad,
+			expected: Table{Header: []string{"a", ""}, Records: [][]string{{"1", "2"}, {"3", ""}}},
+			ragged:   []RaggedRow{{Row: 2, Length: 1}},
+		},
+		{
+			name:     "Truncate to the shortest row",
+			table:    Table{Header: []string{"a", "b", "c"}, Records: [][]string{{"1", "2", "3"}, {"4", "5"}}},
+			mode:     RaggedTruncate,
+			expected: Table{Header: []string{"a", "b"}, Records: [][]string{{"1", "2"}, {"4", "5"}}},
+			ragged:   []RaggedRow{{Row: 1, Length: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, ragged, err := NormalizeRagged(tt.table, tt.mode, tt.pad)
+			if tt.errMessage != "" {
+				assert.ErrorIs(t, err, ErrRaggedRows)
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, table)
+			assert.Equal(t, tt.ragged, ragged)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"league/internal/matrixoperations"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSelector = errors.New("invalid selector")
+var ErrSelectorOutOfRange = errors.New("selector out of range")
+
+// ParseSelector resolves spec against a dimension of length n and returns the
+// selected indices in order. A selector is a comma-separated list of indices
+// and start:stop:step slices with Python semantics: indices are zero-based,
+// negative indices count from the end, stop is exclusive and any slice part
+// may be omitted. An empty spec selects nothing and returns nil, which callers
+// treat as "keep every index". Indices may repeat, but not past
+// matrixoperations.MaxCells of them.
+func ParseSelector(spec string, n int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	indices := []int{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		var selected []int
+		var err error
+		if strings.Contains(item, ":") {
+			selected, err = parseSlice(item, n)
+		} else {
+			selected, err = parseIndex(item, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(indices)+len(selected) > matrixoperations.MaxCells {
+			return nil, fmt.Errorf("%w: selector picks more than %d indices", matrixoperations.ErrTooManyCells, matrixoperations.MaxCells)
+		}
+		indices = append(indices, selected...)
+	}
+
+	return indices, nil
+}
+
+func parseIndex(item string, n int) ([]int, error) {
+	i, err := strconv.Atoi(item)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not an index or slice", ErrInvalidSelector, item)
+	}
+	if i < -n || i >= n {
+		return nil, fmt.Errorf("%w: index %d", ErrSelectorOutOfRange, i)
+	}
+	if i < 0 {
+		i += n
+	}
+
+	return []int{i}, nil
+}
+
+func parseSlice(item string, n int) ([]int, error) {
+	parts := strings.Split(item, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("%w: %q has more than three slice parts", ErrInvalidSelector, item)
+	}
+
+	bound := func(s string) (int, bool, error) {
+		if s == "" {
+			return 0, false, nil
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, false, fmt.Errorf("%w: %q is not a valid slice", ErrInvalidSelector, item)
+		}
+		if v < -n || v > n {
+			return 0, false, fmt.Errorf("%w: slice %s", ErrSelectorOutOfRange, item)
+		}
+		if v < 0 {
+			v += n
+		}
+		return v, true, nil
+	}
+
+	step := 1
+	if len(parts) == 3 && parts[2] != "" {
+		var err error
+		step, err = strconv.Atoi(parts[2])
+		if err != nil || step == 0 {
+			return nil, fmt.Errorf("%w: %q needs a non-zero integer step", ErrInvalidSelector, item)
+		}
+	}
+	start, hasStart, err := bound(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	stop, hasStop, err := bound(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var indices []int
+	if step > 0 {
+		if !hasStop {
+			stop = n
+		}
+		for i := start; i < stop; i += step {
+			indices = append(indices, i)
+		}
+	} else {
+		if !hasStart || start > n-1 {
+			start = n - 1
+		}
+		if !hasStop {
+			stop = -1
+		}
+		for i := start; i > stop; i += step {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices, nil
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"league/internal/matrixoperations"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		n        int
+		expected []int
+		wantErr  error
+	}{
+		{name: "Empty selects everything", spec: "", n: 5, expected: nil},
+		{name: "Single index", spec: "2", n: 5, expected: []int{2}},
+		{name: "Index list", spec: "2,0,4", n: 5, expected: []int{2, 0, 4}},
+		{name: "Negative index", spec: "-1", n: 5, expected: []int{4}},
+		{name: "Slice", spec: "1:3", n: 5, expected: []int{1, 2}},
+		{name: "Open slice", spec: "2:", n: 5, expected: []int{2, 3, 4}},
+		{name: "Slice with step", spec: "::2", n: 5, expected: []int{0, 2, 4}},
+		{name: "Negative slice bounds", spec: "-3:-1", n: 5, expected: []int{2, 3}},
+		{name: "Reverse slice", spec: "::-1", n: 4, expected: []int{3, 2, 1, 0}},
+		{name: "Reverse slice with bounds", spec: "3:0:-2", n: 5, expected: []int{3, 1}},
+		{name: "Mixed slices and indices", spec: "0, 3:5", n: 5, expected: []int{0, 3, 4}},
+		{name: "Empty slice", spec: "3:1", n: 5, expected: []int{}},
+		{name: "Index out of range", spec: "5", n: 5, wantErr: ErrSelectorOutOfRange},
+		{name: "Negative index out of range", spec: "-6", n: 5, wantErr: ErrSelectorOutOfRange},
+		{name: "Slice out of range", spec: "3:10", n: 5, wantErr: ErrSelectorOutOfRange},
+		{name: "Not a number", spec: "a", n: 5, wantErr: ErrInvalidSelector},
+		{name: "Zero step", spec: "::0", n: 5, wantErr: ErrInvalidSelector},
+		{name: "Too many parts", spec: "1:2:3:4", n: 5, wantErr: ErrInvalidSelector},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indices, err := ParseSelector(tt.spec, tt.n)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				if tt.expected == nil {
+					assert.Nil(t, indices)
+				} else {
+					assert.Equal(t, tt.expected, indices)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSelector_TooManyIndices(t *testing.T) {
+	defer func(n int) { matrixoperations.MaxCells = n }(matrixoperations.MaxCells)
+	matrixoperations.MaxCells = 10
+
+	indices, err := ParseSelector("0:,0:", 5)
+	assert.NoError(t, err)
+	assert.Len(t, indices, 10)
+	_, err = ParseSelector("0:,0:,0", 5)
+	assert.ErrorIs(t, err, matrixoperations.ErrTooManyCells)
+}
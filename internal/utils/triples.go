@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"league/internal/matrixoperations"
+	"strconv"
+)
+
+var ErrInvalidTriples = errors.New("invalid triples file")
+
+// ReadTriples parses a sparse matrix written as one row,col,value line per
+// cell, with zero-based indices in any order. A first line that does not
+// start with an index, such as "row,col,value", is taken as a header and
+// skipped. The shape is the largest row and column seen, so a zero value
+// may be listed to extend it, up to matrixoperations.MaxCells. Values must
+// be integers, and the result is always a Table with Sparse set. The
+// dialect's delimiter, comment and space trimming apply.
+func ReadTriples(r io.Reader, d Dialect) (Table, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = d.Delimiter
+	reader.Comment = d.Comment
+	reader.LazyQuotes = d.LazyQuotes
+	reader.TrimLeadingSpace = d.TrimLeadingSpace
+	reader.FieldsPerRecord = -1
+
+	var entries []matrixoperations.Entry
+	seen := make(map[[2]int]int)
+	rows, cols := 0, 0
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Table{}, fmt.Errorf("%w: %v", ErrInvalidTriples, err)
+		}
+		line, _ := reader.FieldPos(0)
+		fail := func(format string, args ...any) (Table, error) {
+			return Table{}, fmt.Errorf("%w: line %d: %s", ErrInvalidTriples, line, fmt.Sprintf(format, args...))
+		}
+
+		if len(record) != 3 {
+			return fail("has %d fields, want row, col and value", len(record))
+		}
+		i, errI := strconv.Atoi(record[0])
+		if errI != nil && first {
+			continue
+		}
+		j, errJ := strconv.Atoi(record[1])
+		if errI != nil || errJ != nil || i < 0 || j < 0 {
+			return fail("invalid index (%s, %s)", record[0], record[1])
+		}
+		value, err := strconv.Atoi(record[2])
+		if err != nil {
+			return fail("value %q is not an integer", record[2])
+		}
+		if err := matrixoperations.CheckShape(max(rows, i+1), max(cols, j+1)); err != nil {
+			return Table{}, fmt.Errorf("%w: line %d: %w", ErrInvalidTriples, line, err)
+		}
+		if prev, ok := seen[[2]int{i, j}]; ok {
+			return fail("row %d col %d is already set on line %d", i, j, prev)
+		}
+		seen[[2]int{i, j}] = line
+
+		entries = append(entries, matrixoperations.Entry{Row: i, Col: j, Value: value})
+		rows, cols = max(rows, i+1), max(cols, j+1)
+	}
+
+	sparse, err := matrixoperations.NewSparseMatrix(rows, cols, entries)
+	if err != nil {
+		return Table{}, fmt.Errorf("%w: %w", ErrInvalidTriples, err)
+	}
+
+	return Table{Sparse: sparse}, nil
+}
+
+// WriteTriples writes the nonzero cells of t in the layout ReadTriples
+// reads, after a row,col,value header. The bottom-right cell is always
+// written so the shape survives a round trip. Labels are dropped, and any
+// cell that is not an integer is ErrUnrepresentable.
+func WriteTriples(w io.Writer, t Table) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"row", "col", "value"})
+	for i, record := range t.Records {
+		for j, cell := range record {
+			n, err := strconv.Atoi(cell)
+			if err != nil {
+				return fmt.Errorf("%w: triples hold integers only, row %d col %d is %q", ErrUnrepresentable, i+1, j+1, cell)
+			}
+			last := i == len(t.Records)-1 && j == len(record)-1
+			if n == 0 && !last {
+				continue
+			}
+			writer.Write([]string{strconv.Itoa(i), strconv.Itoa(j), cell})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTriples(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		dialect    Dialect
+		expected   [][]string
+		errMessage string
+	}{
+		{
+			name:     "Header and unordered cells",
+			input:    "row,col,value\n2,0,-3\n0,1,5\n",
+			dialect:  DefaultDialect,
+			expected: [][]string{{"0", "5"}, {"0", "0"}, {"-3", "0"}},
+		},
+		{
+			name:     "Zero value extends the shape",
+			input:    "0,0,1\n1,3,0\n",
+			dialect:  DefaultDialect,
+			expected: [][]string{{"1", "0", "0", "0"}, {"0", "0", "0", "0"}},
+		},
+		{
+			name:     "Dialect delimiter",
+			input:    "0;0;7\n",
+			dialect:  Dialect{Delimiter: ';'},
+			expected: [][]string{{"7"}},
+		},
+		{
+			name:       "Wrong field count",
+			input:      "0,0,1\n1,1\n",
+			dialect:    DefaultDialect,
+			errMessage: "invalid triples file: line 2: has 2 fields, want row, col and value",
+		},
+		{
+			name:       "Negative index",
+			input:      "0,-1,1\n",
+			dialect:    DefaultDialect,
+			errMessage: "invalid triples file: line 1: invalid index (0, -1)",
+		},
+		{
+			name:       "Index too large",
+			input:      "0,0,1\n0,9999999999,1\n",
+			dialect:    DefaultDialect,
+			errMessage: "invalid triples file: line 2: matrix has too many cells: 1x10000000000 exceeds 16777216",
+		},
+		{
+			name:       "Non-integer value",
+			input:      "0,0,1.5\n",
+			dialect:    DefaultDialect,
+			errMessage: "invalid triples file: line 1: value \"1.5\" is not an integer",
+		},
+		{
+			name:       "Repeated cell",
+			input:      "row,col,value\n0,0,1\n0,0,2\n",
+			dialect:    DefaultDialect,
+			errMessage: "invalid triples file: line 3: row 0 col 0 is already set on line 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadTriples(strings.NewReader(tt.input), tt.dialect)
+			if tt.errMessage != "" {
+				assert.ErrorIs(t, err, ErrInvalidTriples)
+				assert.EqualError(t, err, tt.errMessage)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Nil(t, table.Records)
+			assert.Equal(t, tt.expected, table.Sparse.Cells())
+		})
+	}
+}
+
+func TestWriteTriples(t *testing.T) {
+	var out strings.Builder
+	table := Table{Records: [][]string{{"0", "4"}, {"-1", "0"}, {"0", "0"}}}
+	assert.NoError(t, WriteTriples(&out, table))
+	assert.Equal(t, "row,col,value\n0,1,4\n1,0,-1\n2,1,0\n", out.String())
+
+	roundTrip, err := ReadTriples(strings.NewReader(out.String()), DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, table.Records, roundTrip.Sparse.Cells())
+
+	err = WriteTriples(&out, Table{Records: [][]string{{"1", "x"}}})
+	assert.ErrorIs(t, err, ErrUnrepresentable)
+	assert.EqualError(t, err, "matrix cannot be written in this format: triples hold integers only, row 1 col 2 is \"x\"")
+}
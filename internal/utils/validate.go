@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// DefaultMaxIssues caps the issues Validate collects when no limit is given.
+const DefaultMaxIssues = 100
+
+// Issue is a problem found while validating a matrix. Row and Col are
+// numbered from 1; Col is 0 when the issue concerns a whole row.
+type Issue struct {
+	Row    int    `json:"row"`
+	Col    int    `json:"col,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Validate scans every cell of data as an integer matrix in which cells equal
+// to one of nullTokens are allowed, instead of stopping at the first bad cell
+// like ParseIntMatrix. Rows whose length differs from the first row are
+// reported once each. It returns at most limit issues in row-major order and
+// whether more were found.
+func Validate(data [][]string, nullTokens []string, limit int) ([]Issue, bool) {
+	if limit <= 0 {
+		limit = DefaultMaxIssues
+	}
+
+	issues := []Issue{}
+	add := func(issue Issue) bool {
+		if len(issues) == limit {
+			return false
+		}
+		issues = append(issues, issue)
+		return true
+	}
+
+	for i, row := range data {
+		if len(row) != len(data[0]) {
+			if !add(Issue{Row: i + 1, Reason: fmt.Sprintf("row has %d fields, expected %d", len(row), len(data[0]))}) {
+				return issues, true
+			}
+		}
+		for j, val := range row {
+			if slices.Contains(nullTokens, val) {
+				continue
+			}
+			if _, err := strconv.Atoi(val); err != nil {
+				if !add(Issue{Row: i + 1, Col: j + 1, Value: val, Reason: intErrorReason(err)}) {
+					return issues, true
+				}
+			}
+		}
+	}
+
+	return issues, false
+}
+
+func intErrorReason(err error) string {
+	if errors.Is(err, strconv.ErrRange) {
+		return "integer out of range"
+	}
+	return "not an integer"
+}
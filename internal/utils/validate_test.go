@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     [][]string
+		limit     int
+		expected  []Issue
+		truncated bool
+	}{
+		{
+			name:     "Valid matrix",
+			input:    [][]string{{"1", "NA"}, {"3", "4"}},
+			expected: []Issue{},
+		},
+		{
+			name:  "Every bad cell is reported",
+			input: [][]string{{"1", "x"}, {"3.5", "4"}, {"5", "99999999999999999999"}},
+			expected: []Issue{
+				{Row: 1, Col: 2, Value: "x", Reason: "not an integer"},
+				{Row: 2, Col: 1, Value: "3.5", Reason: "not an integer"},
+				{Row: 3, Col: 2, Value: "99999999999999999999", Reason: "integer out of range"},
+			},
+		},
+		{
+			name:  "Ragged rows are reported with their cells",
+			input: [][]string{{"1", "2"}, {"y"}},
+			expected: []Issue{
+				{Row: 2, Reason: "row has 1 fields, expected 2"},
+				{Row: 2, Col: 1, Value: "y", Reason: "not an integer"},
+			},
+		},
+		{
+			name:  "Limit truncates the report",
+			input: [][]string{{"a", "b"}, {"c", "d"}},
+			limit: 3,
+			expected: []Issue{
+				{Row: 1, Col: 1, Value: "a", Reason: "not an integer"},
+				{Row: 1, Col: 2, Value: "b", Reason: "not an integer"},
+				{Row: 2, Col: 1, Value: "c", Reason: "not an integer"},
+			},
+			truncated: true,
+		},
+		{
+			name:     "Limit equal to the issue count is not truncated",
+			input:    [][]string{{"a", "1"}},
+			limit:    1,
+			expected: []Issue{{Row: 1, Col: 1, Value: "a", Reason: "not an integer"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, truncated := Validate(tt.input, []string{"", "NA"}, tt.limit)
+			assert.Equal(t, tt.expected, issues)
+			assert.Equal(t, tt.truncated, truncated)
+		})
+	}
+}
@@ -3,43 +3,247 @@ package main
 import (
 	"context"
 	"fmt"
+	"league/internal/admission"
 	"league/internal/api"
+	"league/internal/auth"
+	"league/internal/certs"
+	"league/internal/config"
+	"league/internal/jobs"
+	"league/internal/matrixoperations"
+	"league/internal/metrics"
+	"league/internal/ratelimit"
+	"league/internal/storage"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 )
 
+// The scopes that let a key use the routes other than the operations, whose
+// scopes are their names.
+const (
+	matricesScope = "matrices"
+	jobsScope     = "jobs"
+	metricsScope  = "metrics"
+)
+
 func main() {
+	cfg, err := config.Load(os.Getenv)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	limits := storage.Limits{TTL: cfg.MatrixStoreTTL, MaxBytes: cfg.MatrixStoreMaxBytes}
+	api.MatrixStore = storage.NewMemoryStore(limits)
+	if cfg.MatrixStoreDir != "" {
+		if api.MatrixStore, err = storage.NewFileStore(cfg.MatrixStoreDir, limits); err != nil {
+			fmt.Printf("Matrix store error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	matrixoperations.Workers = cfg.ParallelWorkers
+	matrixoperations.ParallelThreshold = cfg.ParallelThreshold
+	matrixoperations.MaxCells = cfg.MaxCells
+
+	if cfg.ResultCacheBytes > 0 {
+		api.ResultCache = storage.NewMemoryStore(storage.Limits{MaxBytes: cfg.ResultCacheBytes})
+	}
+
+	if cfg.AdmissionMaxInFlight > 0 || cfg.AdmissionMaxBytes > 0 {
+		api.Admission = admission.NewController(admission.Limits{
+			MaxInFlight:  cfg.AdmissionMaxInFlight,
+			MaxBytes:     cfg.AdmissionMaxBytes,
+			QueueSize:    cfg.AdmissionQueueSize,
+			QueueTimeout: cfg.AdmissionQueueTimeout,
+		})
+	}
+
+	pool := jobs.NewPool(jobs.NewMemoryStore(), cfg.JobWorkers, cfg.JobQueueSize, cfg.JobRetention)
+	jobHandler := &api.JobHandler{Pool: pool, MaxUpload: cfg.JobMaxUpload}
+
+	for name := range cfg.OperationTimeouts {
+		if _, ok := api.Operations[name]; !ok {
+			fmt.Printf("Configuration error: OPERATION_TIMEOUTS names unknown operation %q\n", name)
+			os.Exit(1)
+		}
+	}
+	for name := range cfg.RateLimits {
+		if _, ok := api.Operations[name]; !ok && name != matricesScope {
+			fmt.Printf("Configuration error: RATE_LIMITS names unknown operation %q\n", name)
+			os.Exit(1)
+		}
+	}
+	api.RateLimitStore = ratelimit.NewMemoryStore()
+	rateLimit := func(name string) ratelimit.Limit {
+		if limit, ok := cfg.RateLimits[name]; ok {
+			return limit
+		}
+		return cfg.RateLimit
+	}
+	jobHandler.RateLimits = rateLimit
+	operationTimeout := func(name string) time.Duration {
+		if timeout, ok := cfg.OperationTimeouts[name]; ok {
+			return timeout
+		}
+		return cfg.OperationTimeout
+	}
+	jobHandler.Timeouts = operationTimeout
+
+	if cfg.AuthKeysFile != "" || cfg.JWTEnabled() {
+		var keys []auth.Key
+		if cfg.AuthKeysFile != "" {
+			keys, err = auth.LoadKeys(cfg.AuthKeysFile)
+			if err == nil {
+				err = checkScopes(keys)
+			}
+		}
+		if err == nil {
+			api.Auth, err = auth.NewAuthenticator(keys, cfg.AuthMaxSkew)
+		}
+		if err == nil {
+			// Signed bodies are read in full before anything else checks
+			// their size, so they are held to the job upload limit.
+			api.Auth.MaxBody = cfg.JobMaxUpload
+		}
+		if err != nil {
+			fmt.Printf("Auth keys error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.JWTEnabled() {
+		keys, err := tokenKeys(cfg)
+		if err != nil {
+			fmt.Printf("JWT keys error: %v\n", err)
+			os.Exit(1)
+		}
+		api.Auth.Tokens = auth.NewTokenVerifier(keys, cfg.JWTAudience, cfg.JWTLeeway, routeScopes())
+	}
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/echo", api.EchoHandler)
-	mux.HandleFunc("/invert", api.InvertHandler)
-	mux.HandleFunc("/sum", api.SumHandler)
-	mux.HandleFunc("/multiply", api.MultiplyHandler)
-	mux.HandleFunc("/flatten", api.FlattenHandler)
+	for name, handler := range api.Operations {
+		route := api.Admitted(api.Cached(name, api.WithTimeout(operationTimeout(name), handler)))
+		mux.HandleFunc("/"+name, api.Authorized(name, api.RateLimited(name, rateLimit(name), route)))
+	}
+	mux.HandleFunc("GET /healthz", api.HealthHandler)
+	mux.HandleFunc("GET /metrics", api.Authorized(metricsScope, metrics.Handler))
+	mux.HandleFunc("POST /matrices", api.Authorized(matricesScope, api.RateLimited(matricesScope, rateLimit(matricesScope), api.Admitted(api.StoreMatrixHandler))))
+	mux.HandleFunc("GET /matrices/{id}", api.Authorized(matricesScope, api.GetMatrixHandler))
+	mux.HandleFunc("DELETE /matrices/{id}", api.Authorized(matricesScope, api.DeleteMatrixHandler))
+	mux.HandleFunc("POST /jobs", api.Authorized(jobsScope, jobHandler.Submit))
+	mux.HandleFunc("GET /jobs/{id}", api.Authorized(jobsScope, jobHandler.Status))
+	mux.HandleFunc("GET /jobs/{id}/result", api.Authorized(jobsScope, jobHandler.Result))
+	mux.HandleFunc("DELETE /jobs/{id}", api.Authorized(jobsScope, jobHandler.Cancel))
 
+	// Every request's context derives from this one, so canceling it stops
+	// the operations still running.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
 	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr:        ":8080",
+		Handler:     api.Logged(mux),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
 
-	// Graceful shutdown listener
+	// Graceful shutdown listener. Operations still running are canceled and
+	// respond 503, open requests finish, then queued and running jobs drain.
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 		fmt.Println("Shutting down server...")
+		cancelRequests()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
 			fmt.Printf("Server shutdown failed: %v\n", err)
 		}
+		if err := pool.Shutdown(ctx); err != nil {
+			fmt.Printf("Job pool shutdown failed: %v\n", err)
+		}
 	}()
 
-	fmt.Println("Server running at http://localhost:8080")
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+	serve := srv.ListenAndServe
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		reloader, err := certs.NewReloader(certs.Options{
+			CertFile:          cfg.TLSCertFile,
+			KeyFile:           cfg.TLSKeyFile,
+			ClientCAFile:      cfg.TLSClientCAFile,
+			RequireClientCert: cfg.TLSRequireClientCert,
+			MinVersion:        cfg.TLSMinVersion,
+			CheckEvery:        cfg.TLSReloadInterval,
+		})
+		if err != nil {
+			fmt.Printf("TLS error: %v\n", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+		serve = func() error { return srv.ListenAndServeTLS("", "") }
+		scheme = "https"
+	}
+
+	fmt.Printf("Server running at %s://localhost:8080\n", scheme)
+	if err := serve(); err != http.ErrServerClosed {
 		fmt.Printf("Server error: %v\n", err)
+		return
+	}
+	<-stopped
+}
+
+// checkScopes reports a key with a scope that names no route, which is most
+// likely a typo for one that does.
+func checkScopes(keys []auth.Key) error {
+	known := routeScopes()
+	for _, key := range keys {
+		for _, scope := range key.Scopes {
+			if scope != auth.AllScopes && !slices.Contains(known, scope) {
+				return fmt.Errorf("key %q has unknown scope %q", key.ID, scope)
+			}
+		}
+	}
+	return nil
+}
+
+// routeScopes lists the scopes routes are checked against: one per
+// operation, and those of the matrix store, jobs and metrics.
+func routeScopes() []string {
+	scopes := []string{matricesScope, jobsScope, metricsScope}
+	for name := range api.Operations {
+		scopes = append(scopes, name)
+	}
+	return scopes
+}
+
+// tokenKeys gathers the keys bearer tokens may be signed with.
+func tokenKeys(cfg config.Config) ([]auth.TokenKey, error) {
+	var keys []auth.TokenKey
+	if cfg.JWTJWKSFile != "" {
+		set, err := auth.LoadJWKS(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, set...)
+	}
+	if cfg.JWTSecret != "" {
+		key, err := auth.NewTokenKey("", []byte(cfg.JWTSecret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if cfg.JWTPublicKeyFile != "" {
+		key, err := auth.LoadPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
 	}
+	return keys, nil
 }
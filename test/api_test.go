@@ -2,12 +2,19 @@ package test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -223,3 +230,1035 @@ func TestStringMatrixOperations(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 }
+
+func TestAggregateOperations(t *testing.T) {
+	client := &http.Client{}
+	filePath := "../matrix.csv"
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		status   int
+	}{
+		{"GET /sum?axis=row sums each row", "/sum?axis=row", "6,15,24\n", http.StatusOK},
+		{"GET /sum?axis=col sums each column", "/sum?axis=col", "12,15,18\n", http.StatusOK},
+		{"GET /multiply?axis=row multiplies each row", "/multiply?axis=row", "6,120,504\n", http.StatusOK},
+		{"GET /min returns the smallest element", "/min", "1\n", http.StatusOK},
+		{"GET /max?axis=col returns each column maximum", "/max?axis=col", "7,8,9\n", http.StatusOK},
+		{"GET /mean?axis=row returns each row mean", "/mean?axis=row", "2,5,8\n", http.StatusOK},
+		{"GET /count counts all elements", "/count", "9\n", http.StatusOK},
+		{"GET /sum?axis=col&format=json returns a JSON array", "/sum?axis=col&format=json", "[12,15,18]\n", http.StatusOK},
+		{"GET /sum responds with 400 on invalid axis", "/sum?axis=diagonal", "invalid axis: \"diagonal\" (want row, col or all)\n", http.StatusBadRequest},
+		{"GET /sum responds with 400 on unsupported format", "/sum?format=xml", "unsupported format \"xml\"\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequest(t, "GET", serverAddr+tt.path, filePath)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /mean returns error on string matrix", func(t *testing.T) {
+		req := createMultipartRequest(t, "GET", serverAddr+"/mean", "../stringMatrix.csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "failed to process request: unsupported operation\n", string(respBody))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	client := &http.Client{}
+	filePath := "../matrix.csv"
+
+	t.Run("GET /stats describes the whole matrix", func(t *testing.T) {
+		req := createMultipartRequest(t, "GET", serverAddr+"/stats?p=50,90&bins=2", filePath)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `[{
+			"count": 9, "mean": 5, "median": 5, "mode": 1,
+			"variance": 6.666666666666667, "stddev": 2.581988897471611,
+			"percentiles": [{"p": 50, "value": 5}, {"p": 90, "value": 8.2}],
+			"histogram": [{"lower": 1, "upper": 5, "count": 4}, {"lower": 5, "upper": 9, "count": 5}]
+		}]`, string(respBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("GET /stats?axis=row describes each row", func(t *testing.T) {
+		req := createMultipartRequest(t, "GET", serverAddr+"/stats?axis=row", filePath)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var stats []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		assert.Len(t, stats, 3)
+		assert.Equal(t, 8.0, stats[2]["median"])
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("GET /stats responds with 400 on out of range percentile", func(t *testing.T) {
+		req := createMultipartRequest(t, "GET", serverAddr+"/stats?p=150", filePath)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "invalid stats option: percentile 150 is outside [0, 100]\n", string(respBody))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func createMultipartRequestWithFiles(t *testing.T, method, url string, files map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for field, filePath := range files {
+		part, err := writer.CreateFormFile(field, filepath.Base(filePath))
+		assert.NoError(t, err)
+
+		file, err := os.Open(filePath)
+		assert.NoError(t, err)
+		t.Cleanup(func() { file.Close() })
+
+		_, err = io.Copy(part, file)
+		assert.NoError(t, err)
+	}
+	writer.Close()
+
+	req, err := http.NewRequest(method, url, body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestElementwiseOperations(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name     string
+		path     string
+		files    map[string]string
+		expected string
+		status   int
+	}{
+		{
+			"GET /add adds two matrices", "/add",
+			map[string]string{"file": "../matrix.csv", "other": "../matrix.csv"},
+			"2,4,6\n8,10,12\n14,16,18\n\n", http.StatusOK,
+		},
+		{
+			"GET /subtract subtracts a scalar", "/subtract?scalar=1",
+			map[string]string{"file": "../matrix.csv"},
+			"0,1,2\n3,4,5\n6,7,8\n\n", http.StatusOK,
+		},
+		{
+			"GET /hadamard multiplies two matrices element-wise", "/hadamard",
+			map[string]string{"file": "../matrix.csv", "other": "../matrix.csv"},
+			"1,4,9\n16,25,36\n49,64,81\n\n", http.StatusOK,
+		},
+		{
+			"GET /divide divides by a scalar", "/divide?scalar=2",
+			map[string]string{"file": "../matrix.csv"},
+			"0,1,1\n2,2,3\n3,4,4\n\n", http.StatusOK,
+		},
+		{
+			"GET /add responds with 400 on shape mismatch", "/add",
+			map[string]string{"file": "../matrix.csv", "other": "../rectangleMatrix.csv"},
+			"shape mismatch: left matrix is 3x3, right matrix is 2x3\n", http.StatusBadRequest,
+		},
+		{
+			"GET /divide reports division by zero", "/divide?scalar=0",
+			map[string]string{"file": "../matrix.csv"},
+			"failed to process request: divide failed at row 1 col 1: division by zero\n", http.StatusInternalServerError,
+		},
+		{
+			"GET /add responds with 400 on both operands", "/add?scalar=1",
+			map[string]string{"file": "../matrix.csv", "other": "../matrix.csv"},
+			"provide either an other matrix or a scalar, not both\n", http.StatusBadRequest,
+		},
+		{
+			"GET /add responds with 400 on missing operand", "/add",
+			map[string]string{"file": "../matrix.csv"},
+			"failed to get other from request: http: no such file: provide an other matrix or a scalar\n", http.StatusBadRequest,
+		},
+		{
+			"GET /add returns error on string matrix", "/add?scalar=1",
+			map[string]string{"file": "../stringMatrix.csv"},
+			"failed to process request: unsupported operation\n", http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestWithFiles(t, "GET", serverAddr+tt.path, tt.files)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestGeometryOperations(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name     string
+		path     string
+		filePath string
+		expected string
+		status   int
+	}{
+		{"GET /rotate?degrees=90 rotates clockwise", "/rotate?degrees=90", "../rectangleMatrix.csv", "4,1\n5,2\n6,3\n\n", http.StatusOK},
+		{"GET /rotate?degrees=180 rotates string matrix", "/rotate?degrees=180", "../stringMatrix.csv", "j,i,h\ng,f,e\nc,b,a\n\n", http.StatusOK},
+		{"GET /rotate responds with 400 on invalid degrees", "/rotate?degrees=45", "../matrix.csv", "invalid rotation: 45 degrees is not a multiple of 90\n", http.StatusBadRequest},
+		{"GET /flip-horizontal mirrors left to right", "/flip-horizontal", "../rectangleMatrix.csv", "3,2,1\n6,5,4\n\n", http.StatusOK},
+		{"GET /flip-vertical mirrors top to bottom", "/flip-vertical", "../rectangleMatrix.csv", "4,5,6\n1,2,3\n\n", http.StatusOK},
+		{"GET /anti-transpose mirrors across the anti-diagonal", "/anti-transpose", "../matrix.csv", "9,6,3\n8,5,2\n7,4,1\n\n", http.StatusOK},
+		{"GET /reshape keeps row-major order", "/reshape?shape=3x2", "../rectangleMatrix.csv", "1,2\n3,4\n5,6\n\n", http.StatusOK},
+		{"GET /reshape responds with 400 on element count mismatch", "/reshape?shape=2x2", "../rectangleMatrix.csv", "invalid shape: cannot reshape 6 elements into 2x2\n", http.StatusBadRequest},
+		{"GET /invert transposes non-square matrix", "/invert", "../rectangleMatrix.csv", "1,4\n2,5\n3,6\n\n", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequest(t, "GET", serverAddr+tt.path, tt.filePath)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name     string
+		path     string
+		filePath string
+		expected string
+		status   int
+	}{
+		{"GET /echo?rows=1: drops the first row", "/echo?rows=1:", "../matrix.csv", "4,5,6\n7,8,9\n\n", http.StatusOK},
+		{"GET /sum?cols=0,2 sums selected columns", "/sum?cols=0,2", "../matrix.csv", "30\n", http.StatusOK},
+		{"GET /flatten?rows=-1&cols=::-1 reverses the last row", "/flatten?rows=-1&cols=::-1", "../stringMatrix.csv", "j,i,h\n", http.StatusOK},
+		{"GET /invert?rows=0 transposes the first row", "/invert?rows=0", "../matrix.csv", "1\n2\n3\n\n", http.StatusOK},
+		{
+			"GET /sum responds with 422 on out of range selector", "/sum?rows=10:20", "../matrix.csv",
+			"rows=10:20: selector out of range: slice 10:20, matrix shape is 3x3\n", http.StatusUnprocessableEntity,
+		},
+		{
+			"GET /sum responds with 400 on malformed selector", "/sum?cols=x", "../matrix.csv",
+			"cols=x: invalid selector: \"x\" is not an index or slice, matrix shape is 3x3\n", http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequest(t, "GET", serverAddr+tt.path, tt.filePath)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func createMultipartRequestFromContent(t *testing.T, method, url, content, contentType string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	assert.NoError(t, err)
+
+	_, err = io.WriteString(part, content)
+	assert.NoError(t, err)
+	writer.Close()
+
+	req, err := http.NewRequest(method, url, body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestCSVDialects(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name        string
+		path        string
+		content     string
+		contentType string
+		expected    string
+		status      int
+	}{
+		{"GET /sum reads tabs with delimiter=tab", "/sum?delimiter=tab", "1\t2\n3\t4\n", "text/plain", "10\n", http.StatusOK},
+		{"GET /sum reads tabs from a TSV upload", "/sum", "1\t2\n3\t4\n", "text/tab-separated-values", "10\n", http.StatusOK},
+		{"GET /echo reads semicolons with comments", "/echo?delimiter=%3B&comment=%23", "# export\n1;2\n3;4\n", "text/csv", "1,2\n3,4\n\n", http.StatusOK},
+		{"GET /sum skips the header row", "/sum?header=true", "a,b\n1,2\n3,4\n", "text/csv", "10\n", http.StatusOK},
+		{"GET /sum skips the header row from text/csv header=present", "/sum", "a,b\n1,2\n3,4\n", "text/csv; header=present", "10\n", http.StatusOK},
+		{"GET /sum trims leading space", "/sum?trim_space=true", "1, 2\n3, 4\n", "text/csv", "10\n", http.StatusOK},
+		{"GET /sum responds with 400 on invalid delimiter", "/sum?delimiter=ab", "1,2\n", "text/csv", "delimiter: invalid CSV dialect: \"ab\" is not a single usable character\n", http.StatusBadRequest},
+		{"GET /sum responds with 400 on invalid header flag", "/sum?header=maybe", "1,2\n", "text/csv", "invalid CSV dialect: header=\"maybe\" is not a boolean\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, tt.content, tt.contentType)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestLabeledMatrices(t *testing.T) {
+	client := &http.Client{}
+	content := "region,q1,q2,q3\nnorth,1,2,3\nsouth,4,5,6\n"
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"GET /echo re-emits the header line", "/echo?header=true&index=true", "region,q1,q2,q3\nnorth,1,2,3\nsouth,4,5,6\n\n"},
+		{"GET /invert swaps row and column labels", "/invert?header=true&index=true", "region,north,south\nq1,1,4\nq2,2,5\nq3,3,6\n\n"},
+		{"GET /rotate carries labels", "/rotate?degrees=90&header=true&index=true", "region,south,north\nq1,4,1\nq2,5,2\nq3,6,3\n\n"},
+		{"GET /sum?axis=col labels each column total", "/sum?axis=col&header=true&index=true", "q1,q2,q3\n5,7,9\n"},
+		{"GET /sum?axis=row labels each row total", "/sum?axis=row&header=true&index=true", "north,south\n6,15\n"},
+		{"GET /sum?axis=col&format=json returns labels and values", "/sum?axis=col&format=json&header=true&index=true", "{\"labels\":[\"q1\",\"q2\",\"q3\"],\"values\":[5,7,9]}\n"},
+		{"GET /echo?cols=2,0 selects labels with columns", "/echo?cols=2,0&header=true&index=true", "region,q3,q1\nnorth,3,1\nsouth,6,4\n\n"},
+		{"GET /flatten leaves labels out", "/flatten?header=true&index=true", "1,2,3,4,5,6\n"},
+		{"GET /reshape drops labels", "/reshape?shape=3x2&header=true&index=true", "1,2\n3,4\n5,6\n\n"},
+		{"GET /add keeps the labels of the left matrix", "/add?scalar=10&header=true&index=true", "region,q1,q2,q3\nnorth,11,12,13\nsouth,14,15,16\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, content, "text/csv")
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /stats?axis=col labels each lane", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/stats?axis=col&header=true&index=true", content, "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var stats []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		assert.Len(t, stats, 3)
+		assert.Equal(t, "q2", stats[1]["label"])
+	})
+
+	t.Run("GET /invert quotes labels and cells that need it", func(t *testing.T) {
+		content := "name,\"a,b\",c\nx,\"1\n2\",\" 3\"\n\"y \"\"z\"\"\",4,5\n"
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/invert?header=true&index=true", content, "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "name,x,\"y \"\"z\"\"\"\n\"a,b\",\"1\n2\",4\nc,\" 3\",5\n\n", string(respBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestNullHandling(t *testing.T) {
+	client := &http.Client{}
+	content := "1,NA,3\n4,5,\n"
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		status   int
+	}{
+		{"GET /sum skips nulls by default", "/sum", "13\n", http.StatusOK},
+		{"GET /count?axis=row counts non-null cells", "/count?axis=row", "2,2\n", http.StatusOK},
+		{"GET /multiply?nulls=zero treats nulls as zero", "/multiply?nulls=zero", "0\n", http.StatusOK},
+		{"GET /sum?nulls=error fails on the first null", "/sum?nulls=error", "failed to process request: null value at row 1 col 2\n", http.StatusInternalServerError},
+		{"GET /invert preserves null positions", "/invert", "1,4\n,5\n3,\n\n", http.StatusOK},
+		{"GET /flatten preserves null positions", "/flatten?null_tokens=NA,", "1,NA,3,4,5,NA\n", http.StatusOK},
+		{"GET /add?scalar=1 propagates nulls", "/add?scalar=1", "2,,4\n5,6,\n\n", http.StatusOK},
+		{"GET /sum treats unlisted tokens as strings", "/sum?null_tokens=-", "failed to process request: unsupported operation\n", http.StatusInternalServerError},
+		{"GET /sum responds with 400 on invalid policy", "/sum?nulls=ignore", "invalid null policy: \"ignore\" (want skip, zero or error)\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, content, "text/csv")
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /add combines a numeric matrix with a nullable one", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for field, content := range map[string]string{"file": "1,2\n3,4\n", "other": "10,NA\n10,10\n"} {
+			part, err := writer.CreateFormFile(field, field+".csv")
+			assert.NoError(t, err)
+			_, err = io.WriteString(part, content)
+			assert.NoError(t, err)
+		}
+		writer.Close()
+
+		req, err := http.NewRequest("GET", serverAddr+"/add", body)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "11,\n13,14\n\n", string(respBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestRaggedRows(t *testing.T) {
+	client := &http.Client{}
+	content := "1,2,3\n4,5\n6\n"
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		status   int
+	}{
+		{"GET /echo lists every ragged row", "/echo", "ragged rows: expected 3 fields per row, row 2 has 2, row 3 has 1\n", http.StatusBadRequest},
+		{"GET /echo?ragged=pad pads with nulls", "/echo?ragged=pad", "1,2,3\n4,5,\n6,,\n\n", http.StatusOK},
+		{"GET /sum?ragged=pad&pad=0 pads with the pad value", "/sum?ragged=pad&pad=0&axis=row", "6,9,6\n", http.StatusOK},
+		{"GET /echo?ragged=truncate cuts to the shortest row", "/echo?ragged=truncate", "1\n4\n6\n\n", http.StatusOK},
+		{"GET /echo responds with 400 on invalid mode", "/echo?ragged=fill", "invalid ragged mode: \"fill\" (want error, pad or truncate)\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, content, "text/csv")
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected string
+		status   int
+	}{
+		{
+			"GET /validate accepts a numeric matrix", "/validate", "1,2\n3,NA\n",
+			`{"valid": true, "type": "nullable", "rows": 2, "cols": 2, "issues": [], "truncated": false}`,
+			http.StatusOK,
+		},
+		{
+			"GET /validate reports every bad cell", "/validate", "1,x\n3.5,4\n",
+			`{"valid": false, "type": "string", "rows": 2, "cols": 2, "issues": [
+				{"row": 1, "col": 2, "value": "x", "reason": "not an integer"},
+				{"row": 2, "col": 1, "value": "3.5", "reason": "not an integer"}
+			], "truncated": false}`,
+			http.StatusOK,
+		},
+		{
+			"GET /validate reports ragged rows", "/validate", "1,2\n3\n",
+			`{"valid": false, "rows": 0, "cols": 0,
+				"error": "ragged rows: expected 2 fields per row, row 2 has 1",
+				"issues": [{"row": 2, "reason": "row has 1 fields, expected 2"}], "truncated": false}`,
+			http.StatusOK,
+		},
+		{
+			"GET /validate?ragged=pad reports the rows it padded", "/validate?ragged=pad", "1,2\n3\n4\n",
+			`{"valid": true, "type": "nullable", "rows": 3, "cols": 2, "issues": [], "truncated": false,
+				"adjusted_rows": [{"row": 2, "length": 1}, {"row": 3, "length": 1}]}`,
+			http.StatusOK,
+		},
+		{
+			"GET /validate?max_issues=1 truncates the report", "/validate?max_issues=1", "a,b\n",
+			`{"valid": false, "type": "string", "rows": 1, "cols": 2,
+				"issues": [{"row": 1, "col": 1, "value": "a", "reason": "not an integer"}], "truncated": true}`,
+			http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, tt.content, "text/csv")
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.JSONEq(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /validate responds with 400 on invalid max_issues", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/validate?max_issues=0", "1\n", "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "invalid max_issues \"0\"\n", string(respBody))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestCharsets(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name        string
+		path        string
+		content     string
+		contentType string
+		expected    string
+		status      int
+	}{
+		{"GET /sum strips a UTF-8 BOM", "/sum", "\xEF\xBB\xBF1,2\n3,4\n", "text/csv", "10\n", http.StatusOK},
+		{"GET /sum decodes UTF-16 from its BOM", "/sum", "\xFF\xFE1\x00,\x002\x00\n\x00", "text/csv", "3\n", http.StatusOK},
+		{"GET /echo decodes the Content-Type charset", "/echo", "caf\xE9,\x80\n", "text/csv; charset=windows-1252", "café,€\n\n", http.StatusOK},
+		{"GET /echo?charset=latin1 overrides the Content-Type", "/echo?charset=latin1", "caf\xE9\n", "text/csv; charset=utf-8", "café\n\n", http.StatusOK},
+		{"GET /echo reports invalid bytes with offsets", "/echo", "1,\xFF\n\xFE,2\n", "text/csv", "failed to decode file: invalid encoding: invalid utf-8 at byte offsets 2, 4\n", http.StatusBadRequest},
+		{"GET /echo responds with 400 on unsupported charset", "/echo?charset=ebcdic", "1\n", "text/csv", "unsupported charset: \"ebcdic\" (want utf-8, utf-16le, utf-16be, iso-8859-1 or windows-1252)\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, tt.content, tt.contentType)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	client := &http.Client{}
+
+	tests := []struct {
+		name        string
+		path        string
+		content     string
+		contentType string
+		accept      string
+		expected    string
+		status      int
+	}{
+		{"GET /invert reads JSON arrays", "/invert", "[[1,2],[3,4]]", "application/json", "", "1,3\n2,4\n\n", http.StatusOK},
+		{"GET /echo reads labeled JSON", "/echo", `{"columns":["a","b"],"index":["x"],"data":[[1,2]]}`, "application/json", "", ",a,b\nx,1,2\n\n", http.StatusOK},
+		{"GET /echo?input_format=json overrides the Content-Type", "/echo?input_format=json", "[[1,null]]", "text/plain", "", "1,\n\n", http.StatusOK},
+		{"GET /sum reads Matrix Market", "/sum", "%%MatrixMarket matrix array integer general\n2 1\n3\n4\n", "application/x-matrix-market", "", "7\n", http.StatusOK},
+		{"GET /echo reads TSV", "/echo", "1\t2\n", "text/tab-separated-values", "", "1,2\n\n", http.StatusOK},
+		{"GET /echo?input_format=tsv reads tabs", "/echo?input_format=tsv", "1\t2\n", "text/plain", "", "1,2\n\n", http.StatusOK},
+		{"GET /echo?input_format=tsv&delimiter=, keeps the explicit comma", "/echo?input_format=tsv&delimiter=,", "1,2\n", "text/plain", "", "1,2\n\n", http.StatusOK},
+		{"GET /invert?format=json writes JSON", "/invert?format=json", "1,2\n3,\n", "text/csv", "", "[[1,3],[2,null]]\n\n", http.StatusOK},
+		{"GET /echo?format=json writes labeled JSON", "/echo?format=json&header=true", "a,b\n1,2\n", "text/csv", "", "{\"columns\":[\"a\",\"b\"],\"data\":[[1,2]]}\n\n", http.StatusOK},
+		{"GET /rotate writes the Accept type", "/rotate?degrees=90", "1,2\n", "text/csv", "text/html, application/json;q=0.5, text/tab-separated-values;q=0.9", "1\n2\n\n", http.StatusOK},
+		{"GET /echo with Accept: application/json", "/echo", "1,2\n", "text/csv", "application/json", "[[1,2]]\n\n", http.StatusOK},
+		{"GET /echo?format=mtx writes Matrix Market", "/echo?format=mtx", "1,2\n3,4\n", "text/csv", "", "%%MatrixMarket matrix array integer general\n2 2\n1\n3\n2\n4\n\n", http.StatusOK},
+		{"GET /echo?format=mtx responds with 406 on strings", "/echo?format=mtx", "a\n", "text/csv", "", "matrix cannot be written in this format: Matrix Market holds integers only, row 1 col 1 is \"a\"\n", http.StatusNotAcceptable},
+		{"GET /flatten?format=json writes one row", "/flatten?format=json", "1,2\n3,4\n", "text/csv", "", "[[1,2,3,4]]\n\n", http.StatusOK},
+		{"GET /sum?axis=col&format=tsv writes a labeled row", "/sum?axis=col&format=tsv&header=true", "a,b\n1,2\n3,4\n", "text/csv", "", "a\tb\n4\t6\n\n", http.StatusOK},
+		{"GET /echo responds with 400 on invalid JSON", "/echo", "[[1,2]", "application/json", "", "failed to read JSON file: invalid JSON matrix: unexpected EOF\n", http.StatusBadRequest},
+		{"GET /echo responds with 400 on unsupported input format", "/echo?input_format=xml", "1\n", "text/csv", "", "unsupported format \"xml\"\n", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, tt.content, tt.contentType)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.expected, string(respBody))
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /sum picks Matrix Market from the .mtx extension", func(t *testing.T) {
+		req := createMultipartRequest(t, "GET", serverAddr+"/sum", "../matrix.mtx")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "45\n", string(respBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestNPYFormat(t *testing.T) {
+	client := &http.Client{}
+
+	// A 2x2 little endian int32 array in Fortran order: [[1, 2], [3, 4]].
+	header := "{'descr': '<i4', 'fortran_order': True, 'shape': (2, 2), }\n"
+	npy := &bytes.Buffer{}
+	npy.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(npy, binary.LittleEndian, uint16(len(header)))
+	npy.WriteString(header)
+	binary.Write(npy, binary.LittleEndian, []int32{1, 3, 2, 4})
+
+	t.Run("GET /invert reads .npy", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/invert", npy.String(), "application/x-npy")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "1,3\n2,4\n\n", string(respBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("GET /echo?format=npy writes int64 .npy", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/echo?format=npy", "1,2\n3,4\n", "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "application/x-npy", resp.Header.Get("Content-Type"))
+		assert.True(t, bytes.HasPrefix(respBody, []byte("\x93NUMPY\x01\x00")))
+		assert.Contains(t, string(respBody), "'descr': '<i8', 'fortran_order': False, 'shape': (2, 2)")
+
+		var values [4]int64
+		assert.NoError(t, binary.Read(bytes.NewReader(respBody[len(respBody)-32:]), binary.LittleEndian, &values))
+		assert.Equal(t, [4]int64{1, 2, 3, 4}, values)
+	})
+
+	t.Run("GET /echo responds with 400 on unsupported dtype", func(t *testing.T) {
+		content := strings.Replace(npy.String(), "<i4", "<U1", 1)
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/echo", content, "application/x-npy")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "failed to read NPY file: unsupported dtype \"<U1\" (want a signed or unsigned integer, float32 or float64)\n", string(respBody))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestSparseMatrices(t *testing.T) {
+	client := &http.Client{}
+	triples := "row,col,value\n0,0,5\n99,99,3\n"
+
+	tests := []struct {
+		name        string
+		path        string
+		content     string
+		contentType string
+		expected    string
+	}{
+		{"GET /sum reads triples", "/sum", triples, "text/x-triples", "8\n"},
+		{"GET /multiply short-circuits to 0", "/multiply", triples, "text/x-triples", "0\n"},
+		{"GET /invert?format=triples writes the nonzero cells", "/invert?format=triples", triples, "text/x-triples", "row,col,value\n0,0,5\n99,99,3\n\n"},
+		{"GET /flatten?rows=99&cols=98: keeps zeros", "/flatten?rows=99&cols=98:", triples, "text/x-triples", "0,3\n"},
+		{
+			"GET /validate reports a sparse Matrix Market upload", "/validate",
+			"%%MatrixMarket matrix coordinate integer general\n50 40 1\n50 40 9\n", "application/x-matrix-market",
+			`{"valid": true, "type": "sparse", "rows": 50, "cols": 40, "issues": [], "truncated": false}`,
+		},
+		{
+			"GET /validate picks sparse storage for a mostly zero CSV", "/validate",
+			"0,0,0,0,0,0\n0,0,0,0,0,7\n", "text/csv",
+			`{"valid": true, "type": "sparse", "rows": 2, "cols": 6, "issues": [], "truncated": false}`,
+		},
+		{
+			"GET /validate expands a dense triples upload", "/validate", "0,0,1\n0,1,2\n", "text/x-triples",
+			`{"valid": true, "type": "numeric", "rows": 1, "cols": 2, "issues": [], "truncated": false}`,
+		},
+		{"GET /add?scalar=1 densifies a sparse matrix", "/add?scalar=1", "0,0,0,0,0,0\n0,0,0,0,0,7\n", "text/csv", "1,1,1,1,1,1\n1,1,1,1,1,8\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createMultipartRequestFromContent(t, "GET", serverAddr+tt.path, tt.content, tt.contentType)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			respBody, _ := io.ReadAll(resp.Body)
+			if strings.HasPrefix(tt.expected, "{") {
+				assert.JSONEq(t, tt.expected, string(respBody))
+			} else {
+				assert.Equal(t, tt.expected, string(respBody))
+			}
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+
+	t.Run("GET /sum responds with 400 on a repeated cell", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/sum", "0,0,1\n0,0,2\n", "text/x-triples")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "failed to read TRIPLES file: invalid triples file: line 2: row 0 col 0 is already set on line 1\n", string(respBody))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestJobs(t *testing.T) {
+	client := &http.Client{}
+
+	// submit posts content as a job and returns its ID.
+	submit := func(t *testing.T, query, content string) string {
+		req := createMultipartRequestFromContent(t, "POST", serverAddr+"/jobs?"+query, content, "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var job struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/jobs/"+job.ID, resp.Header.Get("Location"))
+		return job.ID
+	}
+	// await polls a job until it finishes and returns its final state.
+	await := func(t *testing.T, id string) map[string]any {
+		var job map[string]any
+		assert.Eventually(t, func() bool {
+			resp, err := client.Get(serverAddr + "/jobs/" + id)
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			json.NewDecoder(resp.Body).Decode(&job)
+			return job["status"] != "queued" && job["status"] != "running"
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+	get := func(t *testing.T, method, url string) (int, string) {
+		req, err := http.NewRequest(method, serverAddr+url, nil)
+		assert.NoError(t, err)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
+
+	t.Run("POST /jobs runs an operation with its parameters", func(t *testing.T) {
+		id := submit(t, "operation=sum&axis=row", "1,2\n3,4\n")
+		job := await(t, id)
+		assert.Equal(t, "succeeded", job["status"])
+		assert.Equal(t, "sum", job["operation"])
+		assert.Equal(t, 1.0, job["progress"])
+
+		status, body := get(t, "GET", "/jobs/"+id+"/result")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "3,7\n", body)
+
+		status, _ = get(t, "DELETE", "/jobs/"+id)
+		assert.Equal(t, http.StatusNoContent, status)
+		status, body = get(t, "GET", "/jobs/"+id)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "job "+id+" not found\n", body)
+	})
+
+	t.Run("GET /jobs/{id}/result responds with 409 for a failed job", func(t *testing.T) {
+		id := submit(t, "operation=sum", "a,b\n")
+		job := await(t, id)
+		assert.Equal(t, "failed", job["status"])
+		assert.Equal(t, "failed to process request: unsupported operation", job["error"])
+
+		status, body := get(t, "GET", "/jobs/"+id+"/result")
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "job "+id+" failed: failed to process request: unsupported operation\n", body)
+	})
+
+	t.Run("POST /jobs responds with 400 on an unknown operation", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "POST", serverAddr+"/jobs?operation=explode", "1\n", "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "unknown operation \"explode\"\n", string(respBody))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("GET /jobs/{id} responds with 405 for other methods", func(t *testing.T) {
+		status, _ := get(t, "PUT", "/jobs/abc")
+		assert.Equal(t, http.StatusMethodNotAllowed, status)
+	})
+}
+
+func TestMatrixStore(t *testing.T) {
+	client := &http.Client{}
+
+	// storeAt uploads content to url, /matrices with any parameters, and
+	// returns its ID.
+	storeAt := func(t *testing.T, url, content string) string {
+		req := createMultipartRequestFromContent(t, "POST", serverAddr+url, content, "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var info map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		id, _ := info["id"].(string)
+		assert.Equal(t, "/matrices/"+id, resp.Header.Get("Location"))
+		return id
+	}
+	store := func(t *testing.T, content string) string {
+		return storeAt(t, "/matrices", content)
+	}
+	get := func(t *testing.T, method, url string) (int, string) {
+		req, err := http.NewRequest(method, serverAddr+url, nil)
+		assert.NoError(t, err)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
+
+	id := store(t, "1,2,3\n4,5,6\n")
+	assert.Len(t, id, 64)
+
+	t.Run("POST /matrices gives a matrix the same ID every time", func(t *testing.T) {
+		assert.Equal(t, id, store(t, "1,2,3\r\n4,5,6"))
+		assert.NotEqual(t, id, store(t, "1,2\n3,4\n"))
+	})
+
+	t.Run("POST /matrices tells apart uploads parsed with other null tokens", func(t *testing.T) {
+		// Unless the empty cell is a null token it is text, which writes
+		// out alike.
+		nullable := store(t, "1,\n")
+		assert.NotEqual(t, nullable, storeAt(t, "/matrices?null_tokens=NA", "1,\n"))
+	})
+
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"GET /sum?matrix_id sums a stored matrix", "/sum?matrix_id=" + id, "21\n"},
+		{"GET /invert?matrix_id applies selectors", "/invert?rows=1&matrix_id=" + id, "4\n5\n6\n\n"},
+		{"GET /add?matrix_id&other_matrix_id combines two stored matrices", "/add?matrix_id=" + id + "&other_matrix_id=" + id, "2,4,6\n8,10,12\n\n"},
+		{"GET /matrices/{id} returns the stored matrix", "/matrices/" + id + "?format=json", "[[1,2,3],[4,5,6]]\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(t, "GET", tt.url)
+			assert.Equal(t, tt.expected, body)
+			assert.Equal(t, http.StatusOK, status)
+		})
+	}
+
+	t.Run("POST /matrices keeps labels and nulls", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "POST", serverAddr+"/matrices?header=true", "a,b\n1,NA\n", "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var info map[string]any
+		json.NewDecoder(resp.Body).Decode(&info)
+		assert.Equal(t, "nullable", info["type"])
+
+		status, body := get(t, "GET", "/echo?matrix_id="+info["id"].(string))
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "a,b\n1,\n\n", body)
+	})
+
+	t.Run("DELETE /matrices/{id} removes a stored matrix", func(t *testing.T) {
+		other := store(t, "7\n")
+		status, _ := get(t, "DELETE", "/matrices/"+other)
+		assert.Equal(t, http.StatusNoContent, status)
+
+		status, body := get(t, "GET", "/sum?matrix_id="+other)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "matrix "+other+" not found\n", body)
+	})
+
+	t.Run("POST /jobs?matrix_id runs on a stored matrix", func(t *testing.T) {
+		resp, err := client.Post(serverAddr+"/jobs?operation=max&matrix_id="+id, "", nil)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		var body string
+		assert.Eventually(t, func() bool {
+			var status int
+			status, body = get(t, "GET", resp.Header.Get("Location")+"/result")
+			return status == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, "6\n", body)
+	})
+
+	t.Run("GET /sum responds with 400 on an invalid matrix_id", func(t *testing.T) {
+		status, body := get(t, "GET", "/sum?matrix_id=..%2Fetc")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid ID \"../etc\"\n", body)
+	})
+}
+
+func TestResultCache(t *testing.T) {
+	client := &http.Client{}
+
+	// metric reads one value from /metrics.
+	metric := func(t *testing.T, name string) float64 {
+		resp, err := client.Get(serverAddr + "/metrics")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		for _, line := range strings.Split(string(body), "\n") {
+			if value, ok := strings.CutPrefix(line, name+" "); ok {
+				f, _ := strconv.ParseFloat(value, 64)
+				return f
+			}
+		}
+		t.Fatalf("metric %s not found in:\n%s", name, body)
+		return 0
+	}
+	// sum uploads a matrix to /sum, unique to this test run so the first
+	// request misses.
+	content := fmt.Sprintf("1,2\n3,%d\n", time.Now().UnixNano()%1000)
+	sum := func(t *testing.T, ifNoneMatch string) *http.Response {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/sum?axis=row", content, "text/csv")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	hits, misses := metric(t, "result_cache_hits_total"), metric(t, "result_cache_misses_total")
+
+	first := sum(t, "")
+	firstBody, _ := io.ReadAll(first.Body)
+	first.Body.Close()
+	etag := first.Header.Get("ETag")
+	assert.Len(t, etag, 66)
+
+	second := sum(t, "")
+	secondBody, _ := io.ReadAll(second.Body)
+	second.Body.Close()
+	assert.Equal(t, http.StatusOK, second.StatusCode)
+	assert.Equal(t, etag, second.Header.Get("ETag"))
+	assert.Equal(t, string(firstBody), string(secondBody))
+	assert.Equal(t, first.Header.Get("Content-Type"), second.Header.Get("Content-Type"))
+
+	notModified := sum(t, `"other", `+etag)
+	notModified.Body.Close()
+	assert.Equal(t, http.StatusNotModified, notModified.StatusCode)
+
+	assert.Equal(t, hits+2, metric(t, "result_cache_hits_total"))
+	assert.Equal(t, misses+1, metric(t, "result_cache_misses_total"))
+
+	t.Run("Errors are not cached and carry no ETag", func(t *testing.T) {
+		req := createMultipartRequestFromContent(t, "GET", serverAddr+"/sum", "a,b\n", "text/csv")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("ETag"))
+	})
+}
+
+func TestAdmissionMetrics(t *testing.T) {
+	client := &http.Client{}
+	req := createMultipartRequestFromContent(t, "GET", serverAddr+"/sum", "1,2\n", "text/csv")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get(serverAddr + "/metrics")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	// A finished request gives its room back.
+	assert.Contains(t, string(body), "\nadmission_in_flight 0\n")
+	assert.Contains(t, string(body), "\nadmission_in_flight_bytes 0\n")
+	assert.Contains(t, string(body), "\nadmission_queue_depth 0\n")
+	assert.Contains(t, string(body), "\nadmission_rejected_queue_full_total ")
+	assert.Contains(t, string(body), "\nadmission_rejected_queue_timeout_total ")
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	resp, err := http.Get(serverAddr + "/healthz")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok\n", string(body))
+}